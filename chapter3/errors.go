@@ -0,0 +1,179 @@
+package chapter3
+
+import (
+	"fmt"
+)
+
+// RuntimeError is implemented by all the errors the evaluators themselves
+// produce (as opposed to errors raised by EPL programs).  Use errors.As with a
+// RuntimeError target to check whether an error came from the interpreter.
+type RuntimeError interface {
+	error
+	runtimeError()
+}
+
+// RuntimeErrorBase can be embedded in the errors of evaluators in other
+// packages to make them RuntimeErrors, so they can be caught like these.
+type RuntimeErrorBase struct{}
+
+func (RuntimeErrorBase) runtimeError() {}
+
+// UnboundVariableError is returned when a variable cannot be found in the
+// environment chain.
+type UnboundVariableError struct {
+	Name string
+}
+
+func (e UnboundVariableError) runtimeError() {}
+
+func (e UnboundVariableError) Error() string {
+	return fmt.Sprintf("variable '%s' not found in environment", e.Name)
+}
+
+// UnknownOperatorError is returned when an OpExpr refers to an operator that
+// has not been registered with the evaluator.
+type UnknownOperatorError struct {
+	Op string
+}
+
+func (e UnknownOperatorError) runtimeError() {}
+
+func (e UnknownOperatorError) Error() string {
+	return fmt.Sprintf("opfunc not found: %s", e.Op)
+}
+
+// TypeMismatchError is returned when a value of an unexpected kind is found,
+// eg an int where a bool was needed.
+type TypeMismatchError struct {
+	Context  string // What was being evaluated, eg "iszero" or "'+' operator"
+	Expected string // Description of the expected kind of value
+	Found    any    // The value that was actually found
+}
+
+func (e TypeMismatchError) runtimeError() {}
+
+func (e TypeMismatchError) Error() string {
+	return fmt.Sprintf("%s expected %s, got %T (%v)", e.Context, e.Expected, e.Found, e.Found)
+}
+
+// ArityError is returned when an operator or procedure is applied to the
+// wrong number of arguments.
+type ArityError struct {
+	Context  string
+	Expected int
+	Found    int
+}
+
+func (e ArityError) runtimeError() {}
+
+func (e ArityError) Error() string {
+	return fmt.Sprintf("%s expects %d arguments, but called with %d", e.Context, e.Expected, e.Found)
+}
+
+// TupleArityError is returned when a tuple does not have the number of
+// items needed, eg unpacking a pair into three names.
+type TupleArityError struct {
+	Context  string
+	Expected int
+	Found    int
+}
+
+func (e TupleArityError) runtimeError() {}
+
+func (e TupleArityError) Error() string {
+	return fmt.Sprintf("%s expected a tuple of %d items, got %d", e.Context, e.Expected, e.Found)
+}
+
+// MissingFieldError is returned for a field a record does not have.
+type MissingFieldError struct {
+	Field string
+}
+
+func (e MissingFieldError) runtimeError() {}
+
+func (e MissingFieldError) Error() string {
+	return fmt.Sprintf("record has no field '%s'", e.Field)
+}
+
+// NotAReferenceError is returned by deref/setref when their operand does not
+// evaluate to a reference.
+type NotAReferenceError struct {
+	Context string
+	Expr    Expr
+	Found   any
+}
+
+func (e NotAReferenceError) runtimeError() {}
+
+func (e NotAReferenceError) Error() string {
+	return fmt.Sprintf("%s expected a reference argument, but got type %T for expr %s", e.Context, e.Found, e.Expr.Repr())
+}
+
+// NotAThunkError is returned when forcing a value that is not a thunk.
+type NotAThunkError struct {
+	Expr  Expr
+	Found any
+}
+
+func (e NotAThunkError) runtimeError() {}
+
+func (e NotAThunkError) Error() string {
+	return fmt.Sprintf("thunk operator expected a thunk value, but got type %T for expr %s", e.Found, e.Expr.Repr())
+}
+
+// DivisionByZeroError is returned when dividing by zero.
+type DivisionByZeroError struct{}
+
+func (e DivisionByZeroError) runtimeError() {}
+
+func (e DivisionByZeroError) Error() string {
+	return "division by zero"
+}
+
+// IndexError is returned when an index is outside the bounds of a value.
+type IndexError struct {
+	Context string
+	Index   int
+	Length  int
+}
+
+func (e IndexError) runtimeError() {}
+
+func (e IndexError) Error() string {
+	return fmt.Sprintf("%s: index %d out of range for length %d", e.Context, e.Index, e.Length)
+}
+
+// ConversionError is returned when a value cannot be converted to another type.
+type ConversionError struct {
+	Context string
+	Value   any
+}
+
+func (e ConversionError) runtimeError() {}
+
+func (e ConversionError) Error() string {
+	return fmt.Sprintf("%s: cannot convert %s", e.Context, ReprOf(e.Value))
+}
+
+// EmptyListError is returned when taking apart the empty list.
+type EmptyListError struct {
+	Op string
+}
+
+func (e EmptyListError) runtimeError() {}
+
+func (e EmptyListError) Error() string {
+	return fmt.Sprintf("%s of the empty list", e.Op)
+}
+
+// UnsupportedExprError is returned when an evaluator is given an expression
+// its language does not have, eg a RefExpr to a LetLangEval.
+type UnsupportedExprError struct {
+	Expr Expr
+}
+
+func (e UnsupportedExprError) runtimeError() {}
+
+func (e UnsupportedExprError) Error() string {
+	return fmt.Sprintf("unsupported expression %T: %s", e.Expr, e.Expr.Repr())
+}
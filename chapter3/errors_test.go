@@ -0,0 +1,83 @@
+package chapter3
+
+import (
+	"errors"
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func evalError(t *testing.T, e Evaluator, expr Expr) error {
+	_, err := e.Eval(expr, epl.NewEnv[any](nil))
+	require.Error(t, err, "Expected an error evaluating %s", expr.Repr())
+	return err
+}
+
+func TestUnboundVariableError(t *testing.T) {
+	err := evalError(t, NewTestLetLangEval(), Op("-", Var("y"), 1))
+	var unbound UnboundVariableError
+	require.True(t, errors.As(err, &unbound))
+	assert.Equal(t, "y", unbound.Name)
+
+	var rt RuntimeError
+	assert.True(t, errors.As(err, &rt))
+}
+
+func TestUnknownOperatorError(t *testing.T) {
+	err := evalError(t, NewTestLetLangEval(), Op("%", 1, 2))
+	var unknown UnknownOperatorError
+	require.True(t, errors.As(err, &unknown))
+	assert.Equal(t, "%", unknown.Op)
+}
+
+func TestUnsupportedExprError(t *testing.T) {
+	// LetLang has no procedures
+	err := evalError(t, NewTestLetLangEval(), Proc([]string{"x"}, Var("x")))
+	var unsupported UnsupportedExprError
+	require.True(t, errors.As(err, &unsupported))
+	assert.IsType(t, &ProcExpr{}, unsupported.Expr)
+
+	var rt RuntimeError
+	assert.True(t, errors.As(err, &rt))
+}
+
+func TestTypeMismatchError(t *testing.T) {
+	var mismatch TypeMismatchError
+
+	err := evalError(t, NewTestLetLangEval(), IsZero(Lit(true)))
+	require.True(t, errors.As(err, &mismatch))
+	assert.Equal(t, "iszero", mismatch.Context)
+	assert.Equal(t, BoolVal(true), mismatch.Found)
+
+	err = evalError(t, NewTestLetLangEval(), Op("+", 1, IsZero(0)))
+	require.True(t, errors.As(err, &mismatch))
+	assert.Equal(t, "'+' operator", mismatch.Context)
+
+	err = evalError(t, NewTestProcLangEval(), Call(Lit(3), Lit(4)))
+	require.True(t, errors.As(err, &mismatch))
+}
+
+func TestArityError(t *testing.T) {
+	var arity ArityError
+
+	err := evalError(t, NewTestLetLangEval(), Op("-", 1, 2, 3))
+	require.True(t, errors.As(err, &arity))
+	assert.Equal(t, 2, arity.Expected)
+	assert.Equal(t, 3, arity.Found)
+
+	// let f = proc(x) x in (f 1 2)
+	err = evalError(t, NewTestProcLangEval(),
+		Let(ExprDict("f", Proc([]string{"x"}, Var("x"))), Call("f", 1, 2)))
+	require.True(t, errors.As(err, &arity))
+	assert.Equal(t, 1, arity.Expected)
+	assert.Equal(t, 2, arity.Found)
+}
+
+func TestErrorsInProcBodyPropagate(t *testing.T) {
+	// let f = proc(x) -(x, y) in (f 1)
+	err := evalError(t, NewTestProcLangEval(),
+		Let(ExprDict("f", Proc([]string{"x"}, Op("-", "x", "y"))), Call("f", 1)))
+	assert.True(t, errors.As(err, &UnboundVariableError{}))
+}
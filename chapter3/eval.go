@@ -8,14 +8,42 @@ import (
 
 type OpFunc func(env *epl.Env[any], args []Expr) (any, error)
 
+// Evaluator is the interface shared by all the evaluators.  Operator
+// libraries (eg SetOpFuncs or the prelude package) register themselves
+// through it.
+type Evaluator interface {
+	Eval(expr Expr, env *epl.Env[any]) (any, error)
+	SetOpFunc(string, OpFunc)
+}
+
 type evaluater interface {
 	This() evaluater
 	LocalEval(expr Expr, env *epl.Env[any]) (any, error)
 }
 
+// EvalHook lets callers intercept the evaluation of every expression, eg to
+// implement a debugger.  Enter is called before an expression is evaluated
+// and Exit after.  Returning an error from Enter aborts evaluation with that
+// error.
+type EvalHook interface {
+	Enter(expr Expr, env *epl.Env[any]) error
+	Exit(expr Expr, env *epl.Env[any], val any, err error)
+}
+
 type BaseEval struct {
 	Self    evaluater
 	OpFuncs map[string]OpFunc
+	Hook    EvalHook
+
+	// When set, errors are wrapped with the Repr of every expression they
+	// propagate through (the old behaviour).  This makes for very long error
+	// messages so it is off by default - use the stack trace instead.
+	VerboseErrors bool
+
+	// The EPL call stack, innermost frame last
+	callStack []Frame
+
+	observers []Observer
 }
 
 func (b *BaseEval) This() evaluater {
@@ -36,23 +64,82 @@ func (b *BaseEval) GetOpFunc(name string) OpFunc {
 	return b.OpFuncs[name]
 }
 
+func (b *BaseEval) SetHook(hook EvalHook) {
+	b.Hook = hook
+}
+
 func (b *BaseEval) Eval(expr Expr, env *epl.Env[any]) (any, error) {
+	var val any
+	var err error
+	if b.Hook != nil {
+		err = b.Hook.Enter(expr, env)
+	}
+	if err == nil {
+		b.notifyEnterExpr(expr, env)
+		val, err = b.Self.LocalEval(expr, env)
+		b.notifyExitExpr(expr, env, val, err)
+	}
+	if b.Hook != nil {
+		b.Hook.Exit(expr, env, val, err)
+	}
+
 	// Error check result before returning
-	val, err := b.Self.LocalEval(expr, env)
 	if err != nil {
-		// Optionally log or wrap error here
-		return nil, fmt.Errorf("evaluating %s: %w", expr.Repr(), err)
+		if b.VerboseErrors {
+			err = fmt.Errorf("evaluating %s: %w", expr.Repr(), err)
+		}
+		// Attach the call stack the first time we see an error so it
+		// reflects where the error actually happened
+		if TracebackOf(err) == nil {
+			err = &TracedError{Err: err, Trace: b.StackTrace()}
+		}
+		return nil, err
 	}
 	return val, nil
 }
 
+// WrapError adds context to an error when VerboseErrors is enabled and returns
+// it unchanged otherwise.
+func (b *BaseEval) WrapError(err error, format string, args ...any) error {
+	if !b.VerboseErrors {
+		return err
+	}
+	return fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), err)
+}
+
+// PushFrame adds a new frame to the top of the EPL call stack.
+func (b *BaseEval) PushFrame(f Frame) {
+	b.callStack = append(b.callStack, f)
+}
+
+// PopFrame removes the top most frame from the EPL call stack.
+func (b *BaseEval) PopFrame() {
+	if len(b.callStack) > 0 {
+		b.callStack = b.callStack[:len(b.callStack)-1]
+	}
+}
+
+// CallDepth returns the number of frames on the EPL call stack.
+func (b *BaseEval) CallDepth() int {
+	return len(b.callStack)
+}
+
+// StackTrace returns a snapshot of the current EPL call stack.
+func (b *BaseEval) StackTrace() *StackTrace {
+	frames := make([]Frame, len(b.callStack))
+	for i, f := range b.callStack {
+		frames[len(frames)-1-i] = f
+	}
+	return &StackTrace{Frames: frames}
+}
+
 func (b *BaseEval) EvalExprList(exprs []Expr, env *epl.Env[any]) ([]any, error) {
 	out := make([]any, len(exprs))
 	for i, exp := range exprs {
-		val, err := b.Self.LocalEval(exp, env) // Use Eval which returns (any, error)
+		val, err := b.Eval(exp, env) // Use Eval which returns (any, error)
 		if err != nil {
 			// If any expression fails, stop and return the error
-			return nil, fmt.Errorf("evaluating argument %d (%s): %w", i, exp.Repr(), err)
+			return nil, b.WrapError(err, "evaluating argument %d (%s)", i, exp.Repr())
 		}
 		out[i] = val
 	}
@@ -2,6 +2,7 @@ package chapter3
 
 import (
 	"log"
+	"math/big"
 	"reflect"
 	"strings"
 
@@ -117,9 +118,7 @@ func AnyToExpr(x any) Expr {
 		return x
 	case string:
 		return Var(x)
-	case int:
-		return Lit(x)
-	case bool:
+	case int, float64, bool, *big.Int, *big.Rat:
 		return Lit(x)
 	default:
 		log.Fatalf("Cannot convert %v (type: %v) to Expr", x, reflect.TypeOf(x))
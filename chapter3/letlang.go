@@ -2,15 +2,13 @@ package chapter3
 
 import (
 	"fmt"
-	"log"
-	"reflect"
 
 	epl "github.com/panyam/eplgo"
 	gfn "github.com/panyam/goutils/fn"
 )
 
 type LitExpr struct {
-	// can only be string, int, float or bool or one of the other lit types
+	// can only be string, int, float64, bool, *big.Int or *big.Rat
 	Value any
 }
 
@@ -21,13 +19,17 @@ func Lit(val any) *LitExpr {
 }
 
 func (l *LitExpr) Eq(another *LitExpr) bool {
-	// if type(another) == type(self.value): return self.value == another
-	// elif type(self) != type(another): return False
+	// Compare as values so big numbers are compared by value not pointer
+	v1, ok1 := ToValue(l.Value)
+	v2, ok2 := ToValue(another.Value)
+	if ok1 && ok2 {
+		return v1.Eq(v2)
+	}
 	return l.Value == another.Value
 }
 
 func (l *LitExpr) Repr() string {
-	return fmt.Sprintf("Val(%v:%v)", l.Value, reflect.TypeOf(l.Value).Name())
+	return fmt.Sprintf("Val(%v:%T)", l.Value, l.Value)
 }
 
 func (l *LitExpr) Printable() *epl.Printable {
@@ -276,15 +278,30 @@ func (l *LetLangEval) LocalEval(expr Expr, env *epl.Env[any]) (any, error) {
 		return l.ValueOfLetExpr(n, env)
 	case *TupleExpr:
 		return l.ValueOfTupleExpr(n, env)
-	default:
-		// log.Printf("Invalid type: %v - %v", n, reflect.TypeOf(n))
+	case *ListExpr:
+		return l.ValueOfListExpr(n, env)
+	case *UnpackExpr:
+		return l.ValueOfUnpackExpr(n, env)
+	case *TupleRefExpr:
+		return l.ValueOfTupleRefExpr(n, env)
+	case *RecordExpr:
+		return l.ValueOfRecordExpr(n, env)
+	case *FieldExpr:
+		return l.ValueOfFieldExpr(n, env)
+	case *UpdateExpr:
+		return l.ValueOfUpdateExpr(n, env)
 	}
-	panic("Invalid type")
+	// None of the evaluators in the chain handle this kind of expression
+	return nil, UnsupportedExprError{Expr: expr}
 }
 
 // Evalute the value of a literal
 func (l *LetLangEval) ValueOfLit(lit *LitExpr, env *epl.Env[any]) (any, error) {
-	return lit, nil
+	val, ok := ToValue(lit.Value)
+	if !ok {
+		return nil, TypeMismatchError{Context: "literal", Expected: "a number, bool or string", Found: lit.Value}
+	}
+	return val, nil
 }
 
 // Evaluate the value of a variable
@@ -292,7 +309,7 @@ func (l *LetLangEval) ValueOfVar(e *VarExpr, env *epl.Env[any]) (any, error) {
 	// TODO - Error and type checking
 	val, found := env.Get(e.Name)
 	if !found {
-		return nil, fmt.Errorf("variable '%s' not found in environment", e.Name)
+		return nil, UnboundVariableError{Name: e.Name}
 	}
 	return val, nil
 }
@@ -301,8 +318,7 @@ func (l *LetLangEval) ValueOfOpExpr(e *OpExpr, env *epl.Env[any]) (any, error) {
 	// TODO - Error and type checking
 	opfunc := l.GetOpFunc(e.Op)
 	if opfunc == nil {
-		log.Fatalf("opfunc not found: %s", e.Op)
-		panic("opfunc not found")
+		return nil, UnknownOperatorError{Op: e.Op}
 	}
 	return opfunc(env, e.Args)
 }
@@ -313,14 +329,8 @@ func (l *LetLangEval) ValueOfIfExpr(e *IfExpr, env *epl.Env[any]) (any, error) {
 		return nil, err // Propagate error
 	}
 
-	condBool := false
-
-	// Define truthiness: only Lit(true) is true, others (incl. Lit(false)) are false
-	if litCond, ok := condVal.(*LitExpr); ok {
-		if boolVal, ok2 := litCond.Value.(bool); ok2 {
-			condBool = boolVal // Use the actual boolean value
-		}
-	} // Any non-literal result (like a BoundProc) also counts as false
+	// Define truthiness: only true is true, others (incl. false) are false
+	condBool, _ := condVal.(BoolVal) // Any non-boolean result (like a procedure) also counts as false
 
 	// log.Printf("If condition %s evaluated to %v (%T), bool result: %v\n", e.Cond.Repr(), condVal, condVal, condBool)
 
@@ -336,8 +346,13 @@ func (l *LetLangEval) ValueOfTupleExpr(e *TupleExpr, env *epl.Env[any]) (any, er
 	if err != nil {
 		return nil, err // Propagate error from list eval
 	}
-	// Return the slice directly
-	return vals, nil
+	out := make(TupleVal, len(vals))
+	for i, val := range vals {
+		if out[i], err = AsValue("tuple", val); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
 }
 
 func (l *LetLangEval) ValueOfIsZeroExpr(e *IsZeroExpr, env *epl.Env[any]) (any, error) {
@@ -345,16 +360,11 @@ func (l *LetLangEval) ValueOfIsZeroExpr(e *IsZeroExpr, env *epl.Env[any]) (any,
 	if err != nil {
 		return nil, err
 	}
-	litVal, ok := val.(*LitExpr)
-	if !ok {
-		return nil, fmt.Errorf("iszero expected a LitExpr argument, got %T (%v) for expr %s", val, val, e.Expr.Repr())
-	}
-	// For now, assume IsZero only works on ints
-	intVal, ok := litVal.Value.(int)
+	num, ok := val.(Number)
 	if !ok {
-		panic(fmt.Sprintf("iszero expected an integer value, got %T (%v)", litVal.Value, litVal.Value))
+		return nil, TypeMismatchError{Context: "iszero", Expected: "a number", Found: val}
 	}
-	return Lit(intVal == 0), nil // Return *LitExpr(bool)
+	return BoolVal(num.IsZero()), nil
 }
 
 type ExprMap = map[string]Expr
@@ -365,7 +375,7 @@ func (l *LetLangEval) ValueOfLetExpr(e *LetExpr, env *epl.Env[any]) (any, error)
 		// log.Printf("Evaluating let binding: %s = %s\n", k, v.Repr())
 		val, err := l.Eval(v, env) // Eval returns (any, error)
 		if err != nil {
-			return nil, fmt.Errorf("evaluating binding '%s': %w", k, err) // Propagate error
+			return nil, l.WrapError(err, "evaluating binding '%s'", k) // Propagate error
 		}
 		bindings[k] = val
 		// log.Printf("Binding %s evaluated to: %v (%T)\n", k, bindings[k], bindings[k])
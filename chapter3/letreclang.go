@@ -40,7 +40,7 @@ func (v *LetRecExpr) Printable() *epl.Printable {
 			proc := v.Procs[name]
 			// Reuse ProcExpr's Printable, adjusting indentation maybe?
 			// Or construct manually here:
-			if !yield(epl.Printablef(1, "%s (%s) =", name, strings.Join(proc.Varnames, ", "))) {
+			if !yield(epl.Printablef(1, "%s %s =", name, proc.signature())) {
 				return
 			}
 			// Indent the body of the proc under its declaration
@@ -83,7 +83,7 @@ func (v *LetRecExpr) Repr() string {
 	var procStrs []string
 	for name, proc := range v.Procs {
 		// Simplified representation for brevity
-		procStrs = append(procStrs, fmt.Sprintf("%s(%s)=%s", name, strings.Join(proc.Varnames, ","), proc.Body.Repr()))
+		procStrs = append(procStrs, fmt.Sprintf("%s%s=%s", name, proc.signature(), proc.Body.Repr()))
 	}
 	return fmt.Sprintf("<LetRec {%s} in %s>", strings.Join(procStrs, "; "), v.Body.Repr())
 }
@@ -131,7 +131,7 @@ func (l *LetRecLangEval) ValueOfLetRec(e *LetRecExpr, env *epl.Env[any]) (any, e
 	// 3. Populate the new environment with these bound procedures.
 	// TODO can steps 2 and 3 be merged in a single loop?
 	for name, boundProc := range boundProcs {
-		newenv.Set(name, boundProc)
+		newenv.Set(name, ProcVal{boundProc})
 	}
 	// log.Printf("Newenv after binding letrec procs: %s", newenv)
 
@@ -0,0 +1,223 @@
+package chapter3
+
+import (
+	"fmt"
+	"strings"
+
+	epl "github.com/panyam/eplgo"
+	gfn "github.com/panyam/goutils/fn"
+)
+
+// ListVal is an immutable singly linked list.  The empty list has a nil Tail,
+// and a nil *ListVal is empty too.  Lists share structure so cons is
+// constant time.
+type ListVal struct {
+	Head Value
+	Tail *ListVal
+}
+
+// EmptyList returns the empty list.
+func EmptyList() *ListVal {
+	return &ListVal{}
+}
+
+// Cons returns a list with head in front of tail.  A nil tail is the empty
+// list.
+func Cons(head Value, tail *ListVal) *ListVal {
+	if tail == nil {
+		tail = EmptyList()
+	}
+	return &ListVal{Head: head, Tail: tail}
+}
+
+// NewList returns a list of the given values in order.
+func NewList(items ...Value) *ListVal {
+	out := EmptyList()
+	for i := len(items) - 1; i >= 0; i-- {
+		out = Cons(items[i], out)
+	}
+	return out
+}
+
+// IsEmpty returns true for the empty list.
+func (v *ListVal) IsEmpty() bool {
+	return v == nil || v.Tail == nil
+}
+
+// Items returns the values in the list in order.
+func (v *ListVal) Items() (out []Value) {
+	for l := v; !l.IsEmpty(); l = l.Tail {
+		out = append(out, l.Head)
+	}
+	return
+}
+
+func (v *ListVal) String() string {
+	return "[" + strings.Join(gfn.Map(v.Items(), Value.String), ", ") + "]"
+}
+
+func (v *ListVal) Repr() string {
+	return "List(" + strings.Join(gfn.Map(v.Items(), Value.Repr), ", ") + ")"
+}
+
+// Eq is true if both lists have equal items.
+func (v *ListVal) Eq(another Value) bool {
+	a, ok := another.(*ListVal)
+	if !ok {
+		return false
+	}
+	l1, l2 := v, a
+	for ; !l1.IsEmpty() && !l2.IsEmpty(); l1, l2 = l1.Tail, l2.Tail {
+		if !l1.Head.Eq(l2.Head) {
+			return false
+		}
+	}
+	return l1.IsEmpty() && l2.IsEmpty()
+}
+
+// ListExpr is a list literal - list(e1, e2, ...).
+type ListExpr struct {
+	Items []Expr
+}
+
+func List(items ...any) *ListExpr {
+	return &ListExpr{Items: gfn.Map(items, AnyToExpr)}
+}
+
+func (e *ListExpr) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "List")) {
+			return
+		}
+		ExprListPrintable(1, e.Items, yield)
+	})
+}
+
+func (e *ListExpr) Eq(another *ListExpr) bool {
+	return ExprListEq(e.Items, another.Items)
+}
+
+func (e *ListExpr) Repr() string {
+	return fmt.Sprintf("<List(%s)>", ExprListRepr(e.Items))
+}
+
+func (l *LetLangEval) ValueOfListExpr(e *ListExpr, env *epl.Env[any]) (any, error) {
+	vals, err := l.EvalExprList(e.Items, env)
+	if err != nil {
+		return nil, err
+	}
+	return listOf(vals)
+}
+
+func listOf(vals []any) (*ListVal, error) {
+	items := make([]Value, len(vals))
+	for i, val := range vals {
+		var err error
+		if items[i], err = AsValue("list", val); err != nil {
+			return nil, err
+		}
+	}
+	return NewList(items...), nil
+}
+
+// EvalList evaluates an operand that must be a list.
+func EvalList(e Evaluator, op string, arg Expr, env *epl.Env[any]) (*ListVal, error) {
+	val, err := e.Eval(arg, env)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := val.(*ListVal)
+	if !ok {
+		return nil, TypeMismatchError{Context: "'" + op + "' operator", Expected: "a list", Found: val}
+	}
+	return list, nil
+}
+
+// EvalNonEmptyList evaluates an operand that must be a non empty list.
+func EvalNonEmptyList(e Evaluator, op string, arg Expr, env *epl.Env[any]) (*ListVal, error) {
+	list, err := EvalList(e, op, arg, env)
+	if err == nil && list.IsEmpty() {
+		err = EmptyListError{Op: op}
+	}
+	return list, err
+}
+
+// SetListOpFuncs registers the list operators on an evaluator:
+//
+//	emptylist()      the empty list
+//	cons(v, lst)     a list with v in front of lst
+//	car(lst)         the first item of a non empty list
+//	cdr(lst)         all but the first item of a non empty list
+//	null?(lst)       true if lst is empty (also available as isnull)
+//	list(v1, v2 ...) a list of the given values
+func SetListOpFuncs(e Evaluator) Evaluator {
+	e.SetOpFunc("emptylist", func(env *epl.Env[any], args []Expr) (any, error) {
+		if err := CheckArity("emptylist", args, 0); err != nil {
+			return nil, err
+		}
+		return EmptyList(), nil
+	})
+	e.SetOpFunc("cons", func(env *epl.Env[any], args []Expr) (any, error) {
+		if err := CheckArity("cons", args, 2); err != nil {
+			return nil, err
+		}
+		val, err := e.Eval(args[0], env)
+		if err != nil {
+			return nil, err
+		}
+		head, err := AsValue("'cons' operator", val)
+		if err != nil {
+			return nil, err
+		}
+		tail, err := EvalList(e, "cons", args[1], env)
+		if err != nil {
+			return nil, err
+		}
+		return Cons(head, tail), nil
+	})
+	e.SetOpFunc("car", func(env *epl.Env[any], args []Expr) (any, error) {
+		if err := CheckArity("car", args, 1); err != nil {
+			return nil, err
+		}
+		list, err := EvalNonEmptyList(e, "car", args[0], env)
+		if err != nil {
+			return nil, err
+		}
+		return list.Head, nil
+	})
+	e.SetOpFunc("cdr", func(env *epl.Env[any], args []Expr) (any, error) {
+		if err := CheckArity("cdr", args, 1); err != nil {
+			return nil, err
+		}
+		list, err := EvalNonEmptyList(e, "cdr", args[0], env)
+		if err != nil {
+			return nil, err
+		}
+		return list.Tail, nil
+	})
+	isNull := func(op string) OpFunc {
+		return func(env *epl.Env[any], args []Expr) (any, error) {
+			if err := CheckArity(op, args, 1); err != nil {
+				return nil, err
+			}
+			list, err := EvalList(e, op, args[0], env)
+			if err != nil {
+				return nil, err
+			}
+			return BoolVal(list.IsEmpty()), nil
+		}
+	}
+	e.SetOpFunc("null?", isNull("null?"))
+	e.SetOpFunc("isnull", isNull("isnull"))
+	e.SetOpFunc("list", func(env *epl.Env[any], args []Expr) (any, error) {
+		vals := make([]any, len(args))
+		for i, arg := range args {
+			var err error
+			if vals[i], err = e.Eval(arg, env); err != nil {
+				return nil, err
+			}
+		}
+		return listOf(vals)
+	})
+	return e
+}
@@ -0,0 +1,78 @@
+package chapter3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func NewTestListEval() Evaluator {
+	return SetListOpFuncs(NewTestLetRecLangEval())
+}
+
+func TestListOps(t *testing.T) {
+	cases := []TestCase{
+		{"emptylist", EmptyList(), Op("emptylist")},
+		{"list_literal", NewList(IntVal(1), IntVal(2), IntVal(3)), List(1, 2, Op("+", 1, 2))},
+		{"list_op", NewList(IntVal(1), BoolVal(true)), Op("list", 1, IsZero(0))},
+		{"cons", NewList(IntVal(0), IntVal(1)), Op("cons", 0, List(1))},
+		{"car", 1, Op("car", List(1, 2))},
+		{"cdr", NewList(IntVal(2)), Op("cdr", List(1, 2))},
+		{"null_empty", true, Op("null?", Op("emptylist"))},
+		{"null_nonempty", false, Op("isnull", List(1))},
+		{"nested", NewList(NewList(IntVal(1)), EmptyList()), List(List(1), List())},
+	}
+	for _, tc := range cases {
+		RunTest(t, NewTestListEval(), &tc, nil)
+	}
+}
+
+func TestListRecursion(t *testing.T) {
+	// letrec length(lst) = if null?(lst) then 0 else -((length cdr(lst)), -1)
+	// in (length list(5, 6, 7))
+	expr := LetRec(ProcMap("length", Proc([]string{"lst"},
+		If(Op("null?", "lst"), 0, Op("-", Call("length", Op("cdr", "lst")), -1)))),
+		Call("length", List(5, 6, 7)))
+	tc := TestCase{"length", 3, expr}
+	RunTest(t, NewTestListEval(), &tc, nil)
+}
+
+func TestListPrinting(t *testing.T) {
+	list := NewList(IntVal(1), StringVal("a"), NewList(BoolVal(true)))
+	assert.Equal(t, "[1, a, [true]]", list.String())
+	assert.Equal(t, `List(Int(1), String("a"), List(Bool(true)))`, list.Repr())
+	assert.Equal(t, "[]", EmptyList().String())
+	assert.False(t, NewList(IntVal(1)).Eq(NewList(IntVal(1), IntVal(2))))
+	assert.Equal(t, "<List(Val(1:int), <Var(x)>)>", List(1, "x").Repr())
+	assert.True(t, ExprEq(List(1, "x"), List(1, "x")))
+}
+
+func TestNilLists(t *testing.T) {
+	// A nil tail or list is the empty list
+	single := Cons(IntVal(1), nil)
+	assert.False(t, single.IsEmpty())
+	assert.Equal(t, []Value{IntVal(1)}, single.Items())
+	assert.True(t, single.Eq(NewList(IntVal(1))))
+	var none *ListVal
+	assert.True(t, none.IsEmpty())
+	assert.Empty(t, none.Items())
+	assert.True(t, none.Eq(EmptyList()))
+	assert.Equal(t, "[]", none.String())
+}
+
+func TestEmptyListErrors(t *testing.T) {
+	for _, op := range []string{"car", "cdr"} {
+		err := evalError(t, NewTestListEval(), Op(op, Op("emptylist")))
+		var empty EmptyListError
+		require.ErrorAs(t, err, &empty)
+		assert.Equal(t, op, empty.Op)
+		var rt RuntimeError
+		assert.ErrorAs(t, err, &rt)
+	}
+
+	var mismatch TypeMismatchError
+	err := evalError(t, NewTestListEval(), Op("cons", 1, 2))
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, "a list", mismatch.Expected)
+}
@@ -0,0 +1,116 @@
+package chapter3
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// NativeProc is a procedure implemented in Go rather than EPL - see the ffi
+// package for binding ordinary Go functions.  Native procedures are called
+// and curried just like a ProcVal: calling one with fewer arguments than its
+// Arity returns a NativeProc waiting for the rest, and any arguments beyond
+// its Arity are passed on to the procedure it returns.
+//
+// Observers are not notified of native calls as there is no BoundProc for
+// them, but they do get a Frame on the EPL call stack.
+type NativeProc struct {
+	Name string
+
+	// Number of arguments Fn takes.  If Variadic this is the minimum and Fn
+	// is given every argument supplied.
+	Arity    int
+	Variadic bool
+
+	Fn func(args []Value) (Value, error)
+
+	// Arguments supplied by earlier curried calls
+	bound []Value
+}
+
+// Pending returns the number of arguments the procedure still needs.
+func (v *NativeProc) Pending() int {
+	return v.Arity - len(v.bound)
+}
+
+func (v *NativeProc) String() string {
+	arity := fmt.Sprintf("%d", v.Pending())
+	if v.Variadic {
+		arity += "+"
+	}
+	return fmt.Sprintf("<native %s/%s>", v.Name, arity)
+}
+
+func (v *NativeProc) Repr() string {
+	return fmt.Sprintf("Native(%s, Arity:%d, Bound:%d)", v.Name, v.Arity, len(v.bound))
+}
+
+// Eq is true only for the same native procedure.
+func (v *NativeProc) Eq(another Value) bool {
+	a, ok := another.(*NativeProc)
+	return ok && a == v
+}
+
+// NativeError wraps an error returned by a native procedure that is not
+// already a RuntimeError, so that it can be caught like any other.
+type NativeError struct {
+	Proc string
+	Err  error
+}
+
+func (e NativeError) runtimeError() {}
+
+func (e NativeError) Error() string {
+	return fmt.Sprintf("native procedure '%s': %v", e.Proc, e.Err)
+}
+
+func (e NativeError) Unwrap() error { return e.Err }
+
+func (l *ProcLangEval) applyNative(proc *NativeProc, args []any) (any, error) {
+	if len(args) == 0 && proc.Arity > len(proc.bound) {
+		return nil, ArityError{Context: "Procedure " + proc.String(), Expected: proc.Arity - len(proc.bound), Found: 0}
+	}
+	vals := slices.Clone(proc.bound)
+	for _, arg := range args {
+		val, err := AsValue("argument of "+proc.String(), arg)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, val)
+	}
+	if len(vals) < proc.Arity {
+		curried := *proc
+		curried.bound = vals
+		return &curried, nil
+	}
+
+	n := proc.Arity
+	if proc.Variadic {
+		n = len(vals)
+	}
+	result, err := proc.Fn(vals[:n])
+	if err != nil {
+		var rt RuntimeError
+		if !errors.As(err, &rt) {
+			err = NativeError{Proc: proc.Name, Err: err}
+		}
+		// Trace here as the native procedure's frame is popped before Eval sees the error
+		if TracebackOf(err) == nil {
+			err = &TracedError{Err: err, Trace: l.StackTrace()}
+		}
+		return nil, err
+	}
+
+	// Pass any extra arguments on to the procedure returned
+	rest := args[n-len(proc.bound):]
+	if len(rest) == 0 {
+		return result, nil
+	}
+	switch next := result.(type) {
+	case ProcVal:
+		return l.applyProc(next.BoundProc, rest)
+	case *NativeProc:
+		return l.applyNative(next, rest)
+	}
+	return nil, ArityError{Context: "Procedure " + proc.String(), Expected: proc.Arity, Found: len(vals)}
+}
@@ -0,0 +1,265 @@
+package chapter3
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Number is a numeric Value.  Numbers form a tower - IntVal, BigIntVal, RatVal
+// and FloatVal - and arithmetic on mixed types promotes both operands to the
+// higher of the two.  Results are normalized so an integer that fits in an int
+// is always an IntVal and a rational with a denominator of 1 an integer.
+type Number interface {
+	Value
+	IsZero() bool
+	rank() int
+}
+
+const (
+	rankInt = iota
+	rankBigInt
+	rankRat
+	rankFloat
+)
+
+func (v IntVal) IsZero() bool { return v == 0 }
+func (v IntVal) rank() int    { return rankInt }
+
+// BigIntVal is an integer too large for an IntVal.
+type BigIntVal struct {
+	*big.Int
+}
+
+func (v BigIntVal) String() string { return v.Int.String() }
+func (v BigIntVal) Repr() string   { return "Int(" + v.Int.String() + ")" }
+func (v BigIntVal) IsZero() bool   { return v.Sign() == 0 }
+func (v BigIntVal) rank() int      { return rankBigInt }
+func (v BigIntVal) Eq(another Value) bool {
+	a, ok := another.(BigIntVal)
+	return ok && a.Cmp(v.Int) == 0
+}
+
+// RatVal is an exact rational number.
+type RatVal struct {
+	*big.Rat
+}
+
+func (v RatVal) String() string { return v.Rat.String() }
+func (v RatVal) Repr() string   { return "Rat(" + v.Rat.String() + ")" }
+func (v RatVal) IsZero() bool   { return v.Sign() == 0 }
+func (v RatVal) rank() int      { return rankRat }
+func (v RatVal) Eq(another Value) bool {
+	a, ok := another.(RatVal)
+	return ok && a.Cmp(v.Rat) == 0
+}
+
+// FloatVal is a floating point number.
+type FloatVal float64
+
+// Like Python, floats always print with a fractional part or exponent.
+func (v FloatVal) String() string {
+	out := strconv.FormatFloat(float64(v), 'g', -1, 64)
+	if !strings.ContainsAny(out, ".eIN") {
+		out += ".0"
+	}
+	return out
+}
+
+func (v FloatVal) Repr() string { return "Float(" + v.String() + ")" }
+func (v FloatVal) IsZero() bool { return v == 0 }
+func (v FloatVal) rank() int    { return rankFloat }
+func (v FloatVal) Eq(another Value) bool {
+	a, ok := another.(FloatVal)
+	return ok && a == v
+}
+
+// NormalizeInt returns a big integer as an IntVal if it fits.
+func NormalizeInt(x *big.Int) Number {
+	if x.IsInt64() && x.Int64() >= math.MinInt && x.Int64() <= math.MaxInt {
+		return IntVal(x.Int64())
+	}
+	return BigIntVal{x}
+}
+
+// NormalizeRat returns a rational as an integer if its denominator is 1.
+func NormalizeRat(x *big.Rat) Number {
+	if x.IsInt() {
+		return NormalizeInt(new(big.Int).Set(x.Num()))
+	}
+	return RatVal{x}
+}
+
+func toBigInt(n Number) *big.Int {
+	switch n := n.(type) {
+	case IntVal:
+		return big.NewInt(int64(n))
+	case BigIntVal:
+		return n.Int
+	}
+	panic("not an integer")
+}
+
+func toRat(n Number) *big.Rat {
+	switch n := n.(type) {
+	case IntVal, BigIntVal:
+		return new(big.Rat).SetInt(toBigInt(n))
+	case RatVal:
+		return n.Rat
+	}
+	panic("not a rational")
+}
+
+func toFloat(n Number) float64 {
+	switch n := n.(type) {
+	case IntVal:
+		return float64(n)
+	case BigIntVal:
+		f, _ := new(big.Float).SetInt(n.Int).Float64()
+		return f
+	case RatVal:
+		f, _ := n.Float64()
+		return f
+	case FloatVal:
+		return float64(n)
+	}
+	panic("not a number")
+}
+
+// Add returns a + b.
+func Add(a, b Number) Number {
+	if x, ok := a.(IntVal); ok {
+		if y, ok := b.(IntVal); ok {
+			if c := x + y; (x^c)&(y^c) >= 0 {
+				return c
+			}
+		}
+	}
+	switch max(a.rank(), b.rank()) {
+	case rankFloat:
+		return FloatVal(toFloat(a) + toFloat(b))
+	case rankRat:
+		return NormalizeRat(new(big.Rat).Add(toRat(a), toRat(b)))
+	}
+	return NormalizeInt(new(big.Int).Add(toBigInt(a), toBigInt(b)))
+}
+
+// Sub returns a - b.
+func Sub(a, b Number) Number {
+	if x, ok := a.(IntVal); ok {
+		if y, ok := b.(IntVal); ok {
+			if c := x - y; (x^y)&(x^c) >= 0 {
+				return c
+			}
+		}
+	}
+	switch max(a.rank(), b.rank()) {
+	case rankFloat:
+		return FloatVal(toFloat(a) - toFloat(b))
+	case rankRat:
+		return NormalizeRat(new(big.Rat).Sub(toRat(a), toRat(b)))
+	}
+	return NormalizeInt(new(big.Int).Sub(toBigInt(a), toBigInt(b)))
+}
+
+// Mul returns a * b.
+func Mul(a, b Number) Number {
+	if x, ok := a.(IntVal); ok {
+		if y, ok := b.(IntVal); ok {
+			if x == 0 || y == 0 {
+				return IntVal(0)
+			}
+			if c := x * y; c/y == x && !(x == -1 && y == math.MinInt) && !(y == -1 && x == math.MinInt) {
+				return c
+			}
+		}
+	}
+	switch max(a.rank(), b.rank()) {
+	case rankFloat:
+		return FloatVal(toFloat(a) * toFloat(b))
+	case rankRat:
+		return NormalizeRat(new(big.Rat).Mul(toRat(a), toRat(b)))
+	}
+	return NormalizeInt(new(big.Int).Mul(toBigInt(a), toBigInt(b)))
+}
+
+// Div returns a / b.  As in Python dividing two integers is true division and
+// results in a float, while dividing exact rationals results in a rational.
+func Div(a, b Number) (Number, error) {
+	if b.IsZero() {
+		return nil, DivisionByZeroError{}
+	}
+	switch max(a.rank(), b.rank()) {
+	case rankFloat, rankInt, rankBigInt:
+		return FloatVal(toFloat(a) / toFloat(b)), nil
+	}
+	return NormalizeRat(new(big.Rat).Quo(toRat(a), toRat(b))), nil
+}
+
+// Mod returns a modulo b.  As in Python the result has the sign of b, so
+// that a == b * floor(a / b) + Mod(a, b).
+func Mod(a, b Number) (Number, error) {
+	if b.IsZero() {
+		return nil, DivisionByZeroError{}
+	}
+	if x, ok := a.(IntVal); ok {
+		if y, ok := b.(IntVal); ok {
+			if y == -1 {
+				return IntVal(0), nil
+			}
+			r := x % y
+			if r != 0 && (r < 0) != (y < 0) {
+				r += y
+			}
+			return r, nil
+		}
+	}
+	switch max(a.rank(), b.rank()) {
+	case rankFloat:
+		x, y := toFloat(a), toFloat(b)
+		r := math.Mod(x, y)
+		if r != 0 && (r < 0) != (y < 0) {
+			r += y
+		}
+		return FloatVal(r), nil
+	case rankRat:
+		x, y := toRat(a), toRat(b)
+		q := new(big.Rat).Quo(x, y)
+		// Denominators are always positive so Euclidean division floors
+		floor := new(big.Rat).SetInt(new(big.Int).Div(q.Num(), q.Denom()))
+		return NormalizeRat(new(big.Rat).Sub(x, floor.Mul(floor, y))), nil
+	}
+	y := toBigInt(b)
+	r := new(big.Int).Mod(toBigInt(a), y)
+	if r.Sign() != 0 && y.Sign() < 0 {
+		r.Add(r, y)
+	}
+	return NormalizeInt(r), nil
+}
+
+// Compare returns -1, 0 or 1 depending on whether a is less than, equal to or
+// greater than b.
+func Compare(a, b Number) int {
+	switch max(a.rank(), b.rank()) {
+	case rankFloat:
+		x, y := toFloat(a), toFloat(b)
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+		return 0
+	case rankRat:
+		return toRat(a).Cmp(toRat(b))
+	case rankBigInt:
+		return toBigInt(a).Cmp(toBigInt(b))
+	}
+	x, y := a.(IntVal), b.(IntVal)
+	if x < y {
+		return -1
+	} else if x > y {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,101 @@
+package chapter3
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bigInt(s string) *big.Int {
+	out, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("invalid integer " + s)
+	}
+	return out
+}
+
+func evalValue(t *testing.T, expr Expr) any {
+	val, err := NewTestLetRecLangEval().Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err, "evaluating %s", expr.Repr())
+	return val
+}
+
+func TestIntOverflowPromotes(t *testing.T) {
+	assert.Equal(t, BigIntVal{bigInt("9223372036854775808")}.Repr(), Add(IntVal(math.MaxInt), IntVal(1)).Repr())
+	assert.Equal(t, BigIntVal{bigInt("-9223372036854775809")}.Repr(), Sub(IntVal(math.MinInt), IntVal(1)).Repr())
+	assert.Equal(t, BigIntVal{bigInt("85070591730234615847396907784232501249")}.Repr(), Mul(IntVal(math.MaxInt), IntVal(math.MaxInt)).Repr())
+	assert.Equal(t, "9223372036854775808", Mul(IntVal(math.MinInt), IntVal(-1)).String())
+
+	// Results that fit are demoted back to IntVal
+	assert.Equal(t, IntVal(math.MaxInt), Sub(Add(IntVal(math.MaxInt), IntVal(1)), IntVal(1)))
+}
+
+func TestMixedArithmetic(t *testing.T) {
+	third := RatVal{big.NewRat(1, 3)}
+	assert.Equal(t, "4/3", Add(IntVal(1), third).String())
+	assert.Equal(t, IntVal(1), Mul(IntVal(3), third))
+	assert.Equal(t, FloatVal(1.5), Add(IntVal(1), FloatVal(0.5)))
+	assert.Equal(t, FloatVal(0.5), Sub(RatVal{big.NewRat(3, 4)}, FloatVal(0.25)))
+	assert.True(t, Add(BigIntVal{bigInt("100000000000000000000")}, third).Eq(RatVal{new(big.Rat).SetFrac(bigInt("300000000000000000001"), big.NewInt(3))}))
+
+	assert.Equal(t, -1, Compare(IntVal(1), RatVal{big.NewRat(4, 3)}))
+	assert.Equal(t, 0, Compare(FloatVal(2), IntVal(2)))
+	assert.Equal(t, 1, Compare(BigIntVal{bigInt("100000000000000000000")}, FloatVal(1e10)))
+}
+
+func TestDivision(t *testing.T) {
+	assert.Equal(t, FloatVal(2.5), evalValue(t, Op("/", 5, 2)))
+	assert.Equal(t, FloatVal(2), evalValue(t, Op("/", 4, 2)))
+	assert.Equal(t, "2.0", FloatVal(2).String())
+	assert.Equal(t, "5/6", evalValue(t, Op("/", Lit(big.NewRat(5, 3)), 2)).(Value).String())
+	assert.Equal(t, FloatVal(0.25), evalValue(t, Op("/", 1, 4.0)))
+
+	err := evalError(t, NewTestLetLangEval(), Op("/", 1, Op("-", 2, 2)))
+	assert.ErrorIs(t, err, DivisionByZeroError{})
+	var rt RuntimeError
+	assert.ErrorAs(t, err, &rt)
+}
+
+func TestModulo(t *testing.T) {
+	mod := func(a, b Number) Number {
+		out, err := Mod(a, b)
+		require.NoError(t, err)
+		return out
+	}
+	// The result takes the sign of the divisor
+	assert.Equal(t, IntVal(1), mod(IntVal(7), IntVal(3)))
+	assert.Equal(t, IntVal(2), mod(IntVal(-7), IntVal(3)))
+	assert.Equal(t, IntVal(-2), mod(IntVal(7), IntVal(-3)))
+	assert.Equal(t, IntVal(-1), mod(IntVal(-7), IntVal(-3)))
+	assert.Equal(t, IntVal(0), mod(IntVal(math.MinInt), IntVal(-1)))
+	assert.Equal(t, IntVal(1), mod(BigIntVal{bigInt("100000000000000000001")}, IntVal(10)))
+	assert.Equal(t, IntVal(-9), mod(BigIntVal{bigInt("100000000000000000001")}, IntVal(-10)))
+	assert.Equal(t, RatVal{big.NewRat(1, 6)}, mod(RatVal{big.NewRat(1, 2)}, RatVal{big.NewRat(1, 3)}))
+	assert.Equal(t, RatVal{big.NewRat(1, 6)}, mod(RatVal{big.NewRat(-1, 2)}, RatVal{big.NewRat(1, 3)}))
+	assert.Equal(t, FloatVal(0.5), mod(FloatVal(-1.5), IntVal(2)))
+
+	_, err := Mod(IntVal(1), FloatVal(0))
+	assert.ErrorIs(t, err, DivisionByZeroError{})
+}
+
+func TestNumericLiterals(t *testing.T) {
+	assert.Equal(t, FloatVal(1.5), evalValue(t, Lit(1.5)))
+	assert.Equal(t, IntVal(7), evalValue(t, Lit(big.NewInt(7))))
+	assert.Equal(t, IntVal(2), evalValue(t, Lit(big.NewRat(4, 2))))
+	assert.Equal(t, BoolVal(true), evalValue(t, IsZero(Lit(0.0))))
+	assert.Equal(t, BoolVal(false), evalValue(t, IsZero(Lit(big.NewRat(1, 2)))))
+	assert.True(t, ExprEq(Lit(bigInt("123456789012345678901")), Lit(bigInt("123456789012345678901"))))
+}
+
+func TestFactorial100(t *testing.T) {
+	// letrec fact(n) = if isz(n) then 1 else *(n, (fact -(n,1))) in (fact 100)
+	expr := LetRec(ProcMap("fact", Proc([]string{"n"},
+		If(IsZero("n"), 1, Op("*", "n", Call("fact", Op("-", "n", 1)))))),
+		Call("fact", 100))
+	expected := "93326215443944152681699238856266700490715968264381621468592963895217599993229915608941463976156518286253697920827223758251185210916864000000000000000000000000"
+	assert.True(t, evalValue(t, expr).(Value).Eq(BigIntVal{bigInt(expected)}))
+}
@@ -0,0 +1,165 @@
+package chapter3
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	epl "github.com/panyam/eplgo"
+	gfn "github.com/panyam/goutils/fn"
+)
+
+// Observer receives notifications as an evaluator runs.  Unlike an EvalHook,
+// observers cannot affect evaluation - they are meant for tracers, profilers,
+// coverage tools and visualizers.  Embed NopObserver to only handle some of
+// the events.
+type Observer interface {
+	// Called before and after every expression is evaluated.
+	EnterExpr(expr Expr, env *epl.Env[any])
+	ExitExpr(expr Expr, env *epl.Env[any], val any, err error)
+
+	// Called before and after the body of a procedure is evaluated with the
+	// arguments bound to its parameters.  A curried application of a
+	// procedure only results in these events once all its parameters are
+	// bound.
+	EnterProc(proc *BoundProc, args []any)
+	ExitProc(proc *BoundProc, val any, err error)
+
+	// Called when a reference cell is updated (via setref or set).
+	StoreChanged(ref *epl.Ref[any], oldValue any, newValue any)
+}
+
+// NopObserver implements Observer by ignoring every event.
+type NopObserver struct{}
+
+func (NopObserver) EnterExpr(expr Expr, env *epl.Env[any])                    {}
+func (NopObserver) ExitExpr(expr Expr, env *epl.Env[any], val any, err error) {}
+func (NopObserver) EnterProc(proc *BoundProc, args []any)                     {}
+func (NopObserver) ExitProc(proc *BoundProc, val any, err error)              {}
+func (NopObserver) StoreChanged(ref *epl.Ref[any], oldValue, newValue any)    {}
+
+// AddObserver registers an observer to be notified of evaluation events.
+func (b *BaseEval) AddObserver(o Observer) {
+	b.observers = append(b.observers, o)
+}
+
+// RemoveObserver unregisters a previously added observer.
+func (b *BaseEval) RemoveObserver(o Observer) {
+	for i, existing := range b.observers {
+		if sameObserver(existing, o) {
+			b.observers = append(b.observers[:i], b.observers[i+1:]...)
+			return
+		}
+	}
+}
+
+// sameObserver is true if a and b are the same pointer (or map, chan etc)
+// or equal values of a comparable type.  Observers that are not comparable,
+// eg a struct holding a slice, never match so should be added by pointer.
+func sameObserver(a, b Observer) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() || va.Type() != vb.Type() {
+		return false
+	}
+	switch va.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return va.Pointer() == vb.Pointer()
+	case reflect.Slice:
+		return va.Pointer() == vb.Pointer() && va.Len() == vb.Len()
+	}
+	return va.Comparable() && va.Equal(vb)
+}
+
+// HasObservers returns true if any observers are registered.  Useful to skip
+// preparing event payloads nobody will see.
+func (b *BaseEval) HasObservers() bool {
+	return len(b.observers) > 0
+}
+
+// NotifyStoreChanged tells all observers that a reference cell was updated.
+// Evaluators that mutate references must call this.
+func (b *BaseEval) NotifyStoreChanged(ref *epl.Ref[any], oldValue, newValue any) {
+	for _, o := range b.observers {
+		o.StoreChanged(ref, oldValue, newValue)
+	}
+}
+
+func (b *BaseEval) notifyEnterExpr(expr Expr, env *epl.Env[any]) {
+	for _, o := range b.observers {
+		o.EnterExpr(expr, env)
+	}
+}
+
+func (b *BaseEval) notifyExitExpr(expr Expr, env *epl.Env[any], val any, err error) {
+	for _, o := range b.observers {
+		o.ExitExpr(expr, env, val, err)
+	}
+}
+
+func (b *BaseEval) notifyEnterProc(proc *BoundProc, args []any) {
+	for _, o := range b.observers {
+		o.EnterProc(proc, args)
+	}
+}
+
+func (b *BaseEval) notifyExitProc(proc *BoundProc, val any, err error) {
+	for _, o := range b.observers {
+		o.ExitProc(proc, val, err)
+	}
+}
+
+// Tracer is an Observer that writes an indented line for every procedure
+// entry/exit and store update.  Set Exprs to also trace every expression.
+type Tracer struct {
+	Out   io.Writer
+	Exprs bool
+	depth int
+}
+
+func (t *Tracer) EnterExpr(expr Expr, env *epl.Env[any]) {
+	if t.Exprs {
+		fmt.Fprintf(t.Out, "%s> %s\n", epl.Indent(t.depth), expr.Repr())
+		t.depth++
+	}
+}
+
+func (t *Tracer) ExitExpr(expr Expr, env *epl.Env[any], val any, err error) {
+	if t.Exprs {
+		t.depth--
+		t.printResult(val, err)
+	}
+}
+
+func (t *Tracer) EnterProc(proc *BoundProc, args []any) {
+	name := proc.ProcExpr.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	fmt.Fprintf(t.Out, "%scall %s(%s)\n", epl.Indent(t.depth), name, strings.Join(gfn.Map(args, traceRepr), ", "))
+	t.depth++
+}
+
+func (t *Tracer) ExitProc(proc *BoundProc, val any, err error) {
+	t.depth--
+	t.printResult(val, err)
+}
+
+func (t *Tracer) StoreChanged(ref *epl.Ref[any], oldValue, newValue any) {
+	fmt.Fprintf(t.Out, "%sstore %p: %s -> %s\n", epl.Indent(t.depth), ref, traceRepr(oldValue), traceRepr(newValue))
+}
+
+func (t *Tracer) printResult(val any, err error) {
+	if err != nil {
+		fmt.Fprintf(t.Out, "%s< error: %v\n", epl.Indent(t.depth), err)
+	} else {
+		fmt.Fprintf(t.Out, "%s< %s\n", epl.Indent(t.depth), traceRepr(val))
+	}
+}
+
+func traceRepr(val any) string {
+	if r, ok := val.(interface{ Repr() string }); ok {
+		return r.Repr()
+	}
+	return fmt.Sprintf("%v", val)
+}
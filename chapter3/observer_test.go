@@ -0,0 +1,137 @@
+package chapter3
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Records events as strings
+type recordingObserver struct {
+	NopObserver
+	events []string
+}
+
+func (r *recordingObserver) EnterExpr(expr Expr, env *epl.Env[any]) {
+	r.events = append(r.events, "enter "+expr.Repr())
+}
+
+func (r *recordingObserver) ExitExpr(expr Expr, env *epl.Env[any], val any, err error) {
+	if err != nil {
+		r.events = append(r.events, "error "+expr.Repr())
+	} else {
+		r.events = append(r.events, "exit "+expr.Repr())
+	}
+}
+
+func (r *recordingObserver) EnterProc(proc *BoundProc, args []any) {
+	r.events = append(r.events, fmt.Sprintf("call %s %d", proc.ProcExpr.Name, len(args)))
+}
+
+func (r *recordingObserver) ExitProc(proc *BoundProc, val any, err error) {
+	r.events = append(r.events, "return "+proc.ProcExpr.Name)
+}
+
+func TestObserverExprEvents(t *testing.T) {
+	evaluator := NewLetLangEval()
+	SetOpFuncs(evaluator)
+	obs := &recordingObserver{}
+	evaluator.AddObserver(obs)
+
+	_, err := evaluator.Eval(Op("-", 5, 3), epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"enter <Op(-, [Val(5:int), Val(3:int)])>",
+		"enter Val(5:int)",
+		"exit Val(5:int)",
+		"enter Val(3:int)",
+		"exit Val(3:int)",
+		"exit <Op(-, [Val(5:int), Val(3:int)])>",
+	}, obs.events)
+
+	// Errors are reported on exit too
+	obs.events = nil
+	_, err = evaluator.Eval(Var("nope"), epl.NewEnv[any](nil))
+	require.Error(t, err)
+	assert.Equal(t, []string{"enter <Var(nope)>", "error <Var(nope)>"}, obs.events)
+
+	// No more events once removed
+	obs.events = nil
+	evaluator.RemoveObserver(obs)
+	_, _ = evaluator.Eval(Lit(1), epl.NewEnv[any](nil))
+	assert.Empty(t, obs.events)
+}
+
+// exprCounter is a map so it is not comparable with ==
+type exprCounter map[string]int
+
+func (c exprCounter) EnterExpr(expr Expr, env *epl.Env[any])                    { c["enter"]++ }
+func (c exprCounter) ExitExpr(expr Expr, env *epl.Env[any], val any, err error) {}
+func (c exprCounter) EnterProc(proc *BoundProc, args []any)                     {}
+func (c exprCounter) ExitProc(proc *BoundProc, val any, err error)              {}
+func (c exprCounter) StoreChanged(ref *epl.Ref[any], oldValue, newValue any)    {}
+
+// eventLog is a struct holding a slice so it is not comparable either
+type eventLog struct {
+	NopObserver
+	events []string
+}
+
+func TestRemoveObserverNotComparable(t *testing.T) {
+	evaluator := NewLetLangEval()
+	counter := exprCounter{}
+	evaluator.AddObserver(counter)
+	_, _ = evaluator.Eval(Lit(1), epl.NewEnv[any](nil))
+	assert.Equal(t, 1, counter["enter"])
+
+	// Maps are removed by identity
+	evaluator.RemoveObserver(exprCounter{})
+	assert.True(t, evaluator.HasObservers())
+	evaluator.RemoveObserver(counter)
+	assert.False(t, evaluator.HasObservers())
+
+	// Values that cannot be compared are never matched rather than panicking
+	evaluator.AddObserver(eventLog{events: []string{"a"}})
+	evaluator.RemoveObserver(eventLog{events: []string{"a"}})
+	assert.True(t, evaluator.HasObservers())
+}
+
+func TestObserverProcEvents(t *testing.T) {
+	evaluator := NewLetRecLangEval()
+	SetOpFuncs(evaluator)
+	obs := &recordingObserver{}
+	evaluator.AddObserver(obs)
+
+	// letrec f(x,y) = if (isz y) then x else (f +(x,y)) in (f 1 2 0)
+	expr := LetRec(ProcMap("f", Proc([]string{"x", "y"},
+		If(IsZero("y"), "x", Call("f", Op("+", "x", "y"))))),
+		Call("f", 1, 2, 0))
+	_, err := evaluator.Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+
+	var procEvents []string
+	for _, e := range obs.events {
+		if e[:4] == "call" || e[:6] == "return" {
+			procEvents = append(procEvents, e)
+		}
+	}
+	// (f 1 2) returns (f 3) which is curried and then applied to the 0
+	assert.Equal(t, []string{"call f 2", "return f", "call f 1", "return f"}, procEvents)
+}
+
+func TestTracer(t *testing.T) {
+	var out bytes.Buffer
+	evaluator := NewProcLangEval()
+	SetOpFuncs(evaluator)
+	evaluator.AddObserver(&Tracer{Out: &out})
+
+	// let f = proc(x) -(x,1) in (f 5)
+	expr := Let(ExprDict("f", Proc([]string{"x"}, Op("-", "x", 1))), Call("f", 5))
+	_, err := evaluator.Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "call <anonymous>(Int(5))\n< Int(4)\n")
+}
@@ -0,0 +1,115 @@
+package chapter3
+
+import (
+	epl "github.com/panyam/eplgo"
+)
+
+// Helpers for writing OpFuncs.  The Eval* helpers evaluate an operand and
+// check it has the expected type, returning a TypeMismatchError naming the
+// operator if not.
+
+// CheckArity returns an ArityError unless an operator has exactly the
+// expected number of arguments.
+func CheckArity(op string, args []Expr, expected int) error {
+	if len(args) != expected {
+		return ArityError{Context: "'" + op + "' operator", Expected: expected, Found: len(args)}
+	}
+	return nil
+}
+
+// EvalString evaluates an operand of a string operator.
+func EvalString(e Evaluator, op string, arg Expr, env *epl.Env[any]) (StringVal, error) {
+	val, err := e.Eval(arg, env)
+	if err != nil {
+		return "", err
+	}
+	str, ok := val.(StringVal)
+	if !ok {
+		return "", TypeMismatchError{Context: "'" + op + "' operator", Expected: "a string", Found: val}
+	}
+	return str, nil
+}
+
+// EvalInt evaluates an operand that must be an integer that fits in an int.
+func EvalInt(e Evaluator, op string, arg Expr, env *epl.Env[any]) (int, error) {
+	val, err := e.Eval(arg, env)
+	if err != nil {
+		return 0, err
+	}
+	i, ok := val.(IntVal)
+	if !ok {
+		return 0, TypeMismatchError{Context: "'" + op + "' operator", Expected: "an integer", Found: val}
+	}
+	return int(i), nil
+}
+
+// EvalNumber evaluates an operand of an arithmetic operator.
+func EvalNumber(e Evaluator, op string, arg Expr, env *epl.Env[any]) (Number, error) {
+	val, err := e.Eval(arg, env)
+	if err != nil {
+		return nil, err
+	}
+	num, ok := val.(Number)
+	if !ok {
+		return nil, TypeMismatchError{Context: "'" + op + "' operator", Expected: "a number", Found: val}
+	}
+	return num, nil
+}
+
+// EvalBool evaluates an operand that must be a boolean.
+func EvalBool(e Evaluator, op string, arg Expr, env *epl.Env[any]) (bool, error) {
+	val, err := e.Eval(arg, env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := val.(BoolVal)
+	if !ok {
+		return false, TypeMismatchError{Context: "'" + op + "' operator", Expected: "a boolean", Found: val}
+	}
+	return bool(b), nil
+}
+
+// BinaryOp returns an OpFunc for an operator that takes exactly two numbers.
+func BinaryOp(e Evaluator, op string, apply func(a, b Number) (Number, error)) OpFunc {
+	return func(env *epl.Env[any], args []Expr) (any, error) {
+		if err := CheckArity(op, args, 2); err != nil {
+			return nil, err
+		}
+		v1, err := EvalNumber(e, op, args[0], env)
+		if err != nil {
+			return nil, err
+		}
+		v2, err := EvalNumber(e, op, args[1], env)
+		if err != nil {
+			return nil, err
+		}
+		return apply(v1, v2)
+	}
+}
+
+// FoldOp returns an OpFunc for an operator that combines any number of
+// numbers, starting from initial.
+func FoldOp(e Evaluator, op string, initial Number, apply func(a, b Number) Number) OpFunc {
+	return func(env *epl.Env[any], args []Expr) (any, error) {
+		out := initial
+		for _, arg := range args {
+			v, err := EvalNumber(e, op, arg, env)
+			if err != nil {
+				return nil, err
+			}
+			out = apply(out, v)
+		}
+		return out, nil
+	}
+}
+
+// SetOpFuncs registers the arithmetic operators "-", "+", "*" and "/" on an
+// evaluator.  These work on the whole numeric tower (see Number) and behave
+// like the Python externs - in particular "/" is true division.
+func SetOpFuncs(e Evaluator) Evaluator {
+	e.SetOpFunc("-", BinaryOp(e, "-", func(a, b Number) (Number, error) { return Sub(a, b), nil }))
+	e.SetOpFunc("/", BinaryOp(e, "/", Div))
+	e.SetOpFunc("+", FoldOp(e, "+", IntVal(0), Add))
+	e.SetOpFunc("*", FoldOp(e, "*", IntVal(1), Mul))
+	return e
+}
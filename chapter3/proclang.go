@@ -15,24 +15,80 @@ type BoundProc struct {
 	Env      *epl.Env[any]
 }
 
+// TypeAnnotation is a type written in the source, eg the int in
+// 'proc (x : int) ...'.  The evaluators ignore annotations - they are used by
+// the type checkers in chapter7.
+type TypeAnnotation interface {
+	String() string
+}
+
 type ProcExpr struct {
 	Name     string
 	Varnames []string
 	Body     Expr
+
+	// Optional type annotations.  ArgTypes is either nil or has an entry,
+	// nil if not annotated, for each parameter.
+	ArgTypes   []TypeAnnotation
+	ResultType TypeAnnotation
 }
 
 func Proc(varnames []string, body Expr) *ProcExpr {
 	return &ProcExpr{Varnames: varnames, Body: body}
 }
 
+// signature formats the parameters and result type along with any
+// annotations, eg "(x : int, y) -> bool".
+func (v *ProcExpr) signature() string {
+	params := make([]string, len(v.Varnames))
+	for i, name := range v.Varnames {
+		params[i] = name
+		if i < len(v.ArgTypes) && v.ArgTypes[i] != nil {
+			params[i] += " : " + v.ArgTypes[i].String()
+		}
+	}
+	out := "(" + strings.Join(params, ", ") + ")"
+	if v.ResultType != nil {
+		out += " -> " + v.ResultType.String()
+	}
+	return out
+}
+
+func annotationEq(a, b TypeAnnotation) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.String() == b.String()
+}
+
+// AnnotationsEq checks whether two procedures have the same type annotations.
+func (v *ProcExpr) AnnotationsEq(another *ProcExpr) bool {
+	if !annotationEq(v.ResultType, another.ResultType) {
+		return false
+	}
+	for i := range v.Varnames {
+		var a, b TypeAnnotation
+		if i < len(v.ArgTypes) {
+			a = v.ArgTypes[i]
+		}
+		if i < len(another.ArgTypes) {
+			b = another.ArgTypes[i]
+		}
+		if !annotationEq(a, b) {
+			return false
+		}
+	}
+	return true
+}
+
 func (v *ProcExpr) Printable() *epl.Printable {
 	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
 		if v.Name != "" {
-			if !yield(epl.Printablef(0, "Proc %s (%s) = ", v.Name, strings.Join(v.Varnames, ", "))) {
+			if !yield(epl.Printablef(0, "Proc %s %s = ", v.Name, v.signature())) {
 				return
 			}
 		} else {
-			if !yield(epl.Printablef(0, "Proc (%s) = ", strings.Join(v.Varnames, ", "))) {
+			if !yield(epl.Printablef(0, "Proc %s = ", v.signature())) {
 				return
 			}
 		}
@@ -53,6 +109,9 @@ func (v *ProcExpr) Eq(another *ProcExpr) bool {
 		log.Printf("ProcExpr.Eq: Varnames differ (%v != %v)\n", v.Varnames, another.Varnames)
 		return false
 	}
+	if !v.AnnotationsEq(another) {
+		return false
+	}
 	// Compare Body
 	bodyEq := ExprEq(v.Body, another.Body)
 	if !bodyEq {
@@ -63,9 +122,9 @@ func (v *ProcExpr) Eq(another *ProcExpr) bool {
 
 func (v *ProcExpr) Repr() string {
 	if v.Name != "" {
-		return fmt.Sprintf("<Proc %s (%s) { %s }", v.Name, strings.Join(v.Varnames, ", "), v.Body.Repr())
+		return fmt.Sprintf("<Proc %s %s { %s }", v.Name, v.signature(), v.Body.Repr())
 	} else {
-		return fmt.Sprintf("<Proc (%s) { %s }", strings.Join(v.Varnames, ", "), v.Body.Repr())
+		return fmt.Sprintf("<Proc %s { %s }", v.signature(), v.Body.Repr())
 	}
 }
 
@@ -134,7 +193,7 @@ func (l *ProcLangEval) LocalEval(expr Expr, env *epl.Env[any]) (any, error) {
 }
 
 func (l *ProcLangEval) ValueOfProc(e *ProcExpr, env *epl.Env[any]) (any, error) {
-	return e.Bind(env), nil
+	return ProcVal{e.Bind(env)}, nil
 }
 
 func (l *ProcLangEval) ValueOfCall(e *CallExpr, env *epl.Env[any]) (any, error) {
@@ -142,17 +201,27 @@ func (l *ProcLangEval) ValueOfCall(e *CallExpr, env *epl.Env[any]) (any, error)
 	if err != nil {
 		return nil, err
 	}
-	boundproc, ok := operatorVal.(*BoundProc)
-	if !ok {
-		return nil, fmt.Errorf("operator in call expression %s did not evaluate to a BoundProc, got %T (%v)", e.Operator.Repr(), operatorVal, operatorVal)
+	var procName string
+	switch proc := operatorVal.(type) {
+	case ProcVal:
+		procName = proc.ProcExpr.Name
+	case *NativeProc:
+		procName = proc.Name
+	default:
+		return nil, TypeMismatchError{Context: "operator in call expression " + e.Operator.Repr(), Expected: "a procedure", Found: operatorVal}
 	}
 
 	args, err := l.EvalExprList(e.Args, env) // returns ([]any, error)
 	if err != nil {
-		return nil, fmt.Errorf("evaluating arguments for call %s: %w", e.Operator.Repr(), err)
+		return nil, l.WrapError(err, "evaluating arguments for call %s", e.Operator.Repr())
 	}
 
-	return l.applyProc(boundproc, args)
+	l.PushFrame(Frame{ProcName: procName, CallSite: e})
+	defer l.PopFrame()
+	if native, ok := operatorVal.(*NativeProc); ok {
+		return l.applyNative(native, args)
+	}
+	return l.applyProc(operatorVal.(ProcVal).BoundProc, args)
 }
 
 func (l *ProcLangEval) applyProc(boundproc *BoundProc, args []any) (any, error) {
@@ -173,17 +242,17 @@ func (l *ProcLangEval) applyProc(boundproc *BoundProc, args []any) (any, error)
 			// If proc takes 0 params, evaluate its body.
 			// It *must not* be called with arguments.
 			if numArgVals > 0 {
-				return nil, fmt.Errorf("Procedure %s takes 0 arguments, but called with %d arguments: %v", currProcexpr.Repr(), numArgVals, currArgs)
+				return nil, ArityError{Context: "Procedure " + currProcexpr.Repr(), Expected: 0, Found: numArgVals}
 			}
 			// log.Println("Proc takes 0 params, evaluating body")
-			result, err = l.Eval(procExpr.Body, currEnv) // Eval returns (any, error)
+			result, err = l.evalProcBody(procExpr, currEnv, currArgs)
 			if err != nil {
 				return nil, err // Propagate error from body
 			}
 
 			// If the body returned *another* 0-arg proc, we need to evaluate that too.
 			// This handles chains like `proc() proc() 5`
-			if bp, ok := result.(*BoundProc); ok && len(bp.ProcExpr.Varnames) == 0 {
+			if bp, ok := result.(ProcVal); ok && len(bp.ProcExpr.Varnames) == 0 {
 				// log.Println("Body returned another 0-arg proc, continuing")
 				currProcexpr = bp.ProcExpr // Update for next iteration
 				currEnv = bp.Env           // Update for next iteration
@@ -199,11 +268,11 @@ func (l *ProcLangEval) applyProc(boundproc *BoundProc, args []any) (any, error)
 		// If we have a proc expecting params, but no args left, it means we have a partial application.
 		if numArgVals == 0 {
 			if initialCall {
-				return nil, fmt.Errorf("initial call to Proc(%v) with no arguments", procExpr.Varnames)
+				return nil, ArityError{Context: "Procedure " + procExpr.Repr(), Expected: numParams, Found: 0}
 			} else {
 				// We consumed args in previous iterations, now none left. Return the current proc bound to its env.
 				// log.Printf("No more args, returning partially applied Proc(%v)\n", currProcexpr.Varnames)
-				return procExpr.Bind(currEnv), nil // Return the *current* bound proc, nil error
+				return ProcVal{procExpr.Bind(currEnv)}, nil // Return the *current* bound proc, nil error
 			}
 		}
 
@@ -220,23 +289,30 @@ func (l *ProcLangEval) applyProc(boundproc *BoundProc, args []any) (any, error)
 			// The procedure expects more arguments than were supplied *in this chunk*.
 			leftVarnames := procExpr.Varnames[numArgVals:] // Params not covered by current args
 			newprocexpr := Proc(leftVarnames, procExpr.Body)
+			newprocexpr.Name = procExpr.Name
 			// log.Printf("Currying: Returning Proc(%v) bound to env %s\n", leftVarnames, newenv)
 			// The environment *must* include the args just consumed.
-			return newprocexpr.Bind(newenv), nil // Return the new curried proc
+			return ProcVal{newprocexpr.Bind(newenv)}, nil // Return the new curried proc
 
 		} else { // Exact match (numParams == numArgVals) OR More args than params (numParams < numArgVals)
 			// We have enough (or more) arguments to satisfy the current procedure's parameters.
 			// log.Printf("Evaluating body of Proc(%v) with env %s\n", currProcexpr.Varnames, newenv)
-			result, err = l.Eval(currProcexpr.Body, newenv) // Evaluate body with the consumed args
+			result, err = l.evalProcBody(currProcexpr, newenv, consumedArgs) // Evaluate body with the consumed args
+			if err != nil {
+				return nil, err // Propagate error from body
+			}
 			// log.Printf("Body evaluation returned: %v (%T)\n", result, result)
 
-			if bp, ok := result.(*BoundProc); ok {
+			if bp, ok := result.(ProcVal); ok {
 				// Body returned another procedure. Continue the loop with this new proc and remaining args.
 				// log.Println("Body returned another proc, continuing loop")
 				currProcexpr = bp.ProcExpr
 				currEnv = bp.Env
 				currArgs = restArgs // Use remaining args for the *new* proc
 				// Loop continues without returning here
+			} else if np, ok := result.(*NativeProc); ok && len(restArgs) > 0 {
+				// Body returned a native procedure - it takes the remaining args.
+				return l.applyNative(np, restArgs)
 			} else {
 				// Body returned a non-procedure value.
 				if len(restArgs) == 0 {
@@ -245,7 +321,7 @@ func (l *ProcLangEval) applyProc(boundproc *BoundProc, args []any) (any, error)
 					return result, nil
 				} else {
 					// Body returned a value, but we still have args left. This is an error.
-					return nil, fmt.Errorf("Procedure %s returned non-procedure value %v (%T), but %d arguments remain: %v", currProcexpr.Repr(), result, result, len(restArgs), restArgs)
+					return nil, ArityError{Context: "Procedure " + currProcexpr.Repr(), Expected: numParams, Found: numArgVals}
 				}
 			}
 		}
@@ -253,3 +329,16 @@ func (l *ProcLangEval) applyProc(boundproc *BoundProc, args []any) (any, error)
 	} // End for loop
 
 }
+
+// evalProcBody evaluates the body of a procedure in an environment where its
+// parameters have been bound to args, notifying any observers.
+func (l *ProcLangEval) evalProcBody(procExpr *ProcExpr, env *epl.Env[any], args []any) (any, error) {
+	if !l.HasObservers() {
+		return l.Eval(procExpr.Body, env)
+	}
+	proc := procExpr.Bind(env)
+	l.notifyEnterProc(proc, args)
+	result, err := l.Eval(procExpr.Body, env)
+	l.notifyExitProc(proc, result, err)
+	return result, err
+}
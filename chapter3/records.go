@@ -0,0 +1,187 @@
+package chapter3
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	epl "github.com/panyam/eplgo"
+	gfn "github.com/panyam/goutils/fn"
+)
+
+// RecordExpr is a record literal - {x = 1, y = 2}.
+type RecordExpr struct {
+	Fields map[string]Expr
+}
+
+func Record(fields map[string]Expr) *RecordExpr {
+	return &RecordExpr{Fields: fields}
+}
+
+func (e *RecordExpr) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "Record")) {
+			return
+		}
+		fieldsPrintable(e.Fields, yield)
+	})
+}
+
+func (e *RecordExpr) Eq(another *RecordExpr) bool {
+	return fieldsEq(e.Fields, another.Fields)
+}
+
+func (e *RecordExpr) Repr() string {
+	return fmt.Sprintf("<Record(%s)>", fieldsRepr(e.Fields))
+}
+
+// FieldExpr is 'r.x' - the value of a field of a record.
+type FieldExpr struct {
+	Record Expr
+	Field  string
+}
+
+func Field(record any, field string) *FieldExpr {
+	return &FieldExpr{Record: AnyToExpr(record), Field: field}
+}
+
+func (e *FieldExpr) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "Field %s:", e.Field)) {
+			return
+		}
+		rP := e.Record.Printable()
+		rP.IndentLevel += 1
+		if !yield(rP) {
+			return
+		}
+	})
+}
+
+func (e *FieldExpr) Eq(another *FieldExpr) bool {
+	return e.Field == another.Field && ExprEq(e.Record, another.Record)
+}
+
+func (e *FieldExpr) Repr() string {
+	return fmt.Sprintf("<Field(%s, %s)>", e.Record.Repr(), e.Field)
+}
+
+// UpdateExpr is the functional update '{r with x = 3}' - a copy of the
+// record r with new values for some of its fields.  The record is not
+// changed and the fields must already be in it.
+type UpdateExpr struct {
+	Record Expr
+	Fields map[string]Expr
+}
+
+func Update(record any, fields map[string]Expr) *UpdateExpr {
+	return &UpdateExpr{Record: AnyToExpr(record), Fields: fields}
+}
+
+func (e *UpdateExpr) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "Update:")) {
+			return
+		}
+		rP := e.Record.Printable()
+		rP.IndentLevel += 1
+		if !yield(rP) {
+			return
+		}
+		if !yield(epl.Printablef(1, "with:")) {
+			return
+		}
+		fieldsPrintable(e.Fields, yield)
+	})
+}
+
+func (e *UpdateExpr) Eq(another *UpdateExpr) bool {
+	return ExprEq(e.Record, another.Record) && fieldsEq(e.Fields, another.Fields)
+}
+
+func (e *UpdateExpr) Repr() string {
+	return fmt.Sprintf("<Update(%s with %s)>", e.Record.Repr(), fieldsRepr(e.Fields))
+}
+
+func fieldsPrintable(fields map[string]Expr, yield func(v *epl.Printable) bool) bool {
+	for _, name := range epl.SortedKeys(fields) {
+		if !yield(epl.Printablef(2, "%s = ", name)) {
+			return false
+		}
+		fP := fields[name].Printable()
+		fP.IndentLevel += 2
+		if !yield(fP) {
+			return false
+		}
+	}
+	return true
+}
+
+func fieldsEq(a, b map[string]Expr) bool {
+	return maps.EqualFunc(a, b, ExprEq)
+}
+
+func fieldsRepr(fields map[string]Expr) string {
+	return strings.Join(gfn.Map(epl.SortedKeys(fields), func(name string) string {
+		return name + " = " + fields[name].Repr()
+	}), ", ")
+}
+
+// EvalRecord evaluates an expression that must be a record.
+func EvalRecord(e Evaluator, context string, arg Expr, env *epl.Env[any]) (RecordVal, error) {
+	val, err := e.Eval(arg, env)
+	if err != nil {
+		return nil, err
+	}
+	record, ok := val.(RecordVal)
+	if !ok {
+		return nil, TypeMismatchError{Context: context, Expected: "a record", Found: val}
+	}
+	return record, nil
+}
+
+// evalFields evaluates the values of the fields of a record literal or
+// update into out.
+func (l *LetLangEval) evalFields(fields map[string]Expr, env *epl.Env[any], out RecordVal) error {
+	for _, name := range epl.SortedKeys(fields) {
+		val, err := l.Eval(fields[name], env)
+		if err != nil {
+			return err
+		}
+		if out[name], err = AsValue("field '"+name+"'", val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *LetLangEval) ValueOfRecordExpr(e *RecordExpr, env *epl.Env[any]) (any, error) {
+	out := RecordVal{}
+	return out, l.evalFields(e.Fields, env, out)
+}
+
+func (l *LetLangEval) ValueOfFieldExpr(e *FieldExpr, env *epl.Env[any]) (any, error) {
+	record, err := EvalRecord(l, "field '"+e.Field+"'", e.Record, env)
+	if err != nil {
+		return nil, err
+	}
+	val, ok := record[e.Field]
+	if !ok {
+		return nil, MissingFieldError{Field: e.Field}
+	}
+	return val, nil
+}
+
+func (l *LetLangEval) ValueOfUpdateExpr(e *UpdateExpr, env *epl.Env[any]) (any, error) {
+	record, err := EvalRecord(l, "record update", e.Record, env)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range epl.SortedKeys(e.Fields) {
+		if _, ok := record[name]; !ok {
+			return nil, MissingFieldError{Field: name}
+		}
+	}
+	out := maps.Clone(record)
+	return out, l.evalFields(e.Fields, env, out)
+}
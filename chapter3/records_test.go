@@ -0,0 +1,57 @@
+package chapter3
+
+import (
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecords(t *testing.T) {
+	point := Record(ExprDict("x", Lit(1), "y", Lit(2)))
+	cases := []TestCase{
+		{"record", RecordVal{"x": IntVal(1), "y": IntVal(2)}, point},
+		{"empty", RecordVal{}, Record(nil)},
+		{"field", 2, Field(point, "y")},
+		// let p = {x = 1, y = 2} in -(p.x, p.y)
+		{"field_var", -1, Let(ExprDict("p", point), Op("-", Field("p", "x"), Field("p", "y")))},
+		{"nested", true, Field(Field(Record(ExprDict("inner", Record(ExprDict("b", Lit(true))))), "inner"), "b")},
+		{"update", RecordVal{"x": IntVal(1), "y": IntVal(5)}, Update(point, ExprDict("y", Lit(5)))},
+		// let p = {x = 1, y = 2} in let q = {p with x = 3} in -(p.x, q.x) - p is unchanged
+		{"update_copies", -2, Let(ExprDict("p", point),
+			Let(ExprDict("q", Update("p", ExprDict("x", Lit(3)))), Op("-", Field("p", "x"), Field("q", "x"))))},
+	}
+	for _, tc := range cases {
+		RunTest(t, NewTestListEval(), &tc, nil)
+	}
+}
+
+func TestRecordErrors(t *testing.T) {
+	point := Record(ExprDict("x", Lit(1)))
+	cases := []struct {
+		name     string
+		expected error
+		expr     Expr
+	}{
+		{"missing_field", MissingFieldError{Field: "z"}, Field(point, "z")},
+		{"update_missing_field", MissingFieldError{Field: "z"}, Update(point, ExprDict("z", Lit(1)))},
+		{"field_not_record", TypeMismatchError{Context: "field 'x'", Expected: "a record", Found: IntVal(1)}, Field(1, "x")},
+		{"update_not_record", TypeMismatchError{Context: "record update", Expected: "a record", Found: IntVal(1)},
+			Update(1, ExprDict("x", Lit(1)))},
+	}
+	for _, tc := range cases {
+		_, err := NewTestListEval().Eval(tc.expr, epl.NewEnv[any](nil))
+		assert.ErrorIs(t, err, tc.expected, "Test %s", tc.name)
+	}
+}
+
+func TestRecordPrinting(t *testing.T) {
+	point := Record(ExprDict("y", Lit(2), "x", Lit(1)))
+	assert.Equal(t, "<Record(x = Val(1:int), y = Val(2:int))>", point.Repr())
+	assert.Equal(t, "<Field(<Var(p)>, x)>", Field("p", "x").Repr())
+	assert.Equal(t, "<Update(<Var(p)> with x = Val(3:int))>", Update("p", ExprDict("x", Lit(3))).Repr())
+	assert.True(t, ExprEq(point, Record(ExprDict("x", Lit(1), "y", Lit(2)))))
+	assert.False(t, ExprEq(point, Record(ExprDict("x", Lit(1)))))
+	assert.False(t, ExprEq(Field("p", "x"), Field("p", "y")))
+	assert.False(t, ExprEq(Update("p", ExprDict("x", Lit(3))), Update("p", ExprDict("x", Lit(4)))))
+}
@@ -0,0 +1,84 @@
+package chapter3
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Frame is a single entry in the EPL call stack.  A frame is pushed every time
+// a procedure is applied by a CallExpr.
+type Frame struct {
+	// Name of the procedure being applied (from ProcExpr.Name or
+	// NativeProc.Name).  Empty for anonymous procedures.
+	ProcName string
+
+	// The call expression that applied the procedure.
+	CallSite *CallExpr
+}
+
+func (f Frame) String() string {
+	name := f.ProcName
+	if name == "" {
+		name = "<anonymous>"
+	}
+	if f.CallSite == nil {
+		return name
+	}
+	return fmt.Sprintf("%s (called at %s)", name, f.CallSite.Repr())
+}
+
+// StackTrace is a snapshot of the EPL call stack with the innermost frame
+// first.
+type StackTrace struct {
+	Frames []Frame
+}
+
+func (s *StackTrace) String() string {
+	if s == nil || len(s.Frames) == 0 {
+		return "EPL stack trace: <top level>"
+	}
+	lines := []string{"EPL stack trace (most recent call first):"}
+	for _, f := range s.Frames {
+		lines = append(lines, "  at "+f.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TracedError is an error annotated with the EPL call stack that was active
+// when it was first returned by an evaluator.
+type TracedError struct {
+	Err   error
+	Trace *StackTrace
+}
+
+func (e *TracedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TracedError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TracedError) Traceback() *StackTrace {
+	return e.Trace
+}
+
+// Format prints the stack trace along with the error for the %+v verb.
+func (e *TracedError) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('+') {
+		fmt.Fprintf(f, "%s\n%s", e.Err.Error(), e.Trace.String())
+		return
+	}
+	fmt.Fprint(f, e.Error())
+}
+
+// TracebackOf returns the EPL stack trace attached to an error (or to any
+// error it wraps), or nil if it has none.
+func TracebackOf(err error) *StackTrace {
+	var traced interface{ Traceback() *StackTrace }
+	if errors.As(err, &traced) {
+		return traced.Traceback()
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package chapter3
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// letrec
+//
+//	countdown(x) = if isz(x) then y else (countdown -(x,1))
+//
+// in (countdown 2)
+func makeCountdownToUnbound() Expr {
+	return LetRec(
+		ProcMap("countdown", Proc([]string{"x"},
+			If(IsZero("x"), Var("y"), Call("countdown", Op("-", "x", 1))))),
+		Call("countdown", 2))
+}
+
+func TestStackTraceOnError(t *testing.T) {
+	evaluator := NewLetRecLangEval()
+	SetOpFuncs(evaluator)
+	_, err := evaluator.Eval(makeCountdownToUnbound(), epl.NewEnv[any](nil))
+	require.Error(t, err)
+
+	// Still matchable as the underlying error
+	assert.True(t, errors.As(err, &UnboundVariableError{}))
+
+	trace := TracebackOf(err)
+	require.NotNil(t, trace)
+	require.Len(t, trace.Frames, 3)
+	for _, f := range trace.Frames {
+		assert.Equal(t, "countdown", f.ProcName)
+	}
+	// Innermost frame first
+	assert.True(t, ExprEq(trace.Frames[0].CallSite, Call("countdown", Op("-", "x", 1))))
+	assert.True(t, ExprEq(trace.Frames[2].CallSite, Call("countdown", 2)))
+
+	// Error message is no longer buried in the evaluation path
+	assert.Equal(t, "variable 'y' not found in environment", err.Error())
+	assert.Contains(t, fmt.Sprintf("%+v", err), "at countdown (called at")
+
+	// Stack is unwound once evaluation returns
+	assert.Empty(t, evaluator.StackTrace().Frames)
+}
+
+func TestVerboseErrors(t *testing.T) {
+	evaluator := NewLetRecLangEval()
+	SetOpFuncs(evaluator)
+	evaluator.VerboseErrors = true
+	_, err := evaluator.Eval(makeCountdownToUnbound(), epl.NewEnv[any](nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "evaluating <Var(y)>: variable 'y' not found in environment")
+	assert.NotNil(t, TracebackOf(err))
+}
+
+func TestStackTraceAtTopLevel(t *testing.T) {
+	_, err := NewTestLetLangEval().Eval(Var("y"), epl.NewEnv[any](nil))
+	trace := TracebackOf(err)
+	require.NotNil(t, trace)
+	assert.Empty(t, trace.Frames)
+	assert.Equal(t, "EPL stack trace: <top level>", trace.String())
+}
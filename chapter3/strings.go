@@ -0,0 +1,127 @@
+package chapter3
+
+import (
+	"math/big"
+	"strings"
+	"unicode/utf8"
+
+	epl "github.com/panyam/eplgo"
+)
+
+// SetStringOpFuncs registers the string library on an evaluator:
+//
+//	concat(s1, s2, ...)      the strings joined together
+//	strlen(s)                the number of characters in s
+//	substring(s, start, end) the characters in [start, end)
+//	stringequals(s1, s2)     true if s1 and s2 are equal
+//	stringcompare(s1, s2)    -1, 0 or 1 as s1 is before, equal to or after s2
+//	tostring(v)              the printed form of any value
+//	toint(s)                 s parsed as a decimal integer
+//
+// Lengths and indexes count characters (runes) not bytes.
+func SetStringOpFuncs(e Evaluator) Evaluator {
+	e.SetOpFunc("concat", func(env *epl.Env[any], args []Expr) (any, error) {
+		var out strings.Builder
+		for _, arg := range args {
+			s, err := EvalString(e, "concat", arg, env)
+			if err != nil {
+				return nil, err
+			}
+			out.WriteString(string(s))
+		}
+		return StringVal(out.String()), nil
+	})
+	e.SetOpFunc("strlen", func(env *epl.Env[any], args []Expr) (any, error) {
+		if err := CheckArity("strlen", args, 1); err != nil {
+			return nil, err
+		}
+		s, err := EvalString(e, "strlen", args[0], env)
+		if err != nil {
+			return nil, err
+		}
+		return IntVal(utf8.RuneCountInString(string(s))), nil
+	})
+	e.SetOpFunc("substring", func(env *epl.Env[any], args []Expr) (any, error) {
+		if err := CheckArity("substring", args, 3); err != nil {
+			return nil, err
+		}
+		s, err := EvalString(e, "substring", args[0], env)
+		if err != nil {
+			return nil, err
+		}
+		start, err := EvalInt(e, "substring", args[1], env)
+		if err != nil {
+			return nil, err
+		}
+		end, err := EvalInt(e, "substring", args[2], env)
+		if err != nil {
+			return nil, err
+		}
+		runes := []rune(string(s))
+		if start < 0 || start > len(runes) {
+			return nil, IndexError{Context: "substring start", Index: start, Length: len(runes)}
+		}
+		if end < start || end > len(runes) {
+			return nil, IndexError{Context: "substring end", Index: end, Length: len(runes)}
+		}
+		return StringVal(runes[start:end]), nil
+	})
+	e.SetOpFunc("stringequals", func(env *epl.Env[any], args []Expr) (any, error) {
+		cmp, err := compareStrings(e, "stringequals", args, env)
+		if err != nil {
+			return nil, err
+		}
+		return BoolVal(cmp == 0), nil
+	})
+	e.SetOpFunc("stringcompare", func(env *epl.Env[any], args []Expr) (any, error) {
+		cmp, err := compareStrings(e, "stringcompare", args, env)
+		if err != nil {
+			return nil, err
+		}
+		return IntVal(cmp), nil
+	})
+	e.SetOpFunc("tostring", func(env *epl.Env[any], args []Expr) (any, error) {
+		if err := CheckArity("tostring", args, 1); err != nil {
+			return nil, err
+		}
+		val, err := e.Eval(args[0], env)
+		if err != nil {
+			return nil, err
+		}
+		v, err := AsValue("'tostring' operator", val)
+		if err != nil {
+			return nil, err
+		}
+		return StringVal(v.String()), nil
+	})
+	e.SetOpFunc("toint", func(env *epl.Env[any], args []Expr) (any, error) {
+		if err := CheckArity("toint", args, 1); err != nil {
+			return nil, err
+		}
+		s, err := EvalString(e, "toint", args[0], env)
+		if err != nil {
+			return nil, err
+		}
+		i, ok := new(big.Int).SetString(strings.TrimSpace(string(s)), 10)
+		if !ok {
+			return nil, ConversionError{Context: "'toint' operator", Value: s}
+		}
+		return NormalizeInt(i), nil
+	})
+	return e
+}
+
+func compareStrings(e Evaluator, op string, args []Expr, env *epl.Env[any]) (int, error) {
+	if err := CheckArity(op, args, 2); err != nil {
+		return 0, err
+	}
+	s1, err := EvalString(e, op, args[0], env)
+	if err != nil {
+		return 0, err
+	}
+	s2, err := EvalString(e, op, args[1], env)
+	if err != nil {
+		return 0, err
+	}
+	return strings.Compare(string(s1), string(s2)), nil
+}
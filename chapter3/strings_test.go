@@ -0,0 +1,66 @@
+package chapter3
+
+import (
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func NewTestStringEval() Evaluator {
+	return SetStringOpFuncs(NewTestLetRecLangEval())
+}
+
+func TestStringOps(t *testing.T) {
+	cases := []TestCase{
+		{"literal", "hello", Lit("hello")},
+		{"concat", "foobarbaz", Op("concat", Lit("foo"), Lit("bar"), Lit("baz"))},
+		{"concat_empty", "", Op("concat")},
+		{"strlen", 5, Op("strlen", Lit("héllo"))},
+		{"substring", "ll", Op("substring", Lit("hello"), 2, 4)},
+		{"substring_runes", "é", Op("substring", Lit("héllo"), 1, 2)},
+		{"stringequals", true, Op("stringequals", Lit("abc"), Op("concat", Lit("a"), Lit("bc")))},
+		{"stringequals_false", false, Op("stringequals", Lit("abc"), Lit("abd"))},
+		{"stringcompare", -1, Op("stringcompare", Lit("abc"), Lit("abd"))},
+		{"tostring", "42", Op("tostring", Op("*", 6, 7))},
+		{"tostring_bool", "true", Op("tostring", IsZero(0))},
+		{"toint", 41, Op("-", Op("toint", Lit(" 42 ")), 1)},
+		{"roundtrip", 3, Op("strlen", Op("tostring", 100))},
+	}
+	for _, tc := range cases {
+		RunTest(t, NewTestStringEval(), &tc, nil)
+	}
+}
+
+func TestStringsInLet(t *testing.T) {
+	// let greet = proc(name) concat("hello, ", name) in (greet "world")
+	expr := Let(ExprDict("greet", Proc([]string{"name"}, Op("concat", Lit("hello, "), Var("name")))),
+		Call("greet", Lit("world")))
+	tc := TestCase{"greet", "hello, world", expr}
+	RunTest(t, NewTestStringEval(), &tc, nil)
+}
+
+func TestStringErrors(t *testing.T) {
+	e := NewTestStringEval()
+
+	var index IndexError
+	err := evalError(t, e, Op("substring", Lit("abc"), 2, 5))
+	require.ErrorAs(t, err, &index)
+	assert.Equal(t, 5, index.Index)
+	assert.Equal(t, 3, index.Length)
+
+	var conv ConversionError
+	err = evalError(t, e, Op("toint", Lit("12a")))
+	require.ErrorAs(t, err, &conv)
+	assert.Equal(t, StringVal("12a"), conv.Value)
+
+	var mismatch TypeMismatchError
+	err = evalError(t, e, Op("concat", Lit("a"), 1))
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, "a string", mismatch.Expected)
+
+	var arity ArityError
+	_, err = e.Eval(Op("strlen", Lit("a"), Lit("b")), epl.NewEnv[any](nil))
+	assert.ErrorAs(t, err, &arity)
+}
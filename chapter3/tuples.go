@@ -0,0 +1,125 @@
+package chapter3
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	epl "github.com/panyam/eplgo"
+)
+
+// UnpackExpr is 'unpack x y z = e in body' (EOPL exercise 3.18) - it binds
+// the names to the items of the tuple e in order, so e must have exactly as
+// many items as there are names.  Lists are unpacked the same way.
+type UnpackExpr struct {
+	Names []string
+	Expr  Expr
+	Body  Expr
+}
+
+func Unpack(names []string, expr any, body any) *UnpackExpr {
+	return &UnpackExpr{Names: names, Expr: AnyToExpr(expr), Body: AnyToExpr(body)}
+}
+
+func (e *UnpackExpr) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "Unpack %s =", strings.Join(e.Names, " "))) {
+			return
+		}
+		eP := e.Expr.Printable()
+		eP.IndentLevel += 2
+		if !yield(eP) {
+			return
+		}
+		if !yield(epl.Printablef(1, "in:")) {
+			return
+		}
+		if !yield(e.Body.Printable()) {
+			return
+		}
+	})
+}
+
+func (e *UnpackExpr) Eq(another *UnpackExpr) bool {
+	return slices.Equal(e.Names, another.Names) && ExprEq(e.Expr, another.Expr) && ExprEq(e.Body, another.Body)
+}
+
+func (e *UnpackExpr) Repr() string {
+	return fmt.Sprintf("<Unpack %s = %s in %s>", strings.Join(e.Names, " "), e.Expr.Repr(), e.Body.Repr())
+}
+
+// TupleRefExpr is 't.1' - the item of a tuple at a fixed, zero based,
+// position.
+type TupleRefExpr struct {
+	Tuple Expr
+	Index int
+}
+
+func TupleRef(tuple any, index int) *TupleRefExpr {
+	return &TupleRefExpr{Tuple: AnyToExpr(tuple), Index: index}
+}
+
+func (e *TupleRefExpr) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "TupleRef %d:", e.Index)) {
+			return
+		}
+		tP := e.Tuple.Printable()
+		tP.IndentLevel += 1
+		if !yield(tP) {
+			return
+		}
+	})
+}
+
+func (e *TupleRefExpr) Eq(another *TupleRefExpr) bool {
+	return e.Index == another.Index && ExprEq(e.Tuple, another.Tuple)
+}
+
+func (e *TupleRefExpr) Repr() string {
+	return fmt.Sprintf("<TupleRef(%s, %d)>", e.Tuple.Repr(), e.Index)
+}
+
+// evalTuple evaluates an expression that must be a tuple, or for unpack a
+// list.
+func (l *LetLangEval) evalTuple(context string, e Expr, env *epl.Env[any], lists bool) (TupleVal, error) {
+	val, err := l.Eval(e, env)
+	if err != nil {
+		return nil, err
+	}
+	switch v := val.(type) {
+	case TupleVal:
+		return v, nil
+	case *ListVal:
+		if lists {
+			return TupleVal(v.Items()), nil
+		}
+	}
+	return nil, TypeMismatchError{Context: context, Expected: "a tuple", Found: val}
+}
+
+func (l *LetLangEval) ValueOfUnpackExpr(e *UnpackExpr, env *epl.Env[any]) (any, error) {
+	items, err := l.evalTuple("unpack", e.Expr, env, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) != len(e.Names) {
+		return nil, TupleArityError{Context: "unpack", Expected: len(e.Names), Found: len(items)}
+	}
+	bindings := map[string]any{}
+	for i, name := range e.Names {
+		bindings[name] = items[i]
+	}
+	return l.Eval(e.Body, env.Extend(bindings))
+}
+
+func (l *LetLangEval) ValueOfTupleRefExpr(e *TupleRefExpr, env *epl.Env[any]) (any, error) {
+	items, err := l.evalTuple("tuple index", e.Tuple, env, false)
+	if err != nil {
+		return nil, err
+	}
+	if e.Index < 0 || e.Index >= len(items) {
+		return nil, IndexError{Context: "tuple index", Index: e.Index, Length: len(items)}
+	}
+	return items[e.Index], nil
+}
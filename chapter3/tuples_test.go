@@ -0,0 +1,62 @@
+package chapter3
+
+import (
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTuples(t *testing.T) {
+	pair := Tuple(AnyToExpr(1), Lit(true))
+	cases := []TestCase{
+		{"tuple", TupleVal{IntVal(1), BoolVal(true)}, pair},
+		// unpack x y = (1, true) in if y then x else 0
+		{"unpack", 1, Unpack([]string{"x", "y"}, pair, If("y", "x", 0))},
+		// unpack x y z = list(1, 2, 3) in -(x, z)
+		{"unpack_list", -2, Unpack([]string{"x", "y", "z"}, List(1, 2, 3), Op("-", "x", "z"))},
+		{"unpack_nested", 3, Unpack([]string{"a", "b"}, Tuple(AnyToExpr(1), Tuple(AnyToExpr(2), AnyToExpr(3))),
+			Unpack([]string{"c", "d"}, "b", "d"))},
+		{"index", true, TupleRef(pair, 1)},
+		{"index_nested", 2, TupleRef(TupleRef(Tuple(AnyToExpr(1), Tuple(AnyToExpr(2))), 1), 0)},
+		{"empty", TupleVal{}, Unpack(nil, Tuple(), Tuple())},
+	}
+	for _, tc := range cases {
+		RunTest(t, NewTestListEval(), &tc, nil)
+	}
+}
+
+func TestTupleErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		expected error
+		expr     Expr
+	}{
+		{"unpack_too_few", TupleArityError{Context: "unpack", Expected: 3, Found: 2},
+			Unpack([]string{"x", "y", "z"}, Tuple(AnyToExpr(1), AnyToExpr(2)), "x")},
+		{"unpack_too_many", TupleArityError{Context: "unpack", Expected: 1, Found: 2},
+			Unpack([]string{"x"}, List(1, 2), "x")},
+		{"unpack_not_tuple", TypeMismatchError{Context: "unpack", Expected: "a tuple", Found: IntVal(1)},
+			Unpack([]string{"x"}, 1, "x")},
+		{"index_out_of_range", IndexError{Context: "tuple index", Index: 2, Length: 2},
+			TupleRef(Tuple(AnyToExpr(1), AnyToExpr(2)), 2)},
+		{"index_not_tuple", TypeMismatchError{Context: "tuple index", Expected: "a tuple", Found: IntVal(1)},
+			TupleRef(1, 0)},
+	}
+	for _, tc := range cases {
+		_, err := NewTestListEval().Eval(tc.expr, epl.NewEnv[any](nil))
+		assert.ErrorIs(t, err, tc.expected, "Test %s", tc.name)
+	}
+}
+
+func TestTuplePrinting(t *testing.T) {
+	assert.Equal(t, "(1, (a, true))", TupleVal{IntVal(1), TupleVal{StringVal("a"), BoolVal(true)}}.String())
+	assert.Equal(t, "(1,)", TupleVal{IntVal(1)}.String())
+	assert.Equal(t, "()", TupleVal{}.String())
+	assert.Equal(t, "[(1, 2)]", NewList(TupleVal{IntVal(1), IntVal(2)}).String())
+	assert.Equal(t, "<Unpack x y = <Var(p)> in <Var(x)>>", Unpack([]string{"x", "y"}, "p", "x").Repr())
+	assert.Equal(t, "<TupleRef(<Var(p)>, 0)>", TupleRef("p", 0).Repr())
+	assert.True(t, ExprEq(Unpack([]string{"x", "y"}, "p", "x"), Unpack([]string{"x", "y"}, "p", "x")))
+	assert.False(t, ExprEq(Unpack([]string{"x", "y"}, "p", "x"), Unpack([]string{"y", "x"}, "p", "x")))
+	assert.False(t, ExprEq(TupleRef("p", 0), TupleRef("p", 1)))
+}
@@ -0,0 +1,229 @@
+package chapter3
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	epl "github.com/panyam/eplgo"
+	gfn "github.com/panyam/goutils/fn"
+)
+
+// Value is the result of evaluating an expression.  Values are kept distinct
+// from the syntax (Expr) that produced them.  String returns the value as an
+// EPL program would show it while Repr is a more explicit debugging form.
+type Value interface {
+	String() string
+	Repr() string
+	Eq(another Value) bool
+}
+
+// IntVal is an integer value.
+type IntVal int
+
+func (v IntVal) String() string { return fmt.Sprintf("%d", int(v)) }
+func (v IntVal) Repr() string   { return fmt.Sprintf("Int(%d)", int(v)) }
+func (v IntVal) Eq(another Value) bool {
+	a, ok := another.(IntVal)
+	return ok && a == v
+}
+
+// BoolVal is a boolean value.
+type BoolVal bool
+
+func (v BoolVal) String() string { return fmt.Sprintf("%t", bool(v)) }
+func (v BoolVal) Repr() string   { return fmt.Sprintf("Bool(%t)", bool(v)) }
+func (v BoolVal) Eq(another Value) bool {
+	a, ok := another.(BoolVal)
+	return ok && a == v
+}
+
+// StringVal is a string value.
+type StringVal string
+
+func (v StringVal) String() string { return string(v) }
+func (v StringVal) Repr() string   { return fmt.Sprintf("String(%q)", string(v)) }
+func (v StringVal) Eq(another Value) bool {
+	a, ok := another.(StringVal)
+	return ok && a == v
+}
+
+// TupleVal is the value of a TupleExpr.  It prints like the tuple literals
+// of the parser, with a trailing comma for a single item so it is not taken
+// for a parenthesized value - (1, 2), (1,) and ().
+type TupleVal []Value
+
+func (v TupleVal) String() string {
+	if len(v) == 1 {
+		return "(" + v[0].String() + ",)"
+	}
+	return "(" + strings.Join(gfn.Map(v, Value.String), ", ") + ")"
+}
+
+func (v TupleVal) Repr() string {
+	return "Tuple(" + strings.Join(gfn.Map(v, Value.Repr), ", ") + ")"
+}
+
+func (v TupleVal) Eq(another Value) bool {
+	a, ok := another.(TupleVal)
+	if !ok || len(a) != len(v) {
+		return false
+	}
+	for i, child := range v {
+		if !child.Eq(a[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// RecordVal is a value with named fields.
+type RecordVal map[string]Value
+
+func (v RecordVal) String() string {
+	return "{" + strings.Join(gfn.Map(epl.SortedKeys(v), func(k string) string { return k + " = " + v[k].String() }), ", ") + "}"
+}
+
+func (v RecordVal) Repr() string {
+	return "Record(" + strings.Join(gfn.Map(epl.SortedKeys(v), func(k string) string { return k + " = " + v[k].Repr() }), ", ") + ")"
+}
+
+// Eq is true if both records have the same fields with equal values.
+func (v RecordVal) Eq(another Value) bool {
+	a, ok := another.(RecordVal)
+	if !ok || len(a) != len(v) {
+		return false
+	}
+	for k, child := range v {
+		if other, ok := a[k]; !ok || !child.Eq(other) {
+			return false
+		}
+	}
+	return true
+}
+
+// ProcVal is a procedure closed over the environment it was defined in.
+type ProcVal struct {
+	*BoundProc
+}
+
+func (v ProcVal) String() string {
+	if v.ProcExpr.Name != "" {
+		return fmt.Sprintf("<proc %s(%s)>", v.ProcExpr.Name, strings.Join(v.ProcExpr.Varnames, ", "))
+	}
+	return fmt.Sprintf("<proc (%s)>", strings.Join(v.ProcExpr.Varnames, ", "))
+}
+
+func (v ProcVal) Repr() string {
+	return fmt.Sprintf("Proc(%s, Env:%p)", v.ProcExpr.Repr(), v.Env)
+}
+
+// Eq is true if both values are the same procedure closed over the same
+// environment.
+func (v ProcVal) Eq(another Value) bool {
+	a, ok := another.(ProcVal)
+	return ok && a.ProcExpr == v.ProcExpr && a.Env == v.Env
+}
+
+// RefVal is a reference to a mutable cell in the store.
+type RefVal struct {
+	*epl.Ref[any]
+}
+
+// The contents of a reference are not printed as they may refer back to it.
+func (v RefVal) String() string { return fmt.Sprintf("<ref %p>", v.Ref) }
+func (v RefVal) Repr() string   { return fmt.Sprintf("Ref(%p)", v.Ref) }
+
+// Eq is true if both values refer to the same cell.
+func (v RefVal) Eq(another Value) bool {
+	a, ok := another.(RefVal)
+	return ok && a.Ref == v.Ref
+}
+
+// ThunkVal is a delayed computation - an expression along with the
+// environment to evaluate it in.
+type ThunkVal struct {
+	Expr Expr
+	Env  *epl.Env[any]
+}
+
+func (v *ThunkVal) String() string { return "<thunk>" }
+func (v *ThunkVal) Repr() string {
+	return fmt.Sprintf("Thunk(%s, Env:%p)", v.Expr.Repr(), v.Env)
+}
+
+// Eq is true if both thunks delay the same expression in the same
+// environment.
+func (v *ThunkVal) Eq(another Value) bool {
+	a, ok := another.(*ThunkVal)
+	return ok && a.Expr == v.Expr && a.Env == v.Env
+}
+
+// ErrorVal is a runtime error as a value - what the handler of a 'try'
+// that catches runtime errors is bound to.  It prints as the error's message.
+type ErrorVal struct {
+	Err error
+}
+
+func (v ErrorVal) String() string { return v.Err.Error() }
+func (v ErrorVal) Repr() string   { return fmt.Sprintf("Error(%q)", v.Err.Error()) }
+
+// Eq is true if both errors have the same message.  The errors themselves
+// may not be comparable.
+func (v ErrorVal) Eq(another Value) bool {
+	a, ok := another.(ErrorVal)
+	return ok && a.Err.Error() == v.Err.Error()
+}
+
+// ToValue converts a Go int, float, bool or string or a *big.Int or *big.Rat
+// (eg the Value of a LitExpr) to the corresponding Value.  Values are
+// returned as is.
+func ToValue(native any) (Value, bool) {
+	switch v := native.(type) {
+	case Value:
+		return v, true
+	case int:
+		return IntVal(v), true
+	case int64:
+		return NormalizeInt(big.NewInt(v)), true
+	case float64:
+		return FloatVal(v), true
+	case *big.Int:
+		return NormalizeInt(v), true
+	case *big.Rat:
+		return NormalizeRat(v), true
+	case bool:
+		return BoolVal(v), true
+	case string:
+		return StringVal(v), true
+	}
+	return nil, false
+}
+
+// ValueEq compares two results of evaluation.
+func ValueEq(a, b any) bool {
+	va, ok1 := a.(Value)
+	vb, ok2 := b.(Value)
+	if !ok1 || !ok2 {
+		return false
+	}
+	return va.Eq(vb)
+}
+
+// ReprOf returns the Repr of a runtime value or its Go representation if it
+// is not a Value.
+func ReprOf(val any) string {
+	if v, ok := val.(Value); ok {
+		return v.Repr()
+	}
+	return fmt.Sprintf("%v", val)
+}
+
+// AsValue checks that a result of evaluation is a Value.
+func AsValue(context string, val any) (Value, error) {
+	v, ok := val.(Value)
+	if !ok {
+		return nil, TypeMismatchError{Context: context, Expected: "a value", Found: val}
+	}
+	return v, nil
+}
@@ -0,0 +1,65 @@
+package chapter3
+
+import (
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValuePrinting(t *testing.T) {
+	tuple := TupleVal{IntVal(1), BoolVal(true), StringVal("hi")}
+	assert.Equal(t, "(1, true, hi)", tuple.String())
+	assert.Equal(t, `Tuple(Int(1), Bool(true), String("hi"))`, tuple.Repr())
+
+	record := RecordVal{"y": StringVal("b"), "x": IntVal(1)}
+	assert.Equal(t, "{x = 1, y = b}", record.String())
+	assert.Equal(t, `Record(x = Int(1), y = String("b"))`, record.Repr())
+
+	proc := Proc([]string{"x", "y"}, Var("x"))
+	proc.Name = "first"
+	assert.Equal(t, "<proc first(x, y)>", ProcVal{proc.Bind(nil)}.String())
+}
+
+func TestValueEq(t *testing.T) {
+	assert.True(t, IntVal(3).Eq(IntVal(3)))
+	assert.False(t, IntVal(3).Eq(IntVal(4)))
+	assert.False(t, IntVal(0).Eq(BoolVal(false)))
+	assert.True(t, TupleVal{IntVal(1), StringVal("a")}.Eq(TupleVal{IntVal(1), StringVal("a")}))
+	assert.False(t, TupleVal{IntVal(1)}.Eq(TupleVal{IntVal(1), IntVal(2)}))
+	assert.True(t, RecordVal{"x": IntVal(1)}.Eq(RecordVal{"x": IntVal(1)}))
+	assert.False(t, RecordVal{"x": IntVal(1)}.Eq(RecordVal{"y": IntVal(1)}))
+	assert.False(t, RecordVal{"x": IntVal(1)}.Eq(RecordVal{"x": IntVal(1), "y": IntVal(2)}))
+
+	// References and procedures are equal only if they are the same
+	ref := &epl.Ref[any]{Value: IntVal(1)}
+	assert.True(t, RefVal{ref}.Eq(RefVal{ref}))
+	assert.False(t, RefVal{ref}.Eq(RefVal{&epl.Ref[any]{Value: IntVal(1)}}))
+
+	env := epl.NewEnv[any](nil)
+	proc := Proc([]string{"x"}, Var("x"))
+	assert.True(t, ProcVal{proc.Bind(env)}.Eq(ProcVal{proc.Bind(env)}))
+	assert.False(t, ProcVal{proc.Bind(env)}.Eq(ProcVal{Proc([]string{"x"}, Var("x")).Bind(env)}))
+}
+
+func TestEvalReturnsValues(t *testing.T) {
+	e := NewTestProcLangEval()
+	env := epl.NewEnv[any](nil)
+
+	val, err := e.Eval(Lit("hello"), env)
+	require.NoError(t, err)
+	assert.Equal(t, StringVal("hello"), val)
+
+	val, err = e.Eval(Tuple(Lit(1), IsZero(Lit(0))), env)
+	require.NoError(t, err)
+	assert.Equal(t, TupleVal{IntVal(1), BoolVal(true)}, val)
+
+	val, err = e.Eval(Proc([]string{"x"}, Var("x")), env)
+	require.NoError(t, err)
+	assert.IsType(t, ProcVal{}, val)
+
+	_, err = e.Eval(Lit([]int{1}), env)
+	var mismatch TypeMismatchError
+	assert.ErrorAs(t, err, &mismatch)
+}
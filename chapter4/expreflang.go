@@ -2,7 +2,6 @@ package chapter4
 
 import (
 	"fmt"
-	"log"
 
 	// For deterministic printing if needed
 	epl "github.com/panyam/eplgo" // Import chapter3 for the base Expr
@@ -235,7 +234,7 @@ func (l *ExpRefLangEval) valueOfBlock(e *BlockExpr, env *epl.Env[any]) (any, err
 	result = nil // Or perhaps Lit(0)? Check Python behavior/tests.
 	// Python: value = self.__caseon__.as_lit(0); result = value
 	// Let's mimic Python for now.
-	result = Lit(0)
+	result = IntVal(0)
 
 	for _, expr := range e.Exprs {
 		result, err = l.Eval(expr, env) // Use Eval to allow dispatch back to Self
@@ -249,10 +248,10 @@ func (l *ExpRefLangEval) valueOfRef(e *RefExpr, env *epl.Env[any]) (any, error)
 		varname := e.ExprOrVar.(string)
 		varRef := env.GetRef(varname) // Get the *epl.Ref[any] itself
 		if varRef == nil {
-			return nil, fmt.Errorf("ref: variable '%s' not found in environment", varname)
+			return nil, UnboundVariableError{Name: varname}
 		}
 		// log.Printf("ref var evaluated %s to Ref %p\n", varname, varRef)
-		return varRef, nil // Return the existing reference
+		return RefVal{Ref: varRef}, nil // Return the existing reference
 	} else {
 		// Mode: 'newref(expr)'
 		initialValueExpr := e.ExprOrVar.(Expr)
@@ -263,7 +262,7 @@ func (l *ExpRefLangEval) valueOfRef(e *RefExpr, env *epl.Env[any]) (any, error)
 		// Create a *new* reference cell containing this value
 		newRef := &epl.Ref[any]{Value: initialValue}
 		// log.Printf("newref evaluated %s to %v, created Ref %p\n", initialValueExpr.Repr(), initialValue, newRef)
-		return newRef, err // Return the pointer to the new Ref struct,
+		return RefVal{Ref: newRef}, err // Return a reference to the new cell
 	}
 }
 
@@ -275,9 +274,9 @@ func (l *ExpRefLangEval) valueOfDeRef(e *DeRefExpr, env *epl.Env[any]) (any, err
 	}
 
 	// Check if the result is actually a reference (*epl.Ref[any])
-	theRef, ok := refVal.(*epl.Ref[any])
+	theRef, ok := refVal.(RefVal)
 	if !ok {
-		log.Panicf("deref expected a reference argument, but got type %T for expr %s", refVal, e.RefExpr.Repr())
+		return nil, NotAReferenceError{Context: "deref", Expr: e.RefExpr, Found: refVal}
 	}
 	// log.Printf("deref evaluated %s to Ref %p, returning Value %v\n", e.RefExpr.Repr(), theRef, theRef.Value)
 	// Return the value *inside* the reference cell
@@ -290,9 +289,9 @@ func (l *ExpRefLangEval) valueOfSetRef(e *SetRefExpr, env *epl.Env[any]) (any, e
 	if err != nil {
 		return nil, err
 	}
-	theRef, ok := refVal.(*epl.Ref[any])
+	theRef, ok := refVal.(RefVal)
 	if !ok {
-		log.Panicf("setref expected a reference argument for the first expression, but got type %T for expr %s", refVal, e.RefExpr.Repr())
+		return nil, NotAReferenceError{Context: "setref", Expr: e.RefExpr, Found: refVal}
 	}
 
 	// Evaluate the expression for the new value
@@ -304,7 +303,9 @@ func (l *ExpRefLangEval) valueOfSetRef(e *SetRefExpr, env *epl.Env[any]) (any, e
 	// log.Printf("setref evaluated %s to Ref %p, evaluated %s to %v. Updating ref.\n", e.RefExpr.Repr(), theRef, e.ValueExpr.Repr(), newValue)
 
 	// Update the value inside the reference cell
+	oldValue := theRef.Value
 	theRef.Value = newValue
+	l.NotifyStoreChanged(theRef.Ref, oldValue, newValue)
 
 	// setref returns the new value
 	return newValue, nil
@@ -15,8 +15,8 @@ func NewTestExpRefLangEval() Evaluator {
 }
 
 // Test runner - adapted from RunTest
-// It needs to handle the fact that evaluation can return non-LitExpr values
-// (like *epl.Ref[any] for newref). Assertions must check types.
+// It needs to handle the fact that evaluation can return values that have no
+// Go literal equivalent (like RefVal for newref). Assertions must check types.
 func RunExpRefTest(t *testing.T, e Evaluator, tc *TestCase, extraenv map[string]any) {
 	env := epl.NewEnv[any](nil)
 	for k, v := range extraenv {
@@ -40,34 +40,24 @@ func RunExpRefTest(t *testing.T, e Evaluator, tc *TestCase, extraenv map[string]
 	// Assert based on the *expected* type and value
 	// For Chapter 4, expected might be a primitive OR could indicate structure (e.g., expect a ref)
 	switch expected := tc.Expected.(type) {
-	case int:
-		finalValue, ok := value.(*LitExpr)
-		if !ok {
-			t.Fatalf("Test %s: Expected LitExpr(int) but got %T (%#v)", tc.Name, value, value)
-		}
-		assert.Equal(t, expected, finalValue.Value, "Test %s Failed - Int Value", tc.Name)
-	case bool:
-		finalValue, ok := value.(*LitExpr)
-		if !ok {
-			t.Fatalf("Test %s: Expected LitExpr(bool) but got %T (%#v)", tc.Name, value, value)
-		}
-		assert.Equal(t, expected, finalValue.Value, "Test %s Failed - Bool Value", tc.Name)
+	case int, bool:
+		AssertValue(t, tc.Name, expected, value)
 	// --- Add specific checks for Chapter 4 ---
-	case *epl.Ref[any]:
+	case RefVal:
 		// Used when the test expects the *result* to be a reference
-		actualRef, ok := value.(*epl.Ref[any])
+		actualRef, ok := value.(RefVal)
 		if !ok {
-			t.Fatalf("Test %s: Expected *epl.Ref[any] but got %T (%#v)", tc.Name, value, value)
+			t.Fatalf("Test %s: Expected RefVal but got %T (%#v)", tc.Name, value, value)
 		}
 		// Compare the *contents* of the reference
 		// This requires the expected ref to also contain a comparable value
-		assert.ObjectsAreEqualValues(expected.Value, actualRef.Value) // Compare values inside refs
+		assert.True(t, ValueEq(expected.Value, actualRef.Value), "Test %s: Ref contents mismatch", tc.Name)
 
 	// Use a sentinel type/value if we just need to check if it's *any* reference
 	case struct{ typeIsRef bool }: // Sentinel struct
 		if expected.typeIsRef {
-			_, ok := value.(*epl.Ref[any])
-			assert.True(t, ok, "Test %s: Expected a reference (*epl.Ref[any]) but got %T (%#v)", tc.Name, value, value)
+			_, ok := value.(RefVal)
+			assert.True(t, ok, "Test %s: Expected a reference (RefVal) but got %T (%#v)", tc.Name, value, value)
 		} else {
 			// Handle case where sentinel is used for non-ref expectation (if needed)
 			t.Fatalf("Test %s: Invalid use of Ref sentinel expectation", tc.Name)
@@ -278,3 +268,19 @@ func TestChapter4ExprEqPrintable(t *testing.T) {
 
 // Helper for simplified printable check (add to common.go?)
 // func (p *Printable) Leaf string { return p.Leaf }
+
+func TestNotAReferenceError(t *testing.T) {
+	evaluator := NewTestExpRefLangEval()
+	env := epl.NewEnv[any](nil)
+
+	// deref(5)
+	_, err := evaluator.Eval(DeRef(5), env)
+	var notRef NotAReferenceError
+	assert.ErrorAs(t, err, &notRef)
+	assert.Equal(t, "deref", notRef.Context)
+
+	// setref(5, 6)
+	_, err = evaluator.Eval(SetRef(5, 6), env)
+	assert.ErrorAs(t, err, &notRef)
+	assert.Equal(t, "setref", notRef.Context)
+}
@@ -12,10 +12,34 @@ type VarExpr = chapter3.VarExpr
 
 var ExprDict = epl.Dict[string, Expr]
 
+type Value = chapter3.Value
+type IntVal = chapter3.IntVal
+type BoolVal = chapter3.BoolVal
+type StringVal = chapter3.StringVal
+type ListVal = chapter3.ListVal
+type RefVal = chapter3.RefVal
+type ThunkVal = chapter3.ThunkVal
+
 type TestCase = chapter3.TestCase
 type Evaluator = chapter3.Evaluator
 
+type RuntimeError = chapter3.RuntimeError
+type UnboundVariableError = chapter3.UnboundVariableError
+type UnknownOperatorError = chapter3.UnknownOperatorError
+type TypeMismatchError = chapter3.TypeMismatchError
+type ArityError = chapter3.ArityError
+type NotAReferenceError = chapter3.NotAReferenceError
+type NotAThunkError = chapter3.NotAThunkError
+type EmptyListError = chapter3.EmptyListError
+type MissingFieldError = chapter3.MissingFieldError
+type RecordVal = chapter3.RecordVal
+
 var SetOpFuncs = chapter3.SetOpFuncs
+var SetStringOpFuncs = chapter3.SetStringOpFuncs
+var SetListOpFuncs = chapter3.SetListOpFuncs
+var List = chapter3.List
+var Record = chapter3.Record
+var Field = chapter3.Field
 var Lit = chapter3.Lit
 var Let = chapter3.Let
 var LetRec = chapter3.LetRec
@@ -30,3 +54,5 @@ var AnyToExpr = chapter3.AnyToExpr
 var ExprEq = chapter3.ExprEq
 var ExprListEq = chapter3.ExprListEq
 var ExprListRepr = chapter3.ExprListRepr
+var AssertValue = chapter3.AssertValue
+var ValueEq = chapter3.ValueEq
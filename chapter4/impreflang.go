@@ -3,7 +3,6 @@ package chapter4
 
 import (
 	"fmt"
-	"log"
 
 	epl "github.com/panyam/eplgo"
 	"github.com/panyam/eplgo/chapter3"
@@ -47,6 +46,50 @@ func (e *AssignExpr) Eq(another *AssignExpr) bool {
 		chapter3.ExprEq(e.Expr, another.Expr)
 }
 
+// SetFieldExpr is 'set r.x = expr' - it changes a field of a record in
+// place, so every variable holding the record sees the new value.
+type SetFieldExpr struct {
+	Record chapter3.Expr
+	Field  string
+	Expr   chapter3.Expr
+}
+
+func SetField(record any, field string, expr any) *SetFieldExpr {
+	return &SetFieldExpr{
+		Record: chapter3.AnyToExpr(record),
+		Field:  field,
+		Expr:   chapter3.AnyToExpr(expr),
+	}
+}
+
+func (e *SetFieldExpr) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "SetField: %s =", e.Field)) {
+			return
+		}
+		rP := e.Record.Printable()
+		rP.IndentLevel += 1
+		if !yield(rP) {
+			return
+		}
+		vP := e.Expr.Printable()
+		vP.IndentLevel += 1
+		if !yield(vP) {
+			return
+		}
+	})
+}
+
+func (e *SetFieldExpr) Repr() string {
+	return fmt.Sprintf("<SetField(%s.%s = %s)>", e.Record.Repr(), e.Field, e.Expr.Repr())
+}
+
+func (e *SetFieldExpr) Eq(another *SetFieldExpr) bool {
+	return e.Field == another.Field &&
+		chapter3.ExprEq(e.Record, another.Record) &&
+		chapter3.ExprEq(e.Expr, another.Expr)
+}
+
 // --- Make sure chapter3.ExprEq handles this via reflection ---
 // No changes needed in chapter3/expr.go if using reflection approach.
 
@@ -69,6 +112,8 @@ func (l *ImpRefLangEval) LocalEval(expr chapter3.Expr, env *epl.Env[any]) (any,
 	switch n := expr.(type) {
 	case *AssignExpr: // Handle the new type
 		return l.valueOfAssign(n, env)
+	case *SetFieldExpr:
+		return l.valueOfSetField(n, env)
 	default:
 		// Delegate to the embedded ExpRefLangEval's LocalEval for other types
 		return l.ExpRefLangEval.LocalEval(expr, env)
@@ -89,14 +134,38 @@ func (l *ImpRefLangEval) valueOfAssign(e *AssignExpr, env *epl.Env[any]) (any, e
 
 	// Check if the variable exists (i.e., if GetRef found it)
 	if varRef == nil {
-		log.Panicf("set: variable '%s' not found in environment", e.Varname)
+		return nil, chapter3.UnboundVariableError{Name: e.Varname}
 	}
 
 	// log.Printf("assign evaluated %s to %v. Found Ref %p for var %s. Updating ref.\n", e.Expr.Repr(), newValue, varRef, e.Varname)
 
 	// Update the value *inside* the existing reference cell for the variable
+	oldValue := varRef.Value
 	varRef.Value = newValue
+	l.NotifyStoreChanged(varRef, oldValue, newValue)
 
 	// 'set' returns the new value
 	return newValue, nil
 }
+
+// valueOfSetField handles 'set r.x = expr'.  Only fields the record already
+// has can be set.
+func (l *ImpRefLangEval) valueOfSetField(e *SetFieldExpr, env *epl.Env[any]) (any, error) {
+	record, err := chapter3.EvalRecord(l, "set field '"+e.Field+"'", e.Record, env)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := record[e.Field]; !ok {
+		return nil, MissingFieldError{Field: e.Field}
+	}
+	val, err := l.Eval(e.Expr, env)
+	if err != nil {
+		return nil, err
+	}
+	newValue, err := chapter3.AsValue("field '"+e.Field+"'", val)
+	if err != nil {
+		return nil, err
+	}
+	record[e.Field] = newValue
+	return newValue, nil
+}
@@ -3,7 +3,7 @@ package chapter4
 import (
 	"testing"
 
-	// epl "github.com/panyam/eplgo"          // Base definitions (Not directly needed here)
+	epl "github.com/panyam/eplgo"      // Base definitions
 	"github.com/panyam/eplgo/chapter3" // AST nodes and base evaluator helpers
 	"github.com/stretchr/testify/assert"
 )
@@ -235,3 +235,58 @@ func TestAssignExprEqPrintable(t *testing.T) {
 	// Printable - rely on common_test.go for formatting checks
 	// Structural check could be added if desired
 }
+
+func TestAssignUnboundVariable(t *testing.T) {
+	evaluator := NewTestImpRefLangEval()
+	_, err := evaluator.Eval(Assign("nope", 1), epl.NewEnv[any](nil))
+	var unbound UnboundVariableError
+	assert.ErrorAs(t, err, &unbound)
+	assert.Equal(t, "nope", unbound.Name)
+}
+
+type storeObserver struct {
+	chapter3.NopObserver
+	changes [][2]any
+}
+
+func (s *storeObserver) StoreChanged(ref *epl.Ref[any], oldValue, newValue any) {
+	s.changes = append(s.changes, [2]any{oldValue, newValue})
+}
+
+func TestStoreChangedEvents(t *testing.T) {
+	evaluator := NewImpRefLangEval()
+	chapter3.SetOpFuncs(evaluator)
+	obs := &storeObserver{}
+	evaluator.AddObserver(obs)
+
+	// let x = 1 in let r = newref(10) in begin set x = 2; setref(r, 20) end
+	expr := chapter3.Let(chapter3.ExprDict("x", chapter3.Lit(1)),
+		chapter3.Let(chapter3.ExprDict("r", NewRef(10)),
+			Begin(Assign("x", 2), SetRef("r", 20))))
+	_, err := evaluator.Eval(expr, epl.NewEnv[any](nil))
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]any{{IntVal(1), IntVal(2)}, {IntVal(10), IntVal(20)}}, obs.changes)
+}
+
+func TestSetField(t *testing.T) {
+	evaluator := NewTestImpRefLangEval()
+	// let r = {x = 1, y = 2} in let s = r in begin set r.x = 10; s.x end == 10
+	// - records are shared, so s sees the change made through r.
+	point := Record(ExprDict("x", Lit(1), "y", Lit(2)))
+	expr := Let(ExprDict("r", point),
+		Let(ExprDict("s", Var("r")),
+			Begin(SetField("r", "x", 10), Field("s", "x"))))
+	RunImpRefTest(t, evaluator, &chapter3.TestCase{Name: "set_field_shared", Expected: 10, Expr: expr}, nil)
+
+	// let r = {x = 1} in set r.x = +(r.x, 5) == 6
+	expr = Let(ExprDict("r", Record(ExprDict("x", Lit(1)))), SetField("r", "x", Op("+", Field("r", "x"), 5)))
+	RunImpRefTest(t, evaluator, &chapter3.TestCase{Name: "set_field_return_val", Expected: 6, Expr: expr}, nil)
+
+	_, err := evaluator.Eval(SetField(Record(ExprDict("x", Lit(1))), "z", 1), epl.NewEnv[any](nil))
+	assert.ErrorIs(t, err, MissingFieldError{Field: "z"})
+	_, err = evaluator.Eval(SetField(1, "x", 1), epl.NewEnv[any](nil))
+	assert.ErrorAs(t, err, &TypeMismatchError{})
+
+	assert.True(t, chapter3.ExprEq(SetField("r", "x", 1), SetField("r", "x", 1)))
+	assert.False(t, chapter3.ExprEq(SetField("r", "x", 1), SetField("r", "y", 1)))
+}
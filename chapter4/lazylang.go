@@ -2,7 +2,6 @@ package chapter4
 
 import (
 	"fmt"
-	"log"
 
 	epl "github.com/panyam/eplgo"
 )
@@ -72,26 +71,6 @@ func (e *ThunkExpr) Eq(another *ThunkExpr) bool {
 	return ExprEq(e.Expr, another.Expr)
 }
 
-// Thunk is the *value* representing a delayed computation.
-// It is NOT an AST node (Expr). It's returned by evaluating LazyExpr.
-type Thunk struct {
-	Expr Expr          // The unevaluated expression.
-	Env  *epl.Env[any] // The environment captured when the LazyExpr was evaluated.
-}
-
-// Repr for Thunk value (for debugging evaluator results)
-func (t *Thunk) Repr() string {
-	return fmt.Sprintf("<ThunkValue Expr:%s Env:%p>", t.Expr.Repr(), t.Env)
-}
-
-// Note: We might need a way to compare Thunk values if they appear in test results,
-// but strict equality based on Env pointer might be too restrictive. Comparing Expr might suffice.
-// Add Eq if needed:
-// func (t *Thunk) Eq(another *Thunk) bool { ... }
-
-// --- Ensure ExprEq handles these via reflection ---
-// No changes needed in chapter3/expr.go.:w
-
 // LazyLangEval evaluates expressions including lazy evaluation constructs.
 type LazyLangEval struct {
 	ImpRefLangEval // Embed the previous evaluator
@@ -123,7 +102,7 @@ func (l *LazyLangEval) LocalEval(expr Expr, env *epl.Env[any]) (any, error) {
 func (l *LazyLangEval) valueOfLazyExpr(e *LazyExpr, env *epl.Env[any]) (any, error) {
 	// Package the expression and the *current* environment into a Thunk value.
 	// Do not evaluate e.Expr yet.
-	thunkValue := &Thunk{
+	thunkValue := &ThunkVal{
 		Expr: e.Expr,
 		Env:  env, // Capture the current environment
 	}
@@ -140,9 +119,9 @@ func (l *LazyLangEval) valueOfThunkExpr(e *ThunkExpr, env *epl.Env[any]) (any, e
 	}
 
 	// 2. Check if the result is actually a Thunk.
-	thunkValue, ok := value.(*Thunk)
+	thunkValue, ok := value.(*ThunkVal)
 	if !ok {
-		log.Panicf("thunk operator expected a thunk value, but got type %T for expr %s", value, e.Expr.Repr())
+		return nil, NotAThunkError{Expr: e.Expr, Found: value}
 	}
 
 	// log.Printf("thunk forcing evaluation of %s in Env %p\n", thunkValue.Expr.Repr(), thunkValue.Env)
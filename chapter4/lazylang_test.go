@@ -3,9 +3,9 @@ package chapter4
 import (
 	"testing"
 
+	epl "github.com/panyam/eplgo"
 	"github.com/panyam/eplgo/chapter3" // AST nodes and base evaluator helpers
 	"github.com/stretchr/testify/assert"
-	// No specific epl import needed unless using ExpectRef etc.
 )
 
 // Helper to create the evaluator and set standard operators
@@ -143,3 +143,9 @@ func TestLazyExprEqPrintable(t *testing.T) {
 
 	// Printable - rely on common_test.go for formatting checks
 }
+
+func TestNotAThunkError(t *testing.T) {
+	evaluator := NewTestLazyLangEval()
+	_, err := evaluator.Eval(ForceThunk(5), epl.NewEnv[any](nil))
+	assert.ErrorAs(t, err, &NotAThunkError{})
+}
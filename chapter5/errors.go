@@ -4,8 +4,7 @@ import (
 	"fmt"
 	"reflect" // For detailed error message if needed
 
-	epl "github.com/panyam/eplgo" // For potentially checking if value is Expr
-	// For potentially checking if value is Expr
+	"github.com/panyam/eplgo/chapter3"
 )
 
 // RaisedError is a custom error type used to signal exceptions
@@ -13,22 +12,25 @@ import (
 // It wraps the actual value that was raised.
 type RaisedError struct {
 	Value any // The value passed to the 'raise' expression.
+
+	// The EPL call stack at the point the value was raised.
+	Trace *chapter3.StackTrace
+}
+
+// Traceback returns the EPL stack trace captured by the 'raise'.
+func (e RaisedError) Traceback() *chapter3.StackTrace {
+	return e.Trace
 }
 
 // Error implements the standard Go error interface.
 func (e RaisedError) Error() string {
 	// Provide a helpful error message including the raised value's representation.
 	var valueRepr string
-	if expr, ok := e.Value.(Expr); ok {
-		// If the value is an Expr, use its Repr() method.
-		valueRepr = expr.Repr()
-	} else if ref, ok := e.Value.(*epl.Ref[any]); ok {
+	if ref, ok := e.Value.(RefVal); ok {
 		// If it's a reference, show the value inside
-		if exprVal, okVal := ref.Value.(Expr); okVal {
-			valueRepr = fmt.Sprintf("Ref(%s)", exprVal.Repr())
-		} else {
-			valueRepr = fmt.Sprintf("Ref(%v:%T)", ref.Value, ref.Value)
-		}
+		valueRepr = fmt.Sprintf("Ref(%s)", ReprOf(ref.Value))
+	} else if val, ok := e.Value.(Value); ok {
+		valueRepr = val.Repr()
 	} else {
 		// Otherwise, use default formatting.
 		valueRepr = fmt.Sprintf("%v:%T", e.Value, e.Value)
@@ -99,6 +99,12 @@ func (e *RaiseExpr) Eq(another *RaiseExpr) bool {
 // TryLangEval evaluates expressions including try/catch and raise.
 type TryLangEval struct {
 	chapter4.LazyLangEval // Embed the previous evaluator
+
+	// CatchRuntimeErrors lets 'try' handlers also catch errors produced by the
+	// interpreter itself (see chapter3.RuntimeError), eg unbound variables or
+	// type mismatches.  The handler variable is bound to the error as a
+	// chapter3.ErrorVal.
+	CatchRuntimeErrors bool
 }
 
 // NewTryLangEval creates a new evaluator for the Chapter 5 language.
@@ -129,13 +135,13 @@ func (l *TryLangEval) valueOfRaise(e *RaiseExpr, env *epl.Env[any]) (any, error)
 	raisedValue, err := l.Eval(e.RaiseValueExpr, env)
 	if err != nil {
 		// If evaluating the value itself causes an error, propagate that error.
-		return nil, fmt.Errorf("evaluating expression for raise: %w", err)
+		return nil, l.WrapError(err, "evaluating expression for raise")
 	}
 
 	// 2. Wrap the evaluated value in our custom RaisedError type.
 	//    Return nil value and the RaisedError.
 	// log.Printf("Raising value %v (%T)\n", raisedValue, raisedValue)
-	return nil, RaisedError{Value: raisedValue}
+	return nil, RaisedError{Value: raisedValue, Trace: l.StackTrace()}
 }
 
 // valueOfTry handles 'try E catch (x) H'.
@@ -167,6 +173,11 @@ func (l *TryLangEval) valueOfTry(e *TryExpr, env *epl.Env[any]) (any, error) {
 			// The result of the entire 'try' expression is the result of the handler.
 			// Propagate any result or error from the handler evaluation.
 			return l.Eval(e.HandlerExpr, handlerEnv)
+		}
+		var runtimeErr RuntimeError
+		if l.CatchRuntimeErrors && errors.As(tryErr, &runtimeErr) {
+			handlerEnv := env.Extend(epl.Dict[string, any](e.VarName, chapter3.ErrorVal{Err: runtimeErr}))
+			return l.Eval(e.HandlerExpr, handlerEnv)
 		} else {
 			// It's some other kind of error (e.g., variable not found, op type error).
 			// This 'try/catch' does not handle it. Propagate the original error.
@@ -7,7 +7,7 @@ import (
 
 	epl "github.com/panyam/eplgo" // Base definitions
 	// AST nodes and base evaluator helpers
-	"github.com/panyam/eplgo/chapter4" // Need AST nodes like Begin, Assign
+	"github.com/panyam/eplgo/chapter3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -57,7 +57,7 @@ func RunTryLangTest(t *testing.T, e Evaluator, tc *TestCase, extraenv map[string
 			require.True(t, isRaised, "Test %s: Expected RaisedError type, got %T", tc.Name, err)
 			// Compare the wrapped values for specific RaisedError expectation
 			expectedRaised := expectedErr.(RaisedError)
-			assert.ObjectsAreEqualValues(expectedRaised.Value, actualRaised.Value) // Compare values inside errors
+			assert.True(t, ValueEq(expectedRaised.Value, actualRaised.Value), "Test %s: Raised value mismatch", tc.Name)
 		} else {
 			// Check for other specific errors
 			assert.ErrorIs(t, err, expectedErr, "Test %s: Error mismatch", tc.Name)
@@ -72,29 +72,23 @@ func RunTryLangTest(t *testing.T, e Evaluator, tc *TestCase, extraenv map[string
 	// --- Assert Value if no error was expected ---
 	// Use logic similar to RunExpRefTest for value comparison
 	switch expected := tc.Expected.(type) {
-	case int:
-		finalValue, ok := value.(*LitExpr)
-		require.True(t, ok, "Test %s: Expected LitExpr(int) but got %T (%#v)", tc.Name, value, value)
-		assert.Equal(t, expected, finalValue.Value, "Test %s Failed - Int Value", tc.Name)
-	case bool:
-		finalValue, ok := value.(*LitExpr)
-		require.True(t, ok, "Test %s: Expected LitExpr(bool) but got %T (%#v)", tc.Name, value, value)
-		assert.Equal(t, expected, finalValue.Value, "Test %s Failed - Bool Value", tc.Name)
-	case *epl.Ref[any]:
-		actualRef, ok := value.(*epl.Ref[any])
-		require.True(t, ok, "Test %s: Expected *epl.Ref[any] but got %T (%#v)", tc.Name, value, value)
-		assert.ObjectsAreEqualValues(expected.Value, actualRef.Value) // Compare values inside refs
+	case int, bool:
+		AssertValue(t, tc.Name, expected, value)
+	case RefVal:
+		actualRef, ok := value.(RefVal)
+		require.True(t, ok, "Test %s: Expected RefVal but got %T (%#v)", tc.Name, value, value)
+		assert.True(t, ValueEq(expected.Value, actualRef.Value), "Test %s: Ref contents mismatch", tc.Name)
 	case struct{ typeIsRef bool }: // Sentinel struct from Ch4 tests
 		if expected.typeIsRef {
-			_, ok := value.(*epl.Ref[any])
-			assert.True(t, ok, "Test %s: Expected a reference (*epl.Ref[any]) but got %T (%#v)", tc.Name, value, value)
+			_, ok := value.(RefVal)
+			assert.True(t, ok, "Test %s: Expected a reference (RefVal) but got %T (%#v)", tc.Name, value, value)
 		} else {
 			t.Fatalf("Test %s: Invalid use of Ref sentinel expectation", tc.Name)
 		}
 	// Add case for Thunk if needed for comparison
-	case *chapter4.Thunk:
-		actualThunk, ok := value.(*chapter4.Thunk)
-		require.True(t, ok, "Test %s: Expected *chapter4.Thunk but got %T (%#v)", tc.Name, value, value)
+	case *ThunkVal:
+		actualThunk, ok := value.(*ThunkVal)
+		require.True(t, ok, "Test %s: Expected *ThunkVal but got %T (%#v)", tc.Name, value, value)
 		// Basic comparison: check if expressions are equal. Env comparison is tricky.
 		assert.True(t, ExprEq(expected.Expr, actualThunk.Expr), "Test %s: Thunk expression mismatch", tc.Name)
 		// Could add more checks if needed (e.g., env non-nil)
@@ -169,7 +163,7 @@ func TestUncaughtRaise(t *testing.T) {
 	// Expect a RaisedError containing Lit(100)
 	tc := TestCase{
 		Name:     "uncaught_raise",
-		Expected: RaisedError{Value: Lit(100)}, // Expect specific error
+		Expected: RaisedError{Value: IntVal(100)}, // Expect specific error
 		Expr:     expr,
 	}
 	RunTryLangTest(t, evaluator, &tc, nil)
@@ -178,7 +172,7 @@ func TestUncaughtRaise(t *testing.T) {
 	exprLet := Let(ExprDict("x", Raise(99)), Op("+", Var("x"), 1))
 	tcLet := TestCase{
 		Name:     "uncaught_raise_in_let",
-		Expected: RaisedError{Value: Lit(99)}, // Expect specific error
+		Expected: RaisedError{Value: IntVal(99)}, // Expect specific error
 		Expr:     exprLet,
 	}
 	RunTryLangTest(t, evaluator, &tcLet, nil)
@@ -221,3 +215,122 @@ func TestTryLangEqPrintable(t *testing.T) {
 
 	// Printable - rely on common_test.go for formatting checks
 }
+
+func TestTryCatchRuntimeErrors(t *testing.T) {
+	// try -(y, 1) catch (x) 42 // y is unbound
+	expr := Try(Op("-", Var("y"), 1), "x", 42)
+
+	// By default runtime errors pass straight through the handler
+	evaluator := NewTryLangEval()
+	SetOpFuncs(evaluator)
+	_, err := evaluator.Eval(expr, epl.NewEnv[any](nil))
+	assert.ErrorAs(t, err, &UnboundVariableError{})
+
+	// ... but can be caught if asked for
+	evaluator.CatchRuntimeErrors = true
+	tc := TestCase{Name: "try_catch_runtime_error", Expected: 42, Expr: expr}
+	RunTryLangTest(t, evaluator, &tc, nil)
+
+	// The handler variable is bound to the error as a value
+	exprBound := Try(IsZero(true), "x", Var("x"))
+	value, err := evaluator.Eval(exprBound, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	require.IsType(t, chapter3.ErrorVal{}, value)
+	assert.ErrorAs(t, value.(chapter3.ErrorVal).Err, &TypeMismatchError{})
+}
+
+func TestRaisedErrorStackTrace(t *testing.T) {
+	// letrec thrower(x) = raise x in (thrower 7)
+	expr := LetRec(ProcMap("thrower", Proc([]string{"x"}, Raise(Var("x")))), Call("thrower", 7))
+	_, err := NewTestTryLangEval().Eval(expr, epl.NewEnv[any](nil))
+
+	var raised RaisedError
+	require.ErrorAs(t, err, &raised)
+	require.NotNil(t, raised.Trace)
+	require.Len(t, raised.Trace.Frames, 1)
+	assert.Equal(t, "thrower", raised.Trace.Frames[0].ProcName)
+	assert.Same(t, raised.Trace, chapter3.TracebackOf(err))
+}
+
+func TestRaiseStringValue(t *testing.T) {
+	evaluator := SetStringOpFuncs(NewTestTryLangEval())
+	// try raise "ListIndexFailed" catch (e) if stringequals(e, "ListIndexFailed") then 1 else 0
+	expr := Try(Raise(Lit("ListIndexFailed")), "e",
+		If(Op("stringequals", Var("e"), Lit("ListIndexFailed")), 1, 0))
+	tc := TestCase{Name: "raise_string", Expected: 1, Expr: expr}
+	RunTryLangTest(t, evaluator, &tc, nil)
+
+	_, err := evaluator.Eval(Raise(Lit("oops")), epl.NewEnv[any](nil))
+	var raised RaisedError
+	require.ErrorAs(t, err, &raised)
+	assert.Equal(t, StringVal("oops"), raised.Value)
+	assert.Equal(t, `raised value: String("oops")`, raised.Error())
+}
+
+func TestListIndex(t *testing.T) {
+	// From tests/chapter5/cases.py:
+	//
+	//	let index = proc(str)
+	//	    letrec inner(lst) =
+	//	        if (isnull lst) then raise ("ListIndexFailed")
+	//	        else if (stringequals (car lst) str) then 0
+	//	        else -((inner (cdr lst)), -1)
+	//	    in inner
+	//	in ((index "c") list("a", "b", "c"))
+	index := Proc([]string{"str"}, LetRec(ProcMap("inner", Proc([]string{"lst"},
+		If(Op("isnull", "lst"), Raise(Lit("ListIndexFailed")),
+			If(Op("stringequals", Op("car", "lst"), "str"), 0,
+				Op("-", Call("inner", Op("cdr", "lst")), -1))))),
+		Var("inner")))
+	evaluator := SetListOpFuncs(SetStringOpFuncs(NewTestTryLangEval()))
+	call := func(s string) Expr {
+		return Let(ExprDict("index", index), Call(Call("index", Lit(s)), List(Lit("a"), Lit("b"), Lit("c"))))
+	}
+	tc := TestCase{Name: "listindex_found", Expected: 2, Expr: call("c")}
+	RunTryLangTest(t, evaluator, &tc, nil)
+
+	tc = TestCase{Name: "listindex_missing", Expected: RaisedError{Value: StringVal("ListIndexFailed")}, Expr: call("z")}
+	RunTryLangTest(t, evaluator, &tc, nil)
+}
+
+func TestCatchEmptyList(t *testing.T) {
+	evaluator := SetListOpFuncs(NewTestTryLangEval()).(*TryLangEval)
+	evaluator.CatchRuntimeErrors = true
+	// try car(emptylist()) catch (e) -1
+	expr := Try(Op("car", Op("emptylist")), "e", -1)
+	tc := TestCase{Name: "catch_empty_list", Expected: -1, Expr: expr}
+	RunTryLangTest(t, evaluator, &tc, nil)
+}
+
+func TestCatchBindsErrorValue(t *testing.T) {
+	evaluator := chapter3.SetStringOpFuncs(SetListOpFuncs(NewTestTryLangEval())).(*TryLangEval)
+	evaluator.CatchRuntimeErrors = true
+	emptyCar := Op("car", Op("emptylist"))
+	message := StringVal(chapter3.EmptyListError{Op: "car"}.Error())
+	cases := []TestCase{
+		// try car(emptylist()) catch (e) tostring(e)
+		{Name: "tostring", Expected: message, Expr: Try(emptyCar, "e", Op("tostring", "e"))},
+		// try car(emptylist()) catch (e) (e, 1)
+		{Name: "tuple", Expected: chapter3.TupleVal{chapter3.ErrorVal{Err: chapter3.EmptyListError{Op: "car"}}, IntVal(1)},
+			Expr: Try(emptyCar, "e", chapter3.Tuple(Var("e"), Lit(1)))},
+		{Name: "value", Expected: chapter3.ErrorVal{Err: chapter3.EmptyListError{Op: "car"}}, Expr: Try(emptyCar, "e", "e")},
+	}
+	for _, tc := range cases {
+		RunTryLangTest(t, evaluator, &tc, nil)
+	}
+}
+
+func TestCatchTupleArity(t *testing.T) {
+	evaluator := NewTestTryLangEval().(*TryLangEval)
+	evaluator.CatchRuntimeErrors = true
+	// try unpack x y = (1, 2, 3) in x catch (e) -1
+	expr := Try(chapter3.Unpack([]string{"x", "y"}, chapter3.Tuple(Lit(1), Lit(2), Lit(3)), "x"), "e", -1)
+	tc := TestCase{Name: "catch_tuple_arity", Expected: -1, Expr: expr}
+	RunTryLangTest(t, evaluator, &tc, nil)
+
+	// The handler variable is bound to the error
+	expr = Try(chapter3.TupleRef(chapter3.Tuple(Lit(1)), 1), "e", Var("e"))
+	value, err := evaluator.Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	assert.Equal(t, chapter3.ErrorVal{Err: chapter3.IndexError{Context: "tuple index", Index: 1, Length: 1}}, value)
+}
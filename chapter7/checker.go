@@ -0,0 +1,488 @@
+package chapter7
+
+import (
+	"fmt"
+
+	epl "github.com/panyam/eplgo"
+	gfn "github.com/panyam/goutils/fn"
+)
+
+// TypeEnv maps names to their types.
+type TypeEnv = epl.Env[Type]
+
+type typeChecker interface {
+	LocalTypeOf(expr Expr, tenv *TypeEnv) (Type, error)
+
+	// Unannotated returns the type to use for something without a type
+	// annotation, described by context.
+	Unannotated(context string) (Type, error)
+}
+
+// TypeChecker finds the types of the expressions in the CHECKED language -
+// the chapter3 languages, with the references, assignment and laziness of
+// chapter4, the exceptions of chapter5, tagged values and datatypes, with
+// procedure parameters annotated with their types.  Like the evaluators,
+// checkers for bigger languages embed it and handle their own expressions in
+// LocalTypeOf before deferring to it.
+//
+// Types are matched by unification so the same rules serve the INFERRED
+// language (see Inferencer), where missing annotations are type variables.
+// In CHECKED the only type variables are those of polymorphic operators like
+// car.
+type TypeChecker struct {
+	Self    typeChecker
+	OpTypes map[string]OpType
+
+	// The type of the values raised as exceptions.  If nil it is found from
+	// the program, which in CHECKED means it must be declared here.
+	ExceptionType Type
+
+	// The solutions for the type variables found so far
+	Subst   Substitution
+	nextVar int
+
+	// The exception type of the expression being checked
+	excType Type
+
+	// The datatypes and constructors declared so far
+	datatypes    map[string]*DatatypeDecl
+	constructors map[string]*constructorInfo
+}
+
+func NewTypeChecker() *TypeChecker {
+	out := &TypeChecker{OpTypes: DefaultOpTypes(), Subst: Substitution{}}
+	out.Self = out
+	return out
+}
+
+// Check returns the fully resolved type of a closed expression.
+func (c *TypeChecker) Check(expr Expr) (Type, error) {
+	c.Subst, c.excType = Substitution{}, c.ExceptionType
+	c.datatypes, c.constructors = map[string]*DatatypeDecl{}, map[string]*constructorInfo{}
+	t, err := c.TypeOf(expr, epl.NewEnv[Type](nil))
+	if err != nil {
+		return nil, err
+	}
+	return c.Subst.Apply(t), nil
+}
+
+// Unannotated returns a MissingAnnotationError as CHECKED needs every
+// procedure parameter annotated.
+func (c *TypeChecker) Unannotated(context string) (Type, error) {
+	return nil, MissingAnnotationError{Context: context}
+}
+
+// Fresh returns a new type variable.
+func (c *TypeChecker) Fresh() *TypeVar {
+	c.nextVar++
+	return &TypeVar{ID: c.nextVar}
+}
+
+// Unify makes two types the same, solving for type variables, or returns a
+// TypeError if they cannot be.
+func (c *TypeChecker) Unify(expected, found Type) error {
+	return c.Subst.Unify(expected, found, c.Fresh)
+}
+
+// TypeOf returns the type of an expression in a type environment.
+func (c *TypeChecker) TypeOf(expr Expr, tenv *TypeEnv) (Type, error) {
+	return c.Self.LocalTypeOf(expr, tenv)
+}
+
+func (c *TypeChecker) LocalTypeOf(expr Expr, tenv *TypeEnv) (Type, error) {
+	switch n := expr.(type) {
+	case *LitExpr:
+		return c.TypeOfLit(n, tenv)
+	case *VarExpr:
+		return c.TypeOfVar(n, tenv)
+	case *OpExpr:
+		return c.TypeOfOpExpr(n, tenv)
+	case *IsZeroExpr:
+		return c.TypeOfIsZeroExpr(n, tenv)
+	case *IfExpr:
+		return c.TypeOfIfExpr(n, tenv)
+	case *LetExpr:
+		return c.TypeOfLetExpr(n, tenv)
+	case *TupleExpr:
+		return c.TypeOfTupleExpr(n, tenv)
+	case *ListExpr:
+		return c.TypeOfListExpr(n, tenv)
+	case *UnpackExpr:
+		return c.TypeOfUnpack(n, tenv)
+	case *TupleRefExpr:
+		return c.TypeOfTupleRef(n, tenv)
+	case *RecordExpr:
+		return c.TypeOfRecord(n, tenv)
+	case *FieldExpr:
+		return c.TypeOfField(n, tenv)
+	case *UpdateExpr:
+		return c.TypeOfUpdate(n, tenv)
+	case *ProcExpr:
+		return c.TypeOfProc(n, tenv)
+	case *CallExpr:
+		return c.TypeOfCall(n, tenv)
+	case *LetRecExpr:
+		return c.TypeOfLetRec(n, tenv)
+	case *RefExpr:
+		return c.TypeOfRef(n, tenv)
+	case *DeRefExpr:
+		return c.TypeOfDeRef(n, tenv)
+	case *SetRefExpr:
+		return c.TypeOfSetRef(n, tenv)
+	case *BlockExpr:
+		return c.TypeOfBlock(n, tenv)
+	case *AssignExpr:
+		return c.TypeOfAssign(n, tenv)
+	case *SetFieldExpr:
+		return c.TypeOfSetField(n, tenv)
+	case *LazyExpr:
+		return c.TypeOfLazy(n, tenv)
+	case *ThunkExpr:
+		return c.TypeOfThunk(n, tenv)
+	case *TryExpr:
+		return c.TypeOfTry(n, tenv)
+	case *RaiseExpr:
+		return c.TypeOfRaise(n, tenv)
+	case *TagExpr:
+		return c.TypeOfTag(n, tenv)
+	case *CasesExpr:
+		return c.TypeOfCases(n, tenv)
+	case *DataProgramExpr:
+		return c.TypeOfDataProgram(n, tenv)
+	case *MatchExpr:
+		return c.TypeOfMatch(n, tenv)
+	}
+	return nil, UnsupportedExprError{Expr: expr}
+}
+
+// TypeOfExprList returns the types of a list of expressions.
+func (c *TypeChecker) TypeOfExprList(exprs []Expr, tenv *TypeEnv) ([]Type, error) {
+	out := make([]Type, len(exprs))
+	for i, e := range exprs {
+		var err error
+		if out[i], err = c.TypeOf(e, tenv); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Only integers are typed - floats and rationals have no type in CHECKED.
+func (c *TypeChecker) TypeOfLit(lit *LitExpr, tenv *TypeEnv) (Type, error) {
+	val, _ := ToValue(lit.Value)
+	switch val.(type) {
+	case IntVal, BigIntVal:
+		return Int, nil
+	case BoolVal:
+		return Bool, nil
+	case StringVal:
+		return String, nil
+	}
+	return nil, UnsupportedExprError{Expr: lit}
+}
+
+// The type of a variable with a Scheme, like a let bound procedure in
+// PolyInferencer or a datatype's constructor, is a fresh instance of it.
+func (c *TypeChecker) TypeOfVar(e *VarExpr, tenv *TypeEnv) (Type, error) {
+	t, found := tenv.Get(e.Name)
+	if !found {
+		return nil, UnboundVariableError{Name: e.Name}
+	}
+	return c.Instantiate(t), nil
+}
+
+func (c *TypeChecker) TypeOfOpExpr(e *OpExpr, tenv *TypeEnv) (Type, error) {
+	optype := c.OpTypes[e.Op]
+	if optype == nil {
+		return nil, UnknownOperatorError{Op: e.Op}
+	}
+	args, err := c.TypeOfExprList(e.Args, tenv)
+	if err != nil {
+		return nil, err
+	}
+	return optype(c, args)
+}
+
+func (c *TypeChecker) TypeOfIsZeroExpr(e *IsZeroExpr, tenv *TypeEnv) (Type, error) {
+	t, err := c.TypeOf(e.Expr, tenv)
+	if err != nil {
+		return nil, err
+	}
+	return Bool, c.Unify(Int, t)
+}
+
+// Both branches of an if must have the same type.
+func (c *TypeChecker) TypeOfIfExpr(e *IfExpr, tenv *TypeEnv) (Type, error) {
+	cond, err := c.TypeOf(e.Cond, tenv)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Unify(Bool, cond); err != nil {
+		return nil, err
+	}
+	then, err := c.TypeOf(e.Then, tenv)
+	if err != nil {
+		return nil, err
+	}
+	els, err := c.TypeOf(e.Else, tenv)
+	if err != nil {
+		return nil, err
+	}
+	return then, c.Unify(then, els)
+}
+
+func (c *TypeChecker) TypeOfLetExpr(e *LetExpr, tenv *TypeEnv) (Type, error) {
+	types := map[string]Type{}
+	for _, name := range epl.SortedKeys(e.Mappings) {
+		t, err := c.TypeOf(e.Mappings[name], tenv)
+		if err != nil {
+			return nil, err
+		}
+		types[name] = t
+	}
+	return c.TypeOf(e.Body, tenv.Extend(types))
+}
+
+func (c *TypeChecker) TypeOfTupleExpr(e *TupleExpr, tenv *TypeEnv) (Type, error) {
+	children, err := c.TypeOfExprList(e.Children, tenv)
+	if err != nil {
+		return nil, err
+	}
+	return TupleOf(children...), nil
+}
+
+// The names of an unpack get the types of the items of the tuple, which
+// must have as many items as there are names, or all get the type of the
+// items of a list.
+func (c *TypeChecker) TypeOfUnpack(e *UnpackExpr, tenv *TypeEnv) (Type, error) {
+	t, err := c.TypeOf(e.Expr, tenv)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Type, len(e.Names))
+	if list, ok := c.Subst.Resolve(t).(*TaggedType); ok && list.Name == "list" {
+		for i := range items {
+			items[i] = list.Type
+		}
+	} else {
+		for i := range items {
+			items[i] = c.Fresh()
+		}
+		if err := c.Unify(TupleOf(items...), t); err != nil {
+			return nil, err
+		}
+	}
+	return c.TypeOf(e.Body, tenv.Extend(epl.DictZip(e.Names, items)))
+}
+
+// The tuple indexed must already be known to be a tuple with an item at the
+// index - there are no types for tuples with at least so many items.
+func (c *TypeChecker) TypeOfTupleRef(e *TupleRefExpr, tenv *TypeEnv) (Type, error) {
+	t, err := c.TypeOf(e.Tuple, tenv)
+	if err != nil {
+		return nil, err
+	}
+	tuple, ok := c.Subst.Resolve(t).(*TupleType)
+	if !ok || e.Index < 0 || e.Index >= len(tuple.Children) {
+		return nil, TupleIndexError{Index: e.Index, Type: c.Subst.Apply(t)}
+	}
+	return tuple.Children[e.Index], nil
+}
+
+// A list literal is typed like the 'list' operator.
+func (c *TypeChecker) TypeOfListExpr(e *ListExpr, tenv *TypeEnv) (Type, error) {
+	items, err := c.TypeOfExprList(e.Items, tenv)
+	if err != nil {
+		return nil, err
+	}
+	return c.listType(items)
+}
+
+// annotation returns the Type of an annotation in the AST, nil if there is
+// none.
+func annotation(a TypeAnnotation) (Type, error) {
+	if a == nil {
+		return nil, nil
+	}
+	t, ok := a.(Type)
+	if !ok {
+		return nil, fmt.Errorf("annotation %s is not a chapter7 Type", a)
+	}
+	return t, nil
+}
+
+// TypedProc is a constructor for a ProcExpr with annotated parameters and,
+// unless result is nil, result.
+func TypedProc(varnames []string, argTypes []Type, result Type, body any) *ProcExpr {
+	out := Proc(varnames, AnyToExpr(body))
+	out.ArgTypes = gfn.Map(argTypes, func(t Type) TypeAnnotation { return t })
+	if result != nil {
+		out.ResultType = result
+	}
+	return out
+}
+
+// paramTypes returns the types of a procedure's parameters.
+func (c *TypeChecker) paramTypes(e *ProcExpr) ([]Type, error) {
+	out := make([]Type, len(e.Varnames))
+	for i, name := range e.Varnames {
+		var err error
+		if i < len(e.ArgTypes) {
+			out[i], err = annotation(e.ArgTypes[i])
+		}
+		if err == nil && out[i] == nil {
+			out[i], err = c.Self.Unannotated(fmt.Sprintf("parameter '%s' of %s", name, procName(e)))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func procName(e *ProcExpr) string {
+	if e.Name == "" {
+		return "proc"
+	}
+	return "proc " + e.Name
+}
+
+// The result type of a procedure is that of its body.  If annotated it
+// must match.
+func (c *TypeChecker) TypeOfProc(e *ProcExpr, tenv *TypeEnv) (Type, error) {
+	fn, err := c.procType(e)
+	if err != nil {
+		return nil, err
+	}
+	return fn, c.checkProcBody(e, fn, tenv)
+}
+
+// procType returns the type of a procedure from its annotations, with a nil
+// Result if it has no result annotation.
+func (c *TypeChecker) procType(e *ProcExpr) (*FuncType, error) {
+	args, err := c.paramTypes(e)
+	if err != nil {
+		return nil, err
+	}
+	result, err := annotation(e.ResultType)
+	if err != nil {
+		return nil, err
+	}
+	return Func(args, result), nil
+}
+
+// checkProcBody checks the body of a procedure against its type, filling in
+// the type's Result if it is nil.
+func (c *TypeChecker) checkProcBody(e *ProcExpr, fn *FuncType, tenv *TypeEnv) error {
+	body, err := c.TypeOf(e.Body, tenv.Extend(epl.DictZip(e.Varnames, fn.Args)))
+	if err != nil {
+		return err
+	}
+	if fn.Result == nil {
+		fn.Result = body
+		return nil
+	}
+	return c.Unify(fn.Result, body)
+}
+
+// Calls are typed the way ProcLangEval applies procedures - a call with too
+// few arguments is a partial application and extra arguments are passed to
+// the procedure returned.
+func (c *TypeChecker) TypeOfCall(e *CallExpr, tenv *TypeEnv) (Type, error) {
+	operator, err := c.TypeOf(e.Operator, tenv)
+	if err != nil {
+		return nil, err
+	}
+	args, err := c.TypeOfExprList(e.Args, tenv)
+	if err != nil {
+		return nil, err
+	}
+	return c.ApplyType(operator, args)
+}
+
+// ApplyType returns the type of applying a procedure of type operator to
+// arguments of the given types.
+func (c *TypeChecker) ApplyType(operator Type, args []Type) (Type, error) {
+	initialCall := true
+	for {
+		operator = c.Subst.Resolve(operator)
+		if tv, ok := operator.(*TypeVar); ok && (initialCall || len(args) > 0) {
+			// Nothing is known about the procedure but it must take these
+			result := c.Fresh()
+			return result, c.Unify(Func(args, result), tv)
+		}
+		fn, ok := operator.(*FuncType)
+		if !ok {
+			if !initialCall && len(args) == 0 {
+				return operator, nil
+			}
+			return nil, TypeError{Expected: Func(c.applyAll(args), nil), Found: c.Subst.Apply(operator)}
+		}
+		if len(fn.Args) == 0 {
+			if len(args) > 0 {
+				return nil, ArityError{Context: "Procedure of type " + fn.String(), Expected: 0, Found: len(args)}
+			}
+			operator, initialCall = fn.Result, false
+			continue
+		}
+		if len(args) == 0 {
+			if initialCall {
+				return nil, ArityError{Context: "Procedure of type " + fn.String(), Expected: len(fn.Args), Found: 0}
+			}
+			return fn, nil
+		}
+		consumed := min(len(args), len(fn.Args))
+		for i, arg := range args[:consumed] {
+			if err := c.Unify(fn.Args[i], arg); err != nil {
+				return nil, err
+			}
+		}
+		if consumed < len(fn.Args) {
+			return Func(fn.Args[consumed:], fn.Result), nil
+		}
+		operator, args, initialCall = fn.Result, args[consumed:], false
+	}
+}
+
+// applyAll applies the substitution to a list of types.
+func (c *TypeChecker) applyAll(types []Type) []Type {
+	return gfn.Map(types, c.Subst.Apply)
+}
+
+// The procedures in a letrec are in scope in each other's bodies so their
+// types are declared before any of the bodies are checked - in CHECKED they
+// must be fully annotated.
+func (c *TypeChecker) TypeOfLetRec(e *LetRecExpr, tenv *TypeEnv) (Type, error) {
+	procs, err := c.LetRecTypes(e, tenv)
+	if err != nil {
+		return nil, err
+	}
+	return c.TypeOf(e.Body, tenv.Extend(procs))
+}
+
+// LetRecTypes checks the procedures of a letrec returning their types.
+func (c *TypeChecker) LetRecTypes(e *LetRecExpr, tenv *TypeEnv) (map[string]Type, error) {
+	names := epl.SortedKeys(e.Procs)
+	fns := make([]*FuncType, len(names))
+	out := map[string]Type{}
+	for i, name := range names {
+		proc := e.Procs[name]
+		fn, err := c.procType(proc)
+		if err != nil {
+			return nil, err
+		}
+		if fn.Result == nil {
+			if fn.Result, err = c.Self.Unannotated("result of " + procName(proc)); err != nil {
+				return nil, err
+			}
+		}
+		fns[i], out[name] = fn, fn
+	}
+	procenv := tenv.Extend(out)
+	for i, name := range names {
+		if err := c.checkProcBody(e.Procs[name], fns[i], procenv); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,150 @@
+package chapter7
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TypeTestCase struct {
+	Name     string
+	Expr     Expr
+	Expected any // A Type or an error
+}
+
+func RunTypeTest(t *testing.T, tc *TypeTestCase) {
+	t.Helper()
+	found, err := NewTypeChecker().Check(tc.Expr)
+	if expectedErr, ok := tc.Expected.(error); ok {
+		assert.ErrorIs(t, err, expectedErr, "Test %s", tc.Name)
+		return
+	}
+	require.NoError(t, err, "Test %s Failed - Unexpected error", tc.Name)
+	assert.True(t, TypeEq(tc.Expected.(Type), found), "Test %s: expected %s, found %s", tc.Name, tc.Expected, found)
+}
+
+var intToInt = Func([]Type{Int}, Int)
+
+// Ported from basic_checks and checked in tests/chapter7/cases.py, with the
+// annotations CHECKED needs
+func TestBasicChecks(t *testing.T) {
+	cases := []TypeTestCase{
+		// if 3 then 88 else 99
+		{Name: "non_bool_test", Expected: TypeError{Expected: Bool, Found: Int}, Expr: If(3, 88, 99)},
+		// proc (x : (int -> int)) (x 3)
+		{Name: "proc_val_rator", Expected: Func([]Type{intToInt}, Int),
+			Expr: TypedProc([]string{"x"}, []Type{intToInt}, nil, Call("x", 3))},
+		// proc (x : int) (3 x)
+		{Name: "non_proc_val_rator1", Expected: TypeError{Expected: Func([]Type{Int}, nil), Found: Int},
+			Expr: TypedProc([]string{"x"}, []Type{Int}, nil, Call(3, "x"))},
+		// let x = 4 in (x 3)
+		{Name: "non_proc_val_rator2", Expected: TypeError{Expected: Func([]Type{Int}, nil), Found: Int},
+			Expr: Let(ExprDict("x", Lit(4)), Call("x", 3))},
+		// (proc (x : (int -> int)) (x 3) 4)
+		{Name: "non_proc_val_rator3", Expected: TypeError{Expected: intToInt, Found: Int},
+			Expr: Call(TypedProc([]string{"x"}, []Type{intToInt}, nil, Call("x", 3)), 4)},
+		// let x = isz(0) in -(3, x)
+		{Name: "non_int_diff_arg", Expected: TypeError{Expected: Int, Found: Bool},
+			Expr: Let(ExprDict("x", IsZero(0)), Op("-", 3, "x"))},
+		// (proc (x : int) -(3, x) isz(0))
+		{Name: "non_int_diff_arg2", Expected: TypeError{Expected: Int, Found: Bool},
+			Expr: Call(TypedProc([]string{"x"}, []Type{Int}, nil, Op("-", 3, "x")), IsZero(0))},
+		// let f = 3 in proc (x : int) (f x)
+		{Name: "non_proc_val_rator4", Expected: TypeError{Expected: Func([]Type{Int}, nil), Found: Int},
+			Expr: Let(ExprDict("f", Lit(3)), TypedProc([]string{"x"}, []Type{Int}, nil, Call("f", "x")))},
+		// (proc (f : (int -> int)) proc (x : int) (f x) 3)
+		{Name: "non_proc_val_rator5", Expected: TypeError{Expected: intToInt, Found: Int},
+			Expr: Call(TypedProc([]string{"f"}, []Type{intToInt}, nil,
+				TypedProc([]string{"x"}, []Type{Int}, nil, Call("f", "x"))), 3)},
+		{Name: "3", Expected: Int, Expr: Lit(3)},
+		{Name: "diff", Expected: Int, Expr: Op("-", 10, 20)},
+
+		// proc (x : int) -> int -(x, 11)
+		{Name: "proc", Expected: intToInt, Expr: TypedProc([]string{"x"}, []Type{Int}, Int, Op("-", "x", 11))},
+		// proc (x : int) -(x, 11) - the result type comes from the body
+		{Name: "proc2", Expected: intToInt, Expr: TypedProc([]string{"x"}, []Type{Int}, nil, Op("-", "x", 11))},
+		// proc (x : int) -> bool -(x, 11)
+		{Name: "bad result", Expected: TypeError{Expected: Bool, Found: Int},
+			Expr: TypedProc([]string{"x"}, []Type{Int}, Bool, Op("-", "x", 11))},
+		// proc (x) -(x, 11)
+		{Name: "unannotated", Expected: MissingAnnotationError{Context: "parameter 'x' of proc"},
+			Expr: Proc([]string{"x"}, Op("-", "x", 11))},
+	}
+	for _, tc := range cases {
+		RunTypeTest(t, &tc)
+	}
+}
+
+func TestCheckedLetRec(t *testing.T) {
+	// letrec
+	//   even (x : int) -> bool = if isz(x) then true else (odd -(x, 1))
+	//   odd (x : int) -> bool = if isz(x) then false else (even -(x, 1))
+	// in (odd 13)
+	evenodd := func(oddResult Type) Expr {
+		return LetRec(ProcMap(
+			"even", TypedProc([]string{"x"}, []Type{Int}, Bool, If(IsZero("x"), Lit(true), Call("odd", Op("-", "x", 1)))),
+			"odd", TypedProc([]string{"x"}, []Type{Int}, oddResult, If(IsZero("x"), Lit(false), Call("even", Op("-", "x", 1))))),
+			Call("odd", 13))
+	}
+	RunTypeTest(t, &TypeTestCase{Name: "evenodd", Expected: Bool, Expr: evenodd(Bool)})
+	RunTypeTest(t, &TypeTestCase{Name: "wrong result", Expected: TypeError{Expected: Bool, Found: Int}, Expr: evenodd(Int)})
+	RunTypeTest(t, &TypeTestCase{Name: "no result", Expected: MissingAnnotationError{Context: "result of proc odd"}, Expr: evenodd(nil)})
+}
+
+func TestCheckedCalls(t *testing.T) {
+	add := TypedProc([]string{"x", "y"}, []Type{Int, Int}, nil, Op("+", "x", "y"))
+	cases := []TypeTestCase{
+		{Name: "full", Expected: Int, Expr: Call(add, 1, 2)},
+		{Name: "partial", Expected: intToInt, Expr: Call(add, 1)},
+		{Name: "curried", Expected: Int,
+			Expr: Call(TypedProc([]string{"x"}, []Type{Int}, nil, TypedProc([]string{"y"}, []Type{Int}, nil, "y")), 1, 2)},
+		{Name: "thunk", Expected: Int,
+			Expr: Call(TypedProc(nil, nil, nil, TypedProc(nil, nil, nil, 5)))},
+		{Name: "no args", Expected: ArityError{Context: "Procedure of type (int * int -> int)", Expected: 2, Found: 0},
+			Expr: Call(add)},
+		{Name: "too many", Expected: TypeError{Expected: Func([]Type{Int}, nil), Found: Int},
+			Expr: Call(add, 1, 2, 3)},
+		{Name: "unbound", Expected: UnboundVariableError{Name: "f"}, Expr: Call("f", 1)},
+	}
+	for _, tc := range cases {
+		RunTypeTest(t, &tc)
+	}
+}
+
+func TestCheckedOperators(t *testing.T) {
+	float := Lit(1.5)
+	cases := []TypeTestCase{
+		{Name: "compare", Expected: Bool, Expr: Op("<", 1, 2)},
+		{Name: "strings", Expected: Int, Expr: Op("strlen", Op("concat", Lit("a"), Lit("b")))},
+		{Name: "string arg", Expected: TypeError{Expected: String, Found: Int}, Expr: Op("strlen", 3)},
+		{Name: "list", Expected: ListOf(Int), Expr: Op("cons", 1, List(2, 3))},
+		{Name: "car", Expected: Int, Expr: Op("car", Op("list", 1, 2))},
+		{Name: "nested", Expected: ListOf(ListOf(Bool)), Expr: List(List(true), List(false))},
+		{Name: "mixed list", Expected: TypeError{Expected: Int, Found: Bool}, Expr: List(1, true)},
+		{Name: "empty list", Expected: MissingAnnotationError{Context: "an empty list"}, Expr: List()},
+		{Name: "not a list", Expected: TypeError{Expected: ListOf(nil), Found: Int}, Expr: Op("cdr", 1)},
+		{Name: "tuple", Expected: TupleOf(Int, String), Expr: Tuple(Lit(1), Lit("x"))},
+		{Name: "arity", Expected: ArityError{Context: "'-' operator", Expected: 2, Found: 3}, Expr: Op("-", 1, 2, 3)},
+		{Name: "unknown", Expected: UnknownOperatorError{Op: "/"}, Expr: Op("/", 1, 2)},
+		{Name: "float", Expected: UnsupportedExprError{Expr: float}, Expr: float},
+		{Name: "if branches", Expected: TypeError{Expected: Int, Found: Bool}, Expr: If(true, 1, false)},
+	}
+	for _, tc := range cases {
+		RunTypeTest(t, &tc)
+	}
+}
+
+func TestTypeStrings(t *testing.T) {
+	assert.Equal(t, "(int * bool -> (-> int))", Func([]Type{Int, Bool}, Func(nil, Int)).String())
+	assert.Equal(t, "(int -> ?)", Func([]Type{Int}, nil).String())
+	assert.Equal(t, "(int, list (list string))", TupleOf(Int, ListOf(ListOf(String))).String())
+	assert.True(t, Func([]Type{Int}, Bool).Eq(Func([]Type{Int}, Bool)))
+	assert.False(t, Func([]Type{Int}, Bool).Eq(Func([]Type{Bool}, Bool)))
+	assert.False(t, ListOf(Int).Eq(Tagged("ref", Int)))
+	assert.EqualError(t, TypeError{Expected: Int, Found: Bool}, "expected type int, found bool")
+
+	proc := TypedProc([]string{"x", "y"}, []Type{Int, nil}, Bool, "x")
+	assert.Equal(t, "<Proc (x : int, y) -> bool { <Var(x)> }", proc.Repr())
+	assert.False(t, proc.Eq(Proc([]string{"x", "y"}, Var("x"))))
+}
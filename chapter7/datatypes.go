@@ -0,0 +1,442 @@
+package chapter7
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+	gfn "github.com/panyam/goutils/fn"
+)
+
+// VariantDecl is one of the constructors of a datatype with the types of its
+// fields, eg 'Node (tree, tree)'.
+type VariantDecl struct {
+	Name   string
+	Fields []Type
+}
+
+func Variant(name string, fields ...Type) *VariantDecl {
+	return &VariantDecl{Name: name, Fields: fields}
+}
+
+func (v *VariantDecl) String() string {
+	return fmt.Sprintf("%s (%s)", v.Name, strings.Join(gfn.Map(v.Fields, typeString), ", "))
+}
+
+func (v *VariantDecl) Eq(another *VariantDecl) bool {
+	return v.Name == another.Name && typeListEq(v.Fields, another.Fields)
+}
+
+// DatatypeDecl is 'define-datatype tree = Leaf (int) | Node (tree, tree)'.
+// A datatype may have type parameters, as in
+// 'define-datatype option (a) = None () | Some (a)', which its fields refer
+// to as DataTypes with the parameter's name.
+type DatatypeDecl struct {
+	Name     string
+	Params   []string
+	Variants []*VariantDecl
+}
+
+func Datatype(name string, params []string, variants ...*VariantDecl) *DatatypeDecl {
+	return &DatatypeDecl{Name: name, Params: params, Variants: variants}
+}
+
+func (d *DatatypeDecl) String() string {
+	name := d.Name
+	if len(d.Params) > 0 {
+		name += " (" + strings.Join(d.Params, ", ") + ")"
+	}
+	return fmt.Sprintf("define-datatype %s = %s", name, strings.Join(gfn.Map(d.Variants, (*VariantDecl).String), " | "))
+}
+
+func (d *DatatypeDecl) Eq(another *DatatypeDecl) bool {
+	return d.Name == another.Name && slices.Equal(d.Params, another.Params) &&
+		slices.EqualFunc(d.Variants, another.Variants, (*VariantDecl).Eq)
+}
+
+// DataProgramExpr is a program declaring datatypes followed by the
+// expression that uses them.  The constructors of the datatypes are bound as
+// procedures taking the variant's fields, or for a variant without fields to
+// its value, so 'Node' is a procedure and 'None' a value.
+type DataProgramExpr struct {
+	Types []*DatatypeDecl
+	Body  Expr
+}
+
+func DataProgram(types []*DatatypeDecl, body any) *DataProgramExpr {
+	return &DataProgramExpr{Types: types, Body: AnyToExpr(body)}
+}
+
+func (e *DataProgramExpr) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "DataProgram:")) {
+			return
+		}
+		for _, d := range e.Types {
+			if !yield(epl.Printablef(1, "%s", d)) {
+				return
+			}
+		}
+		bP := e.Body.Printable()
+		bP.IndentLevel += 1
+		if !yield(bP) {
+			return
+		}
+	})
+}
+
+func (e *DataProgramExpr) Repr() string {
+	return fmt.Sprintf("<DataProgram [%s] %s>", strings.Join(gfn.Map(e.Types, (*DatatypeDecl).String), "; "), e.Body.Repr())
+}
+
+func (e *DataProgramExpr) Eq(another *DataProgramExpr) bool {
+	return slices.EqualFunc(e.Types, another.Types, (*DatatypeDecl).Eq) && ExprEq(e.Body, another.Body)
+}
+
+// MatchArm is 'Pattern => Body' in a match expression.
+type MatchArm struct {
+	Pattern Pattern
+	Body    Expr
+}
+
+func MatchCase(pattern Pattern, body any) *MatchArm {
+	return &MatchArm{Pattern: pattern, Body: AnyToExpr(body)}
+}
+
+func (a *MatchArm) Repr() string {
+	return fmt.Sprintf("%s => %s", a.Pattern, a.Body.Repr())
+}
+
+func (a *MatchArm) Eq(another *MatchArm) bool {
+	return a.Pattern.String() == another.Pattern.String() && ExprEq(a.Body, another.Body)
+}
+
+// MatchExpr is 'match e with Pattern => ... | Pattern => ... end'.  It
+// evaluates the body of the first arm whose pattern matches the value of e.
+type MatchExpr struct {
+	Expr Expr
+	Arms []*MatchArm
+}
+
+func Match(expr any, arms ...*MatchArm) *MatchExpr {
+	return &MatchExpr{Expr: AnyToExpr(expr), Arms: arms}
+}
+
+func (e *MatchExpr) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "Match:")) {
+			return
+		}
+		eP := e.Expr.Printable()
+		eP.IndentLevel += 1
+		if !yield(eP) {
+			return
+		}
+		for _, arm := range e.Arms {
+			if !yield(epl.Printablef(1, "%s =>", arm.Pattern)) {
+				return
+			}
+			bP := arm.Body.Printable()
+			bP.IndentLevel += 2
+			if !yield(bP) {
+				return
+			}
+		}
+	})
+}
+
+func (e *MatchExpr) Repr() string {
+	return fmt.Sprintf("<Match %s [%s]>", e.Expr.Repr(), strings.Join(gfn.Map(e.Arms, (*MatchArm).Repr), ", "))
+}
+
+func (e *MatchExpr) Eq(another *MatchExpr) bool {
+	return ExprEq(e.Expr, another.Expr) && slices.EqualFunc(e.Arms, another.Arms, (*MatchArm).Eq)
+}
+
+// DataVal is a value made by one of the constructors of a datatype.
+type DataVal struct {
+	Datatype    string
+	Constructor string
+	Fields      []Value
+}
+
+func (v *DataVal) String() string {
+	if len(v.Fields) == 0 {
+		return v.Constructor
+	}
+	return v.Constructor + "(" + strings.Join(gfn.Map(v.Fields, Value.String), ", ") + ")"
+}
+
+func (v *DataVal) Repr() string {
+	return fmt.Sprintf("Data(%s.%s, %s)", v.Datatype, v.Constructor, strings.Join(gfn.Map(v.Fields, Value.Repr), ", "))
+}
+
+func (v *DataVal) Eq(another Value) bool {
+	a, ok := another.(*DataVal)
+	return ok && v.Datatype == a.Datatype && v.Constructor == a.Constructor &&
+		slices.EqualFunc(v.Fields, a.Fields, Value.Eq)
+}
+
+// DataLangEval extends VariantLangEval with datatypes and match.
+type DataLangEval struct {
+	VariantLangEval
+}
+
+// NewDataLangEval creates a new evaluator for the datatypes language.
+func NewDataLangEval() *DataLangEval {
+	out := &DataLangEval{}
+	// CRITICAL: Set the Self pointer for the embedded BaseEval
+	out.BaseEval.Self = out
+	return out
+}
+
+// LocalEval handles datatype programs and match or delegates.
+func (l *DataLangEval) LocalEval(expr Expr, env *epl.Env[any]) (any, error) {
+	switch n := expr.(type) {
+	case *DataProgramExpr:
+		return l.ValueOfDataProgram(n, env)
+	case *MatchExpr:
+		return l.ValueOfMatch(n, env)
+	default:
+		return l.VariantLangEval.LocalEval(expr, env)
+	}
+}
+
+// ValueOfDataProgram binds the constructors of the datatypes and evaluates
+// the body.
+func (l *DataLangEval) ValueOfDataProgram(e *DataProgramExpr, env *epl.Env[any]) (any, error) {
+	newenv := env.Push()
+	for _, d := range e.Types {
+		for _, v := range d.Variants {
+			newenv.Set(v.Name, Constructor(d.Name, v))
+		}
+	}
+	return l.Eval(e.Body, newenv)
+}
+
+// Constructor returns the value a variant's constructor is bound to - a
+// procedure taking its fields or, if it has none, its only value.
+func Constructor(datatype string, v *VariantDecl) Value {
+	if len(v.Fields) == 0 {
+		return &DataVal{Datatype: datatype, Constructor: v.Name}
+	}
+	return &chapter3.NativeProc{Name: v.Name, Arity: len(v.Fields), Fn: func(args []Value) (Value, error) {
+		return &DataVal{Datatype: datatype, Constructor: v.Name, Fields: args}, nil
+	}}
+}
+
+func (l *DataLangEval) ValueOfMatch(e *MatchExpr, env *epl.Env[any]) (any, error) {
+	val, err := l.Eval(e.Expr, env)
+	if err != nil {
+		return nil, err
+	}
+	v, err := chapter3.AsValue("match", val)
+	if err != nil {
+		return nil, err
+	}
+	for _, arm := range e.Arms {
+		bindings := map[string]any{}
+		if MatchPattern(arm.Pattern, v, bindings) {
+			return l.Eval(arm.Body, env.Extend(bindings))
+		}
+	}
+	return nil, MatchFailureError{Value: v}
+}
+
+// constructorInfo is what the checker knows about a constructor.
+type constructorInfo struct {
+	Datatype *DatatypeDecl
+	Variant  *VariantDecl
+}
+
+// The datatypes are declared before the constructors are bound so they can
+// refer to each other.  Each constructor's type is a Scheme over the
+// parameters of its datatype, eg 'forall a. (a -> option a)' for 'Some'.
+func (c *TypeChecker) TypeOfDataProgram(e *DataProgramExpr, tenv *TypeEnv) (Type, error) {
+	for _, d := range e.Types {
+		c.datatypes[d.Name] = d
+		for _, v := range d.Variants {
+			if _, exists := c.constructors[v.Name]; exists {
+				return nil, DuplicateConstructorError{Name: v.Name}
+			}
+			c.constructors[v.Name] = &constructorInfo{Datatype: d, Variant: v}
+		}
+	}
+	types := map[string]Type{}
+	for _, d := range e.Types {
+		for _, v := range d.Variants {
+			fields, result, err := c.instantiateVariant(c.constructors[v.Name])
+			if err != nil {
+				return nil, err
+			}
+			var t Type = result
+			if len(fields) > 0 {
+				t = Func(fields, result)
+			}
+			if len(result.Args) > 0 {
+				t = &Scheme{Vars: gfn.Map(result.Args, func(a Type) *TypeVar { return a.(*TypeVar) }), Type: t}
+			}
+			types[v.Name] = t
+		}
+	}
+	return c.TypeOf(e.Body, tenv.Extend(types))
+}
+
+// instantiateVariant returns the types of a variant's fields and of its
+// datatype with fresh type variables for the datatype's parameters.
+func (c *TypeChecker) instantiateVariant(info *constructorInfo) ([]Type, *DataType, error) {
+	params := map[string]Type{}
+	result := Data(info.Datatype.Name)
+	for _, p := range info.Datatype.Params {
+		params[p] = c.Fresh()
+		result.Args = append(result.Args, params[p])
+	}
+	fields := make([]Type, len(info.Variant.Fields))
+	for i, f := range info.Variant.Fields {
+		var err error
+		if fields[i], err = c.declaredType(f, params); err != nil {
+			return nil, nil, err
+		}
+	}
+	return fields, result, nil
+}
+
+// declaredType checks the datatypes in the type of a field, replacing the
+// parameters of the field's datatype with their types in params.
+func (c *TypeChecker) declaredType(t Type, params map[string]Type) (Type, error) {
+	switch t := t.(type) {
+	case *DataType:
+		if p, ok := params[t.Name]; ok && len(t.Args) == 0 {
+			return p, nil
+		}
+		d, ok := c.datatypes[t.Name]
+		if !ok {
+			return nil, UnknownDatatypeError{Name: t.Name}
+		}
+		if len(t.Args) != len(d.Params) {
+			return nil, ArityError{Context: "datatype " + t.Name, Expected: len(d.Params), Found: len(t.Args)}
+		}
+		args, err := c.declaredTypes(t.Args, params)
+		return Data(t.Name, args...), err
+	case *FuncType:
+		args, err := c.declaredTypes(t.Args, params)
+		if err != nil {
+			return nil, err
+		}
+		result, err := c.declaredType(t.Result, params)
+		return Func(args, result), err
+	case *TupleType:
+		children, err := c.declaredTypes(t.Children, params)
+		return TupleOf(children...), err
+	case *TaggedType:
+		elem, err := c.declaredType(t.Type, params)
+		return Tagged(t.Name, elem), err
+	case rowType:
+		labels, rest := t.row()
+		declared := map[string]Type{}
+		for label, lt := range labels {
+			var err error
+			if declared[label], err = c.declaredType(lt, params); err != nil {
+				return nil, err
+			}
+		}
+		return t.withRow(declared, rest), nil
+	}
+	return t, nil
+}
+
+func (c *TypeChecker) declaredTypes(types []Type, params map[string]Type) ([]Type, error) {
+	out := make([]Type, len(types))
+	for i, t := range types {
+		var err error
+		if out[i], err = c.declaredType(t, params); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// The value matched must have the type of every arm's pattern and the arms'
+// bodies, with the variables of their patterns bound, must all have the same
+// type.  The arms are then checked for redundant arms and values no arm
+// matches.
+func (c *TypeChecker) TypeOfMatch(e *MatchExpr, tenv *TypeEnv) (Type, error) {
+	t, err := c.TypeOf(e.Expr, tenv)
+	if err != nil {
+		return nil, err
+	}
+	var result Type
+	for _, arm := range e.Arms {
+		bindings := map[string]Type{}
+		pt, err := c.TypeOfPattern(arm.Pattern, bindings)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Unify(pt, t); err != nil {
+			return nil, err
+		}
+		body, err := c.TypeOf(arm.Body, tenv.Extend(bindings))
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			result = body
+		} else if err := c.Unify(result, body); err != nil {
+			return nil, err
+		}
+	}
+	checker := &matchChecker{constructors: c.constructors}
+	if err := checker.checkArms(e.Arms); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		// No arms so it never returns
+		return c.Fresh(), nil
+	}
+	return result, nil
+}
+
+// TypeOfPattern returns the type of the values a pattern matches, adding
+// the types of its variables to bindings.
+func (c *TypeChecker) TypeOfPattern(p Pattern, bindings map[string]Type) (Type, error) {
+	switch p := p.(type) {
+	case *VarPattern:
+		t := c.Fresh()
+		bindings[p.Name] = t
+		return t, nil
+	case *ConstructorPattern:
+		info, ok := c.constructors[p.Name]
+		if !ok {
+			return nil, UnknownConstructorError{Name: p.Name}
+		}
+		fields, result, err := c.instantiateVariant(info)
+		if err != nil {
+			return nil, err
+		}
+		if len(p.Args) != len(fields) {
+			return nil, ArityError{Context: "constructor " + p.Name, Expected: len(fields), Found: len(p.Args)}
+		}
+		for i, arg := range p.Args {
+			at, err := c.TypeOfPattern(arg, bindings)
+			if err != nil {
+				return nil, err
+			}
+			if err := c.Unify(fields[i], at); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case *TuplePattern:
+		children := make([]Type, len(p.Children))
+		for i, child := range p.Children {
+			var err error
+			if children[i], err = c.TypeOfPattern(child, bindings); err != nil {
+				return nil, err
+			}
+		}
+		return TupleOf(children...), nil
+	}
+	return c.Fresh(), nil
+}
@@ -0,0 +1,145 @@
+package chapter7
+
+import (
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	tree       = Data("tree")
+	treeDecl   = Datatype("tree", nil, Variant("Leaf", Int), Variant("Node", tree, tree))
+	optionDecl = Datatype("option", []string{"a"}, Variant("None"), Variant("Some", Data("a")))
+)
+
+func withTypes(body any) *DataProgramExpr {
+	return DataProgram([]*DatatypeDecl{treeDecl, optionDecl}, body)
+}
+
+// Node(Leaf(1), Node(Leaf(2), Leaf(3)))
+var someTree = Call("Node", Call("Leaf", 1), Call("Node", Call("Leaf", 2), Call("Leaf", 3)))
+
+// sumTree is
+//
+//	letrec sum (t : tree) : int = match t with
+//	    Leaf (n) => n
+//	  | Node (l, r) => +((sum l), (sum r))
+//	  end
+//	in (sum someTree)
+func sumTree(params ...Type) Expr {
+	var result Type
+	if len(params) > 0 {
+		result = Int
+	}
+	return LetRec(ProcMap("sum", TypedProc([]string{"t"}, params, result, Match("t",
+		MatchCase(PatCons("Leaf", PatVar("n")), "n"),
+		MatchCase(PatCons("Node", PatVar("l"), PatVar("r")), Op("+", Call("sum", "l"), Call("sum", "r")))))),
+		Call("sum", someTree))
+}
+
+func TestCheckedDatatypes(t *testing.T) {
+	leafOrNode := func(arms ...*MatchArm) Expr { return withTypes(Match(Call("Leaf", 1), arms...)) }
+	leaf := MatchCase(PatCons("Leaf", PatVar("n")), "n")
+	cases := []TypeTestCase{
+		{Name: "constructor", Expected: Func([]Type{tree, tree}, tree), Expr: withTypes(Var("Node"))},
+		{Name: "sum", Expected: Int, Expr: withTypes(sumTree(tree))},
+		{Name: "polymorphic", Expected: TupleOf(Data("option", Int), Data("option", Bool)),
+			Expr: withTypes(Tuple(Call("Some", 1), Call("Some", true)))},
+		{Name: "field type", Expected: TypeError{Expected: Int, Found: Bool}, Expr: withTypes(Call("Leaf", true))},
+		{Name: "missing", Expected: NonExhaustiveMatchError{Missing: "Node (_, _)"}, Expr: leafOrNode(leaf)},
+		{Name: "nested missing", Expected: NonExhaustiveMatchError{Missing: "Node (Node (_, _), _)"},
+			Expr: leafOrNode(leaf, MatchCase(PatCons("Node", PatCons("Leaf", Wildcard()), Wildcard()), 0))},
+		{Name: "wildcard", Expected: Int, Expr: leafOrNode(leaf, MatchCase(Wildcard(), 0))},
+		{Name: "redundant", Expected: RedundantArmError{Pattern: "Leaf (_)"},
+			Expr: leafOrNode(MatchCase(PatVar("x"), 0), MatchCase(PatCons("Leaf", Wildcard()), 1))},
+		{Name: "pattern type", Expected: TypeError{Expected: tree, Found: Int},
+			Expr: withTypes(Match(1, MatchCase(PatCons("Leaf", Wildcard()), 0)))},
+		{Name: "arm types", Expected: TypeError{Expected: Int, Found: Bool},
+			Expr: leafOrNode(leaf, MatchCase(Wildcard(), true))},
+		{Name: "unknown constructor", Expected: UnknownConstructorError{Name: "Branch"},
+			Expr: leafOrNode(MatchCase(PatCons("Branch"), 0))},
+		{Name: "constructor arity", Expected: ArityError{Context: "constructor Leaf", Expected: 1, Found: 0},
+			Expr: leafOrNode(MatchCase(PatCons("Leaf"), 0))},
+		{Name: "unknown datatype", Expected: UnknownDatatypeError{Name: "shape"},
+			Expr: DataProgram([]*DatatypeDecl{Datatype("t", nil, Variant("A", Data("shape")))}, 0)},
+		{Name: "datatype arity", Expected: ArityError{Context: "datatype option", Expected: 1, Found: 0},
+			Expr: DataProgram([]*DatatypeDecl{optionDecl, Datatype("t", nil, Variant("A", Data("option")))}, 0)},
+		{Name: "duplicate constructor", Expected: DuplicateConstructorError{Name: "Leaf"},
+			Expr: DataProgram([]*DatatypeDecl{treeDecl, Datatype("t", nil, Variant("Leaf"))}, 0)},
+	}
+	for _, tc := range cases {
+		RunTypeTest(t, &tc)
+	}
+}
+
+func TestInferDatatypes(t *testing.T) {
+	// proc (o, d) match o with None => d | Some (x) => x end
+	unwrap := Proc([]string{"o", "d"}, Match("o", MatchCase(PatCons("None"), "d"), MatchCase(PatCons("Some", PatVar("x")), "x")))
+	RunInferTest(t, "none", withTypes(Var("None")), "option t3")
+	RunInferTest(t, "unwrap", withTypes(unwrap), "(option t5 * t5 -> t5)")
+	RunInferTest(t, "sum", withTypes(sumTree()), "int")
+	RunPolyTest(t, "poly unwrap", withTypes(Let(ExprDict("unwrap", unwrap),
+		Tuple(Call("unwrap", Call("Some", 1), 0), Call("unwrap", "None", true)))), "(int, bool)")
+	RunPolyTest(t, "poly constructor", withTypes(Var("Some")), "forall a. (a -> option a)")
+
+	// Tuples of patterns - match p with (None, _) => 0 | (Some (a), Some (b)) => +(a, b) end
+	pair := Proc([]string{"p"}, Match("p",
+		MatchCase(PatTuple(PatCons("None"), Wildcard()), 0),
+		MatchCase(PatTuple(PatCons("Some", PatVar("a")), PatCons("Some", PatVar("b"))), Op("+", "a", "b"))))
+	RunInferTest(t, "pairs", withTypes(pair), NonExhaustiveMatchError{Missing: "(Some (_), None)"})
+
+	assert.Equal(t, "option (list int)", Data("option", ListOf(Int)).String())
+	assert.Equal(t, "option (option int)", Data("option", Data("option", Int)).String())
+	assert.Equal(t, "option tree", Data("option", tree).String())
+	assert.Equal(t, "define-datatype option (a) = None () | Some (a)", optionDecl.String())
+}
+
+func RunDataTest(t *testing.T, tc *chapter3.TestCase) {
+	t.Helper()
+	value, err := chapter3.SetOpFuncs(NewDataLangEval()).Eval(tc.Expr, epl.NewEnv[any](nil))
+	if expectedErr, ok := tc.Expected.(error); ok {
+		assert.ErrorIs(t, err, expectedErr, "Test %s", tc.Name)
+		return
+	}
+	require.NoError(t, err, "Test %s Failed - Unexpected error", tc.Name)
+	chapter3.AssertValue(t, tc.Name, tc.Expected, value)
+}
+
+func TestEvalDatatypes(t *testing.T) {
+	leaf := func(n int) *DataVal {
+		return &DataVal{Datatype: "tree", Constructor: "Leaf", Fields: []Value{IntVal(n)}}
+	}
+	cases := []chapter3.TestCase{
+		{Name: "sum", Expected: 6, Expr: withTypes(sumTree())},
+		{Name: "constructed", Expected: &DataVal{Datatype: "tree", Constructor: "Node", Fields: []Value{leaf(1), leaf(2)}},
+			Expr: withTypes(Call("Node", Call("Leaf", 1), Call("Leaf", 2)))},
+		{Name: "nullary", Expected: &DataVal{Datatype: "option", Constructor: "None"}, Expr: withTypes(Var("None"))},
+		{Name: "nested", Expected: 2, Expr: withTypes(Match(someTree,
+			MatchCase(PatCons("Node", PatCons("Leaf", Wildcard()), PatCons("Node", PatCons("Leaf", PatVar("x")), Wildcard())), "x"),
+			MatchCase(Wildcard(), 0)))},
+		{Name: "tuple", Expected: 3, Expr: withTypes(Match(Tuple(Call("Some", 1), Call("Some", 2)),
+			MatchCase(PatTuple(PatCons("Some", PatVar("a")), PatCons("Some", PatVar("b"))), Op("+", "a", "b"))))},
+		{Name: "no match", Expected: MatchFailureError{Value: leaf(1)}, Expr: withTypes(Match(Call("Leaf", 1),
+			MatchCase(PatCons("Node", Wildcard(), Wildcard()), 0)))},
+	}
+	for _, tc := range cases {
+		RunDataTest(t, &tc)
+	}
+
+	// A failed match is a runtime error so try can catch it
+	e := NewDataLangEval()
+	e.CatchRuntimeErrors = true
+	value, err := chapter3.SetOpFuncs(e).Eval(withTypes(Try(Match(Call("Leaf", 1),
+		MatchCase(PatCons("Node", Wildcard(), Wildcard()), 0)), "err", -1)), epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	chapter3.AssertValue(t, "caught", -1, value)
+
+	assert.Equal(t, "Node(Leaf(1), Leaf(2))", (&DataVal{Constructor: "Node", Fields: []Value{leaf(1), leaf(2)}}).String())
+	assert.Equal(t, "Node (Leaf (_), x)", PatCons("Node", PatCons("Leaf", Wildcard()), PatVar("x")).String())
+	assert.Equal(t, []string{"a", "b"}, PatternVars(PatTuple(PatCons("Some", PatVar("a")), Wildcard(), PatVar("b"))))
+	assert.True(t, ExprEq(withTypes(sumTree()), withTypes(sumTree())))
+	assert.False(t, ExprEq(withTypes(Match("x", MatchCase(PatVar("y"), 0))), withTypes(Match("x", MatchCase(Wildcard(), 0)))))
+}
@@ -0,0 +1,203 @@
+package chapter7
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/panyam/eplgo/chapter3"
+)
+
+// TypeError is returned when an expression does not have the type its
+// context requires, like ensure_type in utils.py.  Expected may be a partial
+// type - eg '(int -> ?)' when a procedure was needed but its result type is
+// not known.
+type TypeError struct {
+	Expected Type
+	Found    Type
+}
+
+func (e TypeError) Error() string {
+	return fmt.Sprintf("expected type %s, found %s", typeString(e.Expected), typeString(e.Found))
+}
+
+// Is compares TypeErrors by their types so errors.Is works with function and
+// tuple types which are pointers.
+func (e TypeError) Is(target error) bool {
+	t, ok := target.(TypeError)
+	return ok && TypeEq(e.Expected, t.Expected) && TypeEq(e.Found, t.Found)
+}
+
+// EnsureType returns a TypeError if found is not the expected type.
+func EnsureType(expected, found Type) error {
+	if !TypeEq(expected, found) {
+		return TypeError{Expected: expected, Found: found}
+	}
+	return nil
+}
+
+// TupleIndexError is returned for indexing a value that is not known to be
+// a tuple with an item at the index.
+type TupleIndexError struct {
+	Index int
+	Type  Type
+}
+
+func (e TupleIndexError) Error() string {
+	return fmt.Sprintf("cannot take item %d of %s", e.Index, typeString(e.Type))
+}
+
+func (e TupleIndexError) Is(target error) bool {
+	t, ok := target.(TupleIndexError)
+	return ok && e.Index == t.Index && TypeEq(e.Type, t.Type)
+}
+
+// FieldError is returned for taking a field of a record type that does not
+// have it.
+type FieldError struct {
+	Field string
+	Type  Type
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s has no field '%s'", typeString(e.Type), e.Field)
+}
+
+func (e FieldError) Is(target error) bool {
+	t, ok := target.(FieldError)
+	return ok && e.Field == t.Field && TypeEq(e.Type, t.Type)
+}
+
+// MissingAnnotationError is returned for an expression whose type cannot be
+// checked without an annotation, eg an unannotated procedure parameter.
+type MissingAnnotationError struct {
+	Context string
+}
+
+func (e MissingAnnotationError) Error() string {
+	return fmt.Sprintf("%s needs a type annotation", e.Context)
+}
+
+// OccursError is returned when unification would make a type variable
+// contain itself, eg for 'proc (x) (x x)'.
+type OccursError struct {
+	Var  *TypeVar
+	Type Type
+}
+
+func (e OccursError) Error() string {
+	return fmt.Sprintf("type variable %s occurs in %s", e.Var, e.Type)
+}
+
+// PolymorphicMutationError is returned for an assignment to, or a 'ref' of,
+// a variable with a polymorphic type.
+type PolymorphicMutationError struct {
+	Name string
+}
+
+func (e PolymorphicMutationError) Error() string {
+	return fmt.Sprintf("'%s' has a polymorphic type and cannot be assigned to", e.Name)
+}
+
+// MissingCasesError is returned for a cases expression without arms for all
+// the options of the union it dispatches on.
+type MissingCasesError struct {
+	Tags []string
+}
+
+func (e MissingCasesError) Error() string {
+	return fmt.Sprintf("cases does not handle %s", strings.Join(e.Tags, ", "))
+}
+
+func (e MissingCasesError) Is(target error) bool {
+	t, ok := target.(MissingCasesError)
+	return ok && slices.Equal(e.Tags, t.Tags)
+}
+
+// RedundantCaseError is returned for an arm of a cases expression that can
+// never be taken.
+type RedundantCaseError struct {
+	Tag string
+}
+
+func (e RedundantCaseError) Error() string {
+	return fmt.Sprintf("the case for %s is redundant", e.Tag)
+}
+
+// UnmatchedCaseError is returned when evaluating a cases expression with no
+// arm for the tag of its value.
+type UnmatchedCaseError struct {
+	chapter3.RuntimeErrorBase
+	Tag string
+}
+
+func (e UnmatchedCaseError) Error() string {
+	return fmt.Sprintf("cases has no arm for %s", e.Tag)
+}
+
+// UnknownDatatypeError is returned for a type naming a datatype that has not
+// been declared.
+type UnknownDatatypeError struct {
+	Name string
+}
+
+func (e UnknownDatatypeError) Error() string {
+	return fmt.Sprintf("unknown datatype '%s'", e.Name)
+}
+
+// UnknownConstructorError is returned for a pattern using a constructor that
+// has not been declared.
+type UnknownConstructorError struct {
+	Name string
+}
+
+func (e UnknownConstructorError) Error() string {
+	return fmt.Sprintf("unknown constructor '%s'", e.Name)
+}
+
+// DuplicateConstructorError is returned when a constructor is declared by
+// more than one variant.
+type DuplicateConstructorError struct {
+	Name string
+}
+
+func (e DuplicateConstructorError) Error() string {
+	return fmt.Sprintf("constructor '%s' is declared more than once", e.Name)
+}
+
+// NonExhaustiveMatchError is returned for a match expression whose arms do
+// not match every value of its type.  Missing is a pattern for values no arm
+// matches, eg 'Node (Leaf (_), _)'.
+type NonExhaustiveMatchError struct {
+	Missing string
+}
+
+func (e NonExhaustiveMatchError) Error() string {
+	return fmt.Sprintf("match does not handle %s", e.Missing)
+}
+
+// RedundantArmError is returned for an arm of a match expression that only
+// matches values earlier arms do.
+type RedundantArmError struct {
+	Pattern string
+}
+
+func (e RedundantArmError) Error() string {
+	return fmt.Sprintf("the arm for %s is redundant", e.Pattern)
+}
+
+// MatchFailureError is returned when evaluating a match expression with no
+// arm matching its value.
+type MatchFailureError struct {
+	chapter3.RuntimeErrorBase
+	Value Value
+}
+
+func (e MatchFailureError) Error() string {
+	return fmt.Sprintf("match has no arm for %s", e.Value)
+}
+
+func (e MatchFailureError) Is(target error) bool {
+	t, ok := target.(MatchFailureError)
+	return ok && e.Value.Eq(t.Value)
+}
@@ -0,0 +1,53 @@
+package chapter7
+
+// Typing rules for the exceptions of chapter5.  Every exception in a program
+// has the same type - TypeChecker.ExceptionType if declared, otherwise found
+// from the first raise or try in the program.  Runtime errors caught by a
+// TryLangEval with CatchRuntimeErrors are not typed.
+
+// exceptionType returns the type of the exceptions of the expression being
+// checked.
+func (c *TypeChecker) exceptionType() (Type, error) {
+	if c.excType == nil {
+		t, err := c.Self.Unannotated("the exception type")
+		if err != nil {
+			return nil, err
+		}
+		c.excType = t
+	}
+	return c.excType, nil
+}
+
+// 'raise e' never returns so it can have any type.
+func (c *TypeChecker) TypeOfRaise(e *RaiseExpr, tenv *TypeEnv) (Type, error) {
+	exc, err := c.exceptionType()
+	if err != nil {
+		return nil, err
+	}
+	t, err := c.TypeOf(e.RaiseValueExpr, tenv)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Unify(exc, t); err != nil {
+		return nil, err
+	}
+	return c.Fresh(), nil
+}
+
+// 'try e catch (x) h' has the type of e, which h must also have with x bound
+// to an exception.
+func (c *TypeChecker) TypeOfTry(e *TryExpr, tenv *TypeEnv) (Type, error) {
+	exc, err := c.exceptionType()
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.TypeOf(e.TryBody, tenv)
+	if err != nil {
+		return nil, err
+	}
+	handler, err := c.TypeOf(e.HandlerExpr, tenv.Extend(map[string]Type{e.VarName: exc}))
+	if err != nil {
+		return nil, err
+	}
+	return body, c.Unify(body, handler)
+}
@@ -0,0 +1,96 @@
+package chapter7
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunExceptionTest checks an expression with the given declared exception
+// type.
+func RunExceptionTest(t *testing.T, exceptionType Type, tc *TypeTestCase) {
+	t.Helper()
+	checker := NewTypeChecker()
+	checker.ExceptionType = exceptionType
+	found, err := checker.Check(tc.Expr)
+	if expectedErr, ok := tc.Expected.(error); ok {
+		assert.ErrorIs(t, err, expectedErr, "Test %s", tc.Name)
+		return
+	}
+	require.NoError(t, err, "Test %s Failed - Unexpected error", tc.Name)
+	assert.True(t, TypeEq(tc.Expected.(Type), found), "Test %s: expected %s, found %s", tc.Name, tc.Expected, found)
+}
+
+// Ported from trylang_test.go in chapter5
+func TestCheckedExceptions(t *testing.T) {
+	cases := []TypeTestCase{
+		// try 10 catch (x) +(x, 1)
+		{Name: "try_normal", Expected: Int, Expr: Try(10, "x", Op("+", "x", 1))},
+		// try raise 10 catch (x) +(x, 1)
+		{Name: "try_catch", Expected: Int, Expr: Try(Raise(10), "x", Op("+", "x", 1))},
+		// try try raise 5 catch (x) raise +(x, 1) catch (y) *(y, 10)
+		{Name: "try_nested_outer_catch", Expected: Int,
+			Expr: Try(Try(Raise(5), "x", Raise(Op("+", "x", 1))), "y", Op("*", "y", 10))},
+		// let x = raise 99 in +(x, 1)
+		{Name: "uncaught_raise_in_let", Expected: Int, Expr: Let(ExprDict("x", Raise(99)), Op("+", "x", 1))},
+		// raise has any type - it is unconstrained here
+		{Name: "uncaught_raise", Expected: &TypeVar{ID: 1}, Expr: Raise(100)},
+		{Name: "handler type", Expected: TypeError{Expected: Int, Found: Bool}, Expr: Try(1, "x", true)},
+		{Name: "raised type", Expected: TypeError{Expected: Int, Found: Bool}, Expr: Raise(true)},
+		{Name: "handler var", Expected: TypeError{Expected: Bool, Found: Int}, Expr: Try(true, "x", "x")},
+	}
+	for _, tc := range cases {
+		RunExceptionTest(t, Int, &tc)
+	}
+
+	// CHECKED needs the exception type declared
+	RunTypeTest(t, &TypeTestCase{Name: "undeclared", Expected: MissingAnnotationError{Context: "the exception type"},
+		Expr: Try(Raise(10), "x", "x")})
+	RunTypeTest(t, &TypeTestCase{Name: "no exceptions", Expected: Int, Expr: Op("+", 1, 2)})
+}
+
+// listIndex is the listindex case from tests/chapter5/cases.py applied -
+//
+//	let index = proc (str) letrec inner (lst) =
+//	        if isnull(lst) then raise "ListIndexFailed"
+//	        else if stringequals(car(lst), str) then 0
+//	        else -((inner cdr(lst)), -1)
+//	    in inner
+//	in ((index "c") list("a", "b", "c"))
+func listIndex(strType, lstType, resultType Type) Expr {
+	inner := TypedProc([]string{"lst"}, []Type{lstType}, resultType,
+		If(Op("isnull", "lst"), Raise(Lit("ListIndexFailed")),
+			If(Op("stringequals", Op("car", "lst"), "str"), 0,
+				Op("-", Call("inner", Op("cdr", "lst")), -1))))
+	index := TypedProc([]string{"str"}, []Type{strType}, nil, LetRec(ProcMap("inner", inner), Var("inner")))
+	return Let(ExprDict("index", index), Call(Call("index", Lit("c")), List(Lit("a"), Lit("b"), Lit("c"))))
+}
+
+func TestListIndexTypes(t *testing.T) {
+	annotated := listIndex(String, ListOf(String), Int)
+	RunExceptionTest(t, String, &TypeTestCase{Name: "listindex", Expected: Int, Expr: annotated})
+	RunExceptionTest(t, Int, &TypeTestCase{Name: "int exceptions", Expected: TypeError{Expected: Int, Found: String},
+		Expr: annotated})
+	RunInferTest(t, "inferred listindex", listIndex(nil, nil, nil), "int")
+	RunInferTest(t, "inferred index", listIndex(nil, nil, nil).(*LetExpr).Mappings["index"], "(string -> (list string -> int))")
+}
+
+func TestInferExceptions(t *testing.T) {
+	// try raise "oops" catch (e) if stringequals(e, "oops") then 1 else 0
+	RunInferTest(t, "string exceptions",
+		Try(Raise(Lit("oops")), "e", If(Op("stringequals", "e", Lit("oops")), 1, 0)), "int")
+	// One exception type per program - (raise 1, raise true)
+	RunInferTest(t, "two exception types", Tuple(Raise(1), Raise(true)), TypeError{Expected: Int, Found: Bool})
+	// proc (x) try x catch (e) e
+	RunInferTest(t, "handler", Proc([]string{"x"}, Try("x", "e", "e")), "(t2 -> t2)")
+
+	// The exception type is not generalized with let -
+	// let f = proc (x) raise x in ((f 1), (f true))
+	raiser := Proc([]string{"x"}, Raise("x"))
+	RunPolyTest(t, "raiser", Let(ExprDict("f", raiser), Tuple(Call("f", 1), Call("f", true))),
+		TypeError{Expected: Int, Found: Bool})
+	// but the results of raise are - let f = proc (x) raise 1 in ((f 1), (f true))
+	RunPolyTest(t, "raise result", Let(ExprDict("f", Proc([]string{"x"}, Raise(1))), Tuple(Call("f", 1), Call("f", true))),
+		"forall a b. (a, b)")
+}
@@ -0,0 +1,85 @@
+package chapter7
+
+import (
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+	"github.com/panyam/eplgo/chapter4"
+	"github.com/panyam/eplgo/chapter5"
+)
+
+// A few imports to not avoid having to prefix with chapter3 all over the place
+type Expr = chapter3.Expr
+type LitExpr = chapter3.LitExpr
+type VarExpr = chapter3.VarExpr
+type OpExpr = chapter3.OpExpr
+type IsZeroExpr = chapter3.IsZeroExpr
+type IfExpr = chapter3.IfExpr
+type LetExpr = chapter3.LetExpr
+type TupleExpr = chapter3.TupleExpr
+type ListExpr = chapter3.ListExpr
+type ProcExpr = chapter3.ProcExpr
+type CallExpr = chapter3.CallExpr
+type LetRecExpr = chapter3.LetRecExpr
+type UnpackExpr = chapter3.UnpackExpr
+type TupleRefExpr = chapter3.TupleRefExpr
+type RecordExpr = chapter3.RecordExpr
+type FieldExpr = chapter3.FieldExpr
+type UpdateExpr = chapter3.UpdateExpr
+type TypeAnnotation = chapter3.TypeAnnotation
+type RefExpr = chapter4.RefExpr
+type DeRefExpr = chapter4.DeRefExpr
+type SetRefExpr = chapter4.SetRefExpr
+type BlockExpr = chapter4.BlockExpr
+type AssignExpr = chapter4.AssignExpr
+type SetFieldExpr = chapter4.SetFieldExpr
+type LazyExpr = chapter4.LazyExpr
+type ThunkExpr = chapter4.ThunkExpr
+type TryExpr = chapter5.TryExpr
+type RaiseExpr = chapter5.RaiseExpr
+
+var ExprDict = epl.Dict[string, Expr]
+
+type Value = chapter3.Value
+type IntVal = chapter3.IntVal
+type BigIntVal = chapter3.BigIntVal
+type BoolVal = chapter3.BoolVal
+type StringVal = chapter3.StringVal
+type TupleVal = chapter3.TupleVal
+type RecordVal = chapter3.RecordVal
+
+type UnboundVariableError = chapter3.UnboundVariableError
+type UnknownOperatorError = chapter3.UnknownOperatorError
+type UnsupportedExprError = chapter3.UnsupportedExprError
+type ArityError = chapter3.ArityError
+
+var ToValue = chapter3.ToValue
+var Lit = chapter3.Lit
+var Var = chapter3.Var
+var Op = chapter3.Op
+var If = chapter3.If
+var IsZero = chapter3.IsZero
+var Let = chapter3.Let
+var LetRec = chapter3.LetRec
+var ProcMap = chapter3.ProcMap
+var Proc = chapter3.Proc
+var Call = chapter3.Call
+var Tuple = chapter3.Tuple
+var List = chapter3.List
+var Unpack = chapter3.Unpack
+var TupleRef = chapter3.TupleRef
+var Record = chapter3.Record
+var Field = chapter3.Field
+var Update = chapter3.Update
+var AnyToExpr = chapter3.AnyToExpr
+var NewRef = chapter4.NewRef
+var RefVar = chapter4.RefVar
+var DeRef = chapter4.DeRef
+var SetRef = chapter4.SetRef
+var Begin = chapter4.Begin
+var Assign = chapter4.Assign
+var SetField = chapter4.SetField
+var Lazy = chapter4.Lazy
+var ForceThunk = chapter4.ForceThunk
+var Try = chapter5.Try
+var Raise = chapter5.Raise
+var ExprEq = chapter3.ExprEq
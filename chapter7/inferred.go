@@ -0,0 +1,21 @@
+package chapter7
+
+// Inferencer finds the types of the expressions in the INFERRED language,
+// where type annotations are optional.  Anything unannotated gets a fresh
+// type variable which is solved for by unification as the expression is
+// checked, as in inferred.py.  Types are monomorphic - a let bound procedure
+// has a single type in its body.
+type Inferencer struct {
+	TypeChecker
+}
+
+func NewInferencer() *Inferencer {
+	out := &Inferencer{TypeChecker: TypeChecker{OpTypes: DefaultOpTypes(), Subst: Substitution{}}}
+	out.Self = out
+	return out
+}
+
+// Unannotated returns a fresh type variable to be solved for.
+func (i *Inferencer) Unannotated(context string) (Type, error) {
+	return i.Fresh(), nil
+}
@@ -0,0 +1,94 @@
+package chapter7
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunInferTest infers the type of an expression, expecting either a type,
+// compared by how it prints as the type variables are numbered in the order
+// they are created, or an error.
+func RunInferTest(t *testing.T, name string, expr Expr, expected any) {
+	t.Helper()
+	found, err := NewInferencer().Check(expr)
+	if expectedErr, ok := expected.(error); ok {
+		assert.ErrorIs(t, err, expectedErr, "Test %s", name)
+		return
+	}
+	require.NoError(t, err, "Test %s Failed - Unexpected error", name)
+	assert.Equal(t, expected, found.String(), "Test %s", name)
+}
+
+// Ported from basic_checks in tests/chapter7/cases.py - without any
+// annotations
+func TestInferBasicChecks(t *testing.T) {
+	fn := func(params []string, body any) Expr { return Proc(params, AnyToExpr(body)) }
+	t1, t2, t3 := &TypeVar{ID: 1}, &TypeVar{ID: 2}, &TypeVar{ID: 3}
+	RunInferTest(t, "non_bool_test", If(3, 88, 99), TypeError{Expected: Bool, Found: Int})
+	RunInferTest(t, "proc_val_rator", fn([]string{"x"}, Call("x", 3)), "((int -> t2) -> t2)")
+	RunInferTest(t, "non_proc_val_rator1", fn([]string{"x"}, Call(3, "x")),
+		TypeError{Expected: Func([]Type{t1}, nil), Found: Int})
+	RunInferTest(t, "non_proc_val_rator2", Let(ExprDict("x", Lit(4)), Call("x", 3)),
+		TypeError{Expected: Func([]Type{Int}, nil), Found: Int})
+	RunInferTest(t, "non_proc_val_rator3", Call(fn([]string{"x"}, Call("x", 3)), 4),
+		TypeError{Expected: Func([]Type{Int}, t2), Found: Int})
+	RunInferTest(t, "non_int_diff_arg", Let(ExprDict("x", IsZero(0)), Op("-", 3, "x")),
+		TypeError{Expected: Int, Found: Bool})
+	RunInferTest(t, "non_int_diff_arg2", Call(fn([]string{"x"}, Op("-", 3, "x")), IsZero(0)),
+		TypeError{Expected: Int, Found: Bool})
+	RunInferTest(t, "non_proc_val_rator4", Let(ExprDict("f", Lit(3)), fn([]string{"x"}, Call("f", "x"))),
+		TypeError{Expected: Func([]Type{t1}, nil), Found: Int})
+	RunInferTest(t, "non_proc_val_rator5", Call(fn([]string{"f"}, fn([]string{"x"}, Call("f", "x"))), 3),
+		TypeError{Expected: Func([]Type{t2}, t3), Found: Int})
+	RunInferTest(t, "3", Lit(3), "int")
+	RunInferTest(t, "diff", Op("-", 10, 20), "int")
+}
+
+func TestInferLetRec(t *testing.T) {
+	// The inferred case from tests/chapter7/cases.py:
+	//
+	//   letrec
+	//     even (x : int) -> ? = if isz(x) then 1 else (odd -(x, 1))
+	//     odd (x : ?) -> bool = if isz(x) then 0 else (even -(x, 1))
+	//   in (odd 13)
+	//
+	// The branches of each if have different types so this is not well typed.
+	evenodd := LetRec(ProcMap(
+		"even", TypedProc([]string{"x"}, []Type{Int}, nil, If(IsZero("x"), 1, Call("odd", Op("-", "x", 1)))),
+		"odd", TypedProc([]string{"x"}, []Type{nil}, Bool, If(IsZero("x"), 0, Call("even", Op("-", "x", 1))))),
+		Call("odd", 13))
+	RunInferTest(t, "case1", evenodd, TypeError{Expected: Int, Found: Bool})
+
+	// letrec double (x) = if isz(x) then 0 else -((double -(x, 1)), -2) in double
+	RunInferTest(t, "double", LetRec(ProcMap("double", Proc([]string{"x"},
+		If(IsZero("x"), 0, Op("-", Call("double", Op("-", "x", 1)), -2)))), Var("double")), "(int -> int)")
+}
+
+func TestInferOperators(t *testing.T) {
+	RunInferTest(t, "car", Proc([]string{"l"}, Op("car", "l")), "(list t2 -> t2)")
+	RunInferTest(t, "emptylist", Op("cons", 1, Op("emptylist")), "list int")
+	RunInferTest(t, "empty list literal", Proc([]string{"x"}, Op("cons", "x", List())), "(t2 -> list t2)")
+	RunInferTest(t, "equal?", Proc([]string{"x", "y"}, If(Op("equal?", "x", "y"), "x", 1)), "(int * int -> int)")
+	RunInferTest(t, "tuple", Proc([]string{"x", "y"}, Tuple(Op("strlen", "x"), Var("y"))), "(string * t2 -> (int, t2))")
+	RunInferTest(t, "curried", Call(Proc([]string{"x", "y"}, Op("+", "x", "y")), 1), "(int -> int)")
+
+	// The checker does not infer
+	_, err := NewTypeChecker().Check(Op("emptylist"))
+	assert.ErrorIs(t, err, MissingAnnotationError{Context: "an empty list"})
+}
+
+func TestInferOccursCheck(t *testing.T) {
+	// proc (x) (x x)
+	_, err := NewInferencer().Check(Proc([]string{"x"}, Call("x", "x")))
+	var occurs OccursError
+	require.ErrorAs(t, err, &occurs)
+	assert.EqualError(t, occurs, "type variable t1 occurs in (t1 -> t2)")
+}
+
+func TestInferLetRecIdentity(t *testing.T) {
+	// letrec f (x) = x in (f 1) - the type of f found from its body is the
+	// one used in the letrec's body
+	RunInferTest(t, "identity", LetRec(ProcMap("f", Proc([]string{"x"}, Var("x"))), Call("f", 1)), "int")
+}
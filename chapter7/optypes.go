@@ -0,0 +1,159 @@
+package chapter7
+
+// OpType gives the type of an operator's result from the types of its
+// arguments, or an error if the operator cannot take them.  Argument types
+// are matched with the checker's Unify so operators work in both the checked
+// and inferred languages.
+type OpType func(c *TypeChecker, args []Type) (Type, error)
+
+func checkArity(op string, args []Type, expected int) error {
+	if len(args) != expected {
+		return ArityError{Context: "'" + op + "' operator", Expected: expected, Found: len(args)}
+	}
+	return nil
+}
+
+// FixedOp is the type of an operator taking exactly the given parameter types.
+func FixedOp(op string, result Type, params ...Type) OpType {
+	return func(c *TypeChecker, args []Type) (Type, error) {
+		if err := checkArity(op, args, len(params)); err != nil {
+			return nil, err
+		}
+		for i, param := range params {
+			if err := c.Unify(param, args[i]); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+}
+
+// VariadicOp is the type of an operator taking any number of arguments of the
+// same type, like '+'.
+func VariadicOp(param Type, result Type) OpType {
+	return func(c *TypeChecker, args []Type) (Type, error) {
+		for _, arg := range args {
+			if err := c.Unify(param, arg); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+}
+
+// listElem returns the type of the elements of a list type.
+func (c *TypeChecker) listElem(t Type) (Type, error) {
+	return c.taggedElem("list", t)
+}
+
+// taggedElem returns the type inside a TaggedType with the given name, eg
+// int for 'list int' or 'refto int'.
+func (c *TypeChecker) taggedElem(name string, t Type) (Type, error) {
+	switch tagged := c.Subst.Resolve(t).(type) {
+	case *TaggedType:
+		if tagged.Name == name {
+			return tagged.Type, nil
+		}
+	case *TypeVar:
+		elem := c.Fresh()
+		return elem, c.Unify(Tagged(name, elem), tagged)
+	}
+	return nil, TypeError{Expected: Tagged(name, nil), Found: c.Subst.Apply(t)}
+}
+
+// listType is the type of a list with items of the given types, which must
+// all be the same.
+func (c *TypeChecker) listType(items []Type) (Type, error) {
+	if len(items) == 0 {
+		elem, err := c.Self.Unannotated("an empty list")
+		if err != nil {
+			return nil, err
+		}
+		return ListOf(elem), nil
+	}
+	for _, item := range items[1:] {
+		if err := c.Unify(items[0], item); err != nil {
+			return nil, err
+		}
+	}
+	return ListOf(items[0]), nil
+}
+
+// DefaultOpTypes returns the types of the operators in chapter3's SetOpFuncs,
+// SetStringOpFuncs and SetListOpFuncs and in the prelude.  '/' is left out as
+// it is true division and so does not always give an int.
+func DefaultOpTypes() map[string]OpType {
+	out := map[string]OpType{
+		"+":   VariadicOp(Int, Int),
+		"*":   VariadicOp(Int, Int),
+		"-":   FixedOp("-", Int, Int, Int),
+		"min": VariadicOp(Int, Int),
+		"max": VariadicOp(Int, Int),
+		"not": FixedOp("not", Bool, Bool),
+
+		"concat":        VariadicOp(String, String),
+		"strlen":        FixedOp("strlen", Int, String),
+		"substring":     FixedOp("substring", String, String, Int, Int),
+		"stringequals":  FixedOp("stringequals", Bool, String, String),
+		"stringcompare": FixedOp("stringcompare", Int, String, String),
+		"toint":         FixedOp("toint", Int, String),
+	}
+	for _, op := range []string{"<", ">", "<=", ">=", "="} {
+		out[op] = FixedOp(op, Bool, Int, Int)
+	}
+	for _, op := range []string{"isz", "zero?"} {
+		out[op] = FixedOp(op, Bool, Int)
+	}
+	out["equal?"] = func(c *TypeChecker, args []Type) (Type, error) {
+		if err := checkArity("equal?", args, 2); err != nil {
+			return nil, err
+		}
+		return Bool, c.Unify(args[0], args[1])
+	}
+	out["tostring"] = func(c *TypeChecker, args []Type) (Type, error) {
+		return String, checkArity("tostring", args, 1)
+	}
+
+	// Lists
+	out["list"] = (*TypeChecker).listType
+	out["emptylist"] = func(c *TypeChecker, args []Type) (Type, error) {
+		if err := checkArity("emptylist", args, 0); err != nil {
+			return nil, err
+		}
+		return c.listType(nil)
+	}
+	out["cons"] = func(c *TypeChecker, args []Type) (Type, error) {
+		if err := checkArity("cons", args, 2); err != nil {
+			return nil, err
+		}
+		if err := c.Unify(ListOf(args[0]), args[1]); err != nil {
+			return nil, err
+		}
+		return args[1], nil
+	}
+	out["car"] = func(c *TypeChecker, args []Type) (Type, error) {
+		if err := checkArity("car", args, 1); err != nil {
+			return nil, err
+		}
+		return c.listElem(args[0])
+	}
+	out["cdr"] = func(c *TypeChecker, args []Type) (Type, error) {
+		if err := checkArity("cdr", args, 1); err != nil {
+			return nil, err
+		}
+		if _, err := c.listElem(args[0]); err != nil {
+			return nil, err
+		}
+		return args[0], nil
+	}
+	for _, op := range []string{"null?", "isnull"} {
+		out[op] = func(c *TypeChecker, args []Type) (Type, error) {
+			if err := checkArity(op, args, 1); err != nil {
+				return nil, err
+			}
+			_, err := c.listElem(args[0])
+			return Bool, err
+		}
+	}
+	return out
+}
@@ -0,0 +1,272 @@
+package chapter7
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	gfn "github.com/panyam/goutils/fn"
+)
+
+// Pattern is the left hand side of an arm of a match expression.  Patterns
+// are compared by how they print.
+type Pattern interface {
+	String() string
+}
+
+// WildcardPattern is '_' - it matches anything.
+type WildcardPattern struct{}
+
+func Wildcard() WildcardPattern { return WildcardPattern{} }
+
+func (p WildcardPattern) String() string { return "_" }
+
+// VarPattern matches anything, binding it to Name.
+type VarPattern struct {
+	Name string
+}
+
+func PatVar(name string) *VarPattern { return &VarPattern{Name: name} }
+
+func (p *VarPattern) String() string { return p.Name }
+
+// ConstructorPattern matches values made by the constructor Name whose
+// fields match Args.
+type ConstructorPattern struct {
+	Name string
+	Args []Pattern
+}
+
+func PatCons(name string, args ...Pattern) *ConstructorPattern {
+	return &ConstructorPattern{Name: name, Args: args}
+}
+
+func (p *ConstructorPattern) String() string {
+	if len(p.Args) == 0 {
+		return p.Name
+	}
+	return fmt.Sprintf("%s (%s)", p.Name, strings.Join(gfn.Map(p.Args, Pattern.String), ", "))
+}
+
+// TuplePattern matches tuples of the same size whose items match Children.
+type TuplePattern struct {
+	Children []Pattern
+}
+
+func PatTuple(children ...Pattern) *TuplePattern {
+	return &TuplePattern{Children: children}
+}
+
+func (p *TuplePattern) String() string {
+	return "(" + strings.Join(gfn.Map(p.Children, Pattern.String), ", ") + ")"
+}
+
+// PatternVars returns the names a pattern binds, in order.
+func PatternVars(p Pattern) (out []string) {
+	switch p := p.(type) {
+	case *VarPattern:
+		out = append(out, p.Name)
+	case *ConstructorPattern:
+		for _, arg := range p.Args {
+			out = append(out, PatternVars(arg)...)
+		}
+	case *TuplePattern:
+		for _, child := range p.Children {
+			out = append(out, PatternVars(child)...)
+		}
+	}
+	return
+}
+
+// MatchPattern checks whether a value matches a pattern, adding the values
+// of the pattern's variables to bindings.
+func MatchPattern(p Pattern, val Value, bindings map[string]any) bool {
+	switch p := p.(type) {
+	case WildcardPattern:
+		return true
+	case *VarPattern:
+		bindings[p.Name] = val
+		return true
+	case *ConstructorPattern:
+		data, ok := val.(*DataVal)
+		if !ok || data.Constructor != p.Name || len(data.Fields) != len(p.Args) {
+			return false
+		}
+		for i, arg := range p.Args {
+			if !MatchPattern(arg, data.Fields[i], bindings) {
+				return false
+			}
+		}
+		return true
+	case *TuplePattern:
+		tuple, ok := val.(TupleVal)
+		if !ok || len(tuple) != len(p.Children) {
+			return false
+		}
+		for i, child := range p.Children {
+			if !MatchPattern(child, tuple[i], bindings) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// The missing and redundant arm checks follow Maranget's "Warnings for
+// pattern matching" - each is a question of whether a row of patterns is
+// useful after the rows of a pattern matrix, ie matches some value none of
+// them do.  Variables are treated as wildcards.
+
+// patternHead is a constructor, or a tuple, at the head of a pattern.
+type patternHead struct {
+	name  string
+	arity int
+	tuple bool
+}
+
+func (h patternHead) pattern(args []Pattern) Pattern {
+	if h.tuple {
+		return PatTuple(args...)
+	}
+	return PatCons(h.name, args...)
+}
+
+func headOf(p Pattern) (patternHead, []Pattern, bool) {
+	switch p := p.(type) {
+	case *ConstructorPattern:
+		return patternHead{name: p.Name, arity: len(p.Args)}, p.Args, true
+	case *TuplePattern:
+		return patternHead{name: fmt.Sprintf("%d-tuple", len(p.Children)), arity: len(p.Children), tuple: true}, p.Children, true
+	}
+	return patternHead{}, nil, false
+}
+
+func wildcards(n int) []Pattern {
+	out := make([]Pattern, n)
+	for i := range out {
+		out[i] = Wildcard()
+	}
+	return out
+}
+
+// specialize returns the rows that match the head h, with the head replaced
+// by its arguments.
+func specialize(rows [][]Pattern, h patternHead) [][]Pattern {
+	var out [][]Pattern
+	for _, row := range rows {
+		if head, args, ok := headOf(row[0]); !ok {
+			out = append(out, append(wildcards(h.arity), row[1:]...))
+		} else if head.name == h.name {
+			out = append(out, append(slices.Clone(args), row[1:]...))
+		}
+	}
+	return out
+}
+
+// defaultRows returns the rows starting with a wildcard without it.
+func defaultRows(rows [][]Pattern) [][]Pattern {
+	var out [][]Pattern
+	for _, row := range rows {
+		if _, _, ok := headOf(row[0]); !ok {
+			out = append(out, row[1:])
+		}
+	}
+	return out
+}
+
+// matchChecker checks the arms of matches given the datatypes declared.
+type matchChecker struct {
+	constructors map[string]*constructorInfo
+}
+
+// signature returns all the heads of the type of the first column of rows
+// and whether the rows start with every one of them.  If the column has no
+// constructors its type is unknown and all is nil.
+func (m *matchChecker) signature(rows [][]Pattern) (all []patternHead, complete bool) {
+	seen := map[string]bool{}
+	for _, row := range rows {
+		head, _, ok := headOf(row[0])
+		if !ok {
+			continue
+		}
+		if head.tuple {
+			// Tuples have a single constructor
+			return []patternHead{head}, true
+		}
+		seen[head.name] = true
+		if all == nil {
+			for _, v := range m.constructors[head.name].Datatype.Variants {
+				all = append(all, patternHead{name: v.Name, arity: len(v.Fields)})
+			}
+		}
+	}
+	return all, all != nil && !slices.ContainsFunc(all, func(h patternHead) bool { return !seen[h.name] })
+}
+
+// useful checks whether the row q matches any values no row of rows does.
+func (m *matchChecker) useful(rows [][]Pattern, q []Pattern) bool {
+	if len(q) == 0 {
+		return len(rows) == 0
+	}
+	if head, args, ok := headOf(q[0]); ok {
+		return m.useful(specialize(rows, head), append(slices.Clone(args), q[1:]...))
+	}
+	if all, complete := m.signature(rows); complete {
+		for _, head := range all {
+			if m.useful(specialize(rows, head), append(wildcards(head.arity), q[1:]...)) {
+				return true
+			}
+		}
+		return false
+	}
+	return m.useful(defaultRows(rows), q[1:])
+}
+
+// witness returns n patterns matching values that no row of rows does, if
+// there are any.
+func (m *matchChecker) witness(rows [][]Pattern, n int) ([]Pattern, bool) {
+	if n == 0 {
+		return nil, len(rows) == 0
+	}
+	all, complete := m.signature(rows)
+	if complete {
+		for _, head := range all {
+			if w, ok := m.witness(specialize(rows, head), head.arity+n-1); ok {
+				return append([]Pattern{head.pattern(w[:head.arity])}, w[head.arity:]...), true
+			}
+		}
+		return nil, false
+	}
+	w, ok := m.witness(defaultRows(rows), n-1)
+	if !ok {
+		return nil, false
+	}
+	var first Pattern = Wildcard()
+	for _, head := range all {
+		if !slices.ContainsFunc(rows, func(row []Pattern) bool { h, _, ok := headOf(row[0]); return ok && h.name == head.name }) {
+			// A constructor no row starts with
+			first = head.pattern(wildcards(head.arity))
+			break
+		}
+	}
+	return append([]Pattern{first}, w...), true
+}
+
+// checkArms returns a RedundantArmError for the first arm that can never
+// match or a NonExhaustiveMatchError with an example of a value no arm
+// matches.
+func (m *matchChecker) checkArms(arms []*MatchArm) error {
+	var rows [][]Pattern
+	for _, arm := range arms {
+		row := []Pattern{arm.Pattern}
+		if !m.useful(rows, row) {
+			return RedundantArmError{Pattern: arm.Pattern.String()}
+		}
+		rows = append(rows, row)
+	}
+	if w, ok := m.witness(rows, 1); ok {
+		return NonExhaustiveMatchError{Missing: w[0].String()}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package chapter7
+
+import (
+	"slices"
+
+	epl "github.com/panyam/eplgo"
+)
+
+// PolyInferencer adds let-polymorphism to the Inferencer.  The types of names
+// bound by let and letrec are generalized into Schemes over the type
+// variables that are not also in the types of the enclosing names, and each
+// use of such a name instantiates its Scheme with fresh variables.  So
+// 'let id = proc (x) x in (id id)' is typed even though 'id' is used at two
+// different types.  Procedure parameters stay monomorphic.
+//
+// With references a polymorphic binding could be unsound - in
+// 'let r = newref(proc (x) x) in ...' r could be set to an int procedure and
+// then called with a bool.  So, as in ML, only the types of syntactic values
+// (see IsValue) are generalized, and variables with polymorphic types cannot
+// be assigned to.
+type PolyInferencer struct {
+	Inferencer
+}
+
+func NewPolyInferencer() *PolyInferencer {
+	out := &PolyInferencer{Inferencer: Inferencer{TypeChecker: TypeChecker{OpTypes: DefaultOpTypes(), Subst: Substitution{}}}}
+	out.Self = out
+	return out
+}
+
+// Check returns the type of a closed expression, generalized over any type
+// variables left in it.
+func (i *PolyInferencer) Check(expr Expr) (Type, error) {
+	t, err := i.Inferencer.Check(expr)
+	if err != nil {
+		return nil, err
+	}
+	return i.Generalize(t, epl.NewEnv[Type](nil)), nil
+}
+
+func (i *PolyInferencer) LocalTypeOf(expr Expr, tenv *TypeEnv) (Type, error) {
+	switch n := expr.(type) {
+	case *LetExpr:
+		return i.TypeOfLetExpr(n, tenv)
+	case *LetRecExpr:
+		return i.TypeOfLetRec(n, tenv)
+	}
+	return i.Inferencer.LocalTypeOf(expr, tenv)
+}
+
+func (i *PolyInferencer) TypeOfLetExpr(e *LetExpr, tenv *TypeEnv) (Type, error) {
+	types := map[string]Type{}
+	for _, name := range epl.SortedKeys(e.Mappings) {
+		t, err := i.TypeOf(e.Mappings[name], tenv)
+		if err != nil {
+			return nil, err
+		}
+		if IsValue(e.Mappings[name]) {
+			t = i.Generalize(t, tenv)
+		}
+		types[name] = t
+	}
+	return i.TypeOf(e.Body, tenv.Extend(types))
+}
+
+// The procedures of a letrec are monomorphic in each other's bodies and
+// generalized for the body of the letrec.
+func (i *PolyInferencer) TypeOfLetRec(e *LetRecExpr, tenv *TypeEnv) (Type, error) {
+	procs, err := i.LetRecTypes(e, tenv)
+	if err != nil {
+		return nil, err
+	}
+	for name, t := range procs {
+		procs[name] = i.Generalize(t, tenv)
+	}
+	return i.TypeOf(e.Body, tenv.Extend(procs))
+}
+
+// IsValue returns true for the expressions whose evaluation cannot create
+// references - literals, variables, procedures and tuples, lists and tagged
+// values of values.
+func IsValue(expr Expr) bool {
+	switch n := expr.(type) {
+	case *LitExpr, *VarExpr, *ProcExpr:
+		return true
+	case *TupleExpr:
+		return !slices.ContainsFunc(n.Children, func(e Expr) bool { return !IsValue(e) })
+	case *ListExpr:
+		return !slices.ContainsFunc(n.Items, func(e Expr) bool { return !IsValue(e) })
+	case *TagExpr:
+		return IsValue(n.Expr)
+	}
+	return false
+}
+
+// Generalize returns a Scheme quantifying the type variables in t that are
+// not free in tenv or in the exception type, or t itself if there are none.
+func (c *TypeChecker) Generalize(t Type, tenv *TypeEnv) Type {
+	t = c.Subst.Apply(t)
+	inEnv := c.freeVars(c.excType, nil)
+	for env := tenv; env != nil; env = env.Outer() {
+		for _, name := range env.Names() {
+			bound, _ := env.Get(name)
+			inEnv = c.freeVars(bound, inEnv)
+		}
+	}
+	var vars []*TypeVar
+	for _, id := range c.freeVars(t, nil) {
+		if !slices.Contains(inEnv, id) {
+			vars = append(vars, &TypeVar{ID: id})
+		}
+	}
+	if len(vars) == 0 {
+		return t
+	}
+	return &Scheme{Vars: vars, Type: t}
+}
+
+// Instantiate replaces the quantified variables of a Scheme with fresh type
+// variables.  Other types are returned as they are.
+func (c *TypeChecker) Instantiate(t Type) Type {
+	scheme, ok := t.(*Scheme)
+	if !ok {
+		return t
+	}
+	fresh := map[int]Type{}
+	for _, v := range scheme.Vars {
+		fresh[v.ID] = c.Fresh()
+	}
+	return replaceVars(scheme.Type, fresh)
+}
+
+// freeVars appends the IDs of the unbound, unquantified type variables in t
+// to out, in the order they first appear.
+func (c *TypeChecker) freeVars(t Type, out []int) []int {
+	switch t := c.Subst.Resolve(t).(type) {
+	case *TypeVar:
+		if !slices.Contains(out, t.ID) {
+			out = append(out, t.ID)
+		}
+	case *FuncType:
+		for _, arg := range t.Args {
+			out = c.freeVars(arg, out)
+		}
+		out = c.freeVars(t.Result, out)
+	case *TupleType:
+		for _, child := range t.Children {
+			out = c.freeVars(child, out)
+		}
+	case *TaggedType:
+		out = c.freeVars(t.Type, out)
+	case rowType:
+		labels, rest := c.Subst.flatten(t).row()
+		for _, label := range epl.SortedKeys(labels) {
+			out = c.freeVars(labels[label], out)
+		}
+		if rest != nil {
+			out = c.freeVars(rest, out)
+		}
+	case *DataType:
+		for _, arg := range t.Args {
+			out = c.freeVars(arg, out)
+		}
+	case *Scheme:
+		for _, id := range c.freeVars(t.Type, nil) {
+			if !slices.ContainsFunc(t.Vars, func(v *TypeVar) bool { return v.ID == id }) && !slices.Contains(out, id) {
+				out = append(out, id)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package chapter7
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func RunPolyTest(t *testing.T, name string, expr Expr, expected any) {
+	t.Helper()
+	found, err := NewPolyInferencer().Check(expr)
+	if expectedErr, ok := expected.(error); ok {
+		assert.ErrorIs(t, err, expectedErr, "Test %s", name)
+		return
+	}
+	require.NoError(t, err, "Test %s Failed - Unexpected error", name)
+	assert.Equal(t, expected, found.String(), "Test %s", name)
+}
+
+var identity = Proc([]string{"x"}, Var("x"))
+
+func TestLetPolymorphism(t *testing.T) {
+	RunPolyTest(t, "identity", identity, "forall a. (a -> a)")
+	// let id = proc (x) x in (id id)
+	RunPolyTest(t, "id id", Let(ExprDict("id", identity), Call("id", "id")), "forall a. (a -> a)")
+	// let id = proc (x) x in ((id 1), (id true))
+	RunPolyTest(t, "two uses", Let(ExprDict("id", identity), Tuple(Call("id", 1), Call("id", true))), "(int, bool)")
+	// let const = proc (x, y) x in const
+	RunPolyTest(t, "const", Let(ExprDict("const", Proc([]string{"x", "y"}, Var("x"))), Var("const")),
+		"forall a b. (a * b -> a)")
+	RunPolyTest(t, "monomorphic", Op("-", 1, 2), "int")
+
+	// Only let bound names are polymorphic - proc (f) ((f 1), (f true))
+	RunPolyTest(t, "parameter", Proc([]string{"f"}, Tuple(Call("f", 1), Call("f", true))),
+		TypeError{Expected: Int, Found: Bool})
+
+	// Variables in the types of enclosing names are not generalized -
+	// proc (x) let y = x in (y 1)
+	RunPolyTest(t, "enclosing", Proc([]string{"x"}, Let(ExprDict("y", Var("x")), Call("y", 1))),
+		"forall a. ((int -> a) -> a)")
+
+	// The monomorphic inferencer finds id used at two types
+	_, err := NewInferencer().Check(Let(ExprDict("id", identity), Call("id", "id")))
+	var occurs OccursError
+	assert.ErrorAs(t, err, &occurs)
+}
+
+func TestLetRecPolymorphism(t *testing.T) {
+	// letrec map (f, l) = if null?(l) then emptylist
+	//                     else cons((f car(l)), (map f cdr(l)))
+	// in map
+	mapProc := LetRec(ProcMap("map", Proc([]string{"f", "l"},
+		If(Op("null?", "l"), Op("emptylist"),
+			Op("cons", Call("f", Op("car", "l")), Call("map", "f", Op("cdr", "l")))))), Var("map"))
+	RunPolyTest(t, "map", mapProc, "forall a b. ((a -> b) * list a -> list b)")
+
+	// Used at two types in the body
+	mapTwice := LetRec(mapProc.Procs, Tuple(
+		Call("map", Proc([]string{"x"}, IsZero("x")), List(1, 2)),
+		Call("map", Proc([]string{"x"}, Op("-", "x", 1)), List(1, 2))))
+	RunPolyTest(t, "map twice", mapTwice, "(list bool, list int)")
+}
+
+func TestSchemes(t *testing.T) {
+	a, b := &TypeVar{ID: 7}, &TypeVar{ID: 3}
+	s := &Scheme{Vars: []*TypeVar{a, b}, Type: Func([]Type{a, ListOf(b)}, &TypeVar{ID: 9})}
+	assert.Equal(t, "forall a b. (a * list b -> t9)", s.String())
+	assert.True(t, s.Eq(&Scheme{Vars: []*TypeVar{b, a}, Type: Func([]Type{b, ListOf(a)}, &TypeVar{ID: 9})}))
+	assert.False(t, s.Eq(&Scheme{Vars: []*TypeVar{b, a}, Type: Func([]Type{a, ListOf(b)}, &TypeVar{ID: 9})}))
+	assert.Equal(t, "z", schemeVarName(25))
+	assert.Equal(t, "a1", schemeVarName(26))
+
+	c := NewPolyInferencer()
+	inst := c.Instantiate(s)
+	assert.Equal(t, "(t1 * list t2 -> t9)", inst.String())
+}
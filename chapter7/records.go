@@ -0,0 +1,83 @@
+package chapter7
+
+import epl "github.com/panyam/eplgo"
+
+// Typing rules for records.  A record literal has a closed record type, and
+// taking a field only needs the record to have that field - the type of
+// 'proc (r) r.x' is '({x : t1, ..t2} -> t1)' so it can be applied to any
+// record with an x.
+
+func (c *TypeChecker) TypeOfRecord(e *RecordExpr, tenv *TypeEnv) (Type, error) {
+	fields := map[string]Type{}
+	for _, name := range epl.SortedKeys(e.Fields) {
+		t, err := c.TypeOf(e.Fields[name], tenv)
+		if err != nil {
+			return nil, err
+		}
+		fields[name] = t
+	}
+	return RecordOf(fields, nil), nil
+}
+
+func (c *TypeChecker) TypeOfField(e *FieldExpr, tenv *TypeEnv) (Type, error) {
+	t, err := c.TypeOf(e.Record, tenv)
+	if err != nil {
+		return nil, err
+	}
+	return c.fieldType(t, e.Field)
+}
+
+// '{r with x = e}' is a record of r's type so e must be of the type of r's
+// x.
+func (c *TypeChecker) TypeOfUpdate(e *UpdateExpr, tenv *TypeEnv) (Type, error) {
+	t, err := c.TypeOf(e.Record, tenv)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range epl.SortedKeys(e.Fields) {
+		if _, err := c.checkField(t, name, e.Fields[name], tenv); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// 'set r.x = e' needs e to be of the type of r's x and evaluates to e.
+func (c *TypeChecker) TypeOfSetField(e *SetFieldExpr, tenv *TypeEnv) (Type, error) {
+	t, err := c.TypeOf(e.Record, tenv)
+	if err != nil {
+		return nil, err
+	}
+	return c.checkField(t, e.Field, e.Expr, tenv)
+}
+
+// checkField checks that expr has the type of the field of a record type,
+// returning that type.
+func (c *TypeChecker) checkField(record Type, field string, expr Expr, tenv *TypeEnv) (Type, error) {
+	ft, err := c.fieldType(record, field)
+	if err != nil {
+		return nil, err
+	}
+	val, err := c.TypeOf(expr, tenv)
+	if err != nil {
+		return nil, err
+	}
+	return ft, c.Unify(ft, val)
+}
+
+// fieldType returns the type of a field of a record type.  A record whose
+// fields are not all known yet is unified with an open record with the
+// field.
+func (c *TypeChecker) fieldType(record Type, field string) (Type, error) {
+	if r, ok := c.Subst.Resolve(record).(*RecordType); ok {
+		fields, rest := c.Subst.flatten(r).row()
+		if t, ok := fields[field]; ok {
+			return t, nil
+		}
+		if rest == nil {
+			return nil, FieldError{Field: field, Type: c.Subst.Apply(record)}
+		}
+	}
+	t := c.Fresh()
+	return t, c.Unify(RecordOf(map[string]Type{field: t}, c.Fresh()), record)
+}
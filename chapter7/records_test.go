@@ -0,0 +1,57 @@
+package chapter7
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckedRecords(t *testing.T) {
+	point := Record(ExprDict("x", Lit(1), "y", Lit(true)))
+	pointType := RecordOf(map[string]Type{"x": Int, "y": Bool}, nil)
+	cases := []TypeTestCase{
+		{Name: "record", Expected: pointType, Expr: point},
+		{Name: "empty", Expected: RecordOf(map[string]Type{}, nil), Expr: Record(nil)},
+		{Name: "field", Expected: Bool, Expr: Field(point, "y")},
+		{Name: "missing field", Expected: FieldError{Field: "z", Type: pointType}, Expr: Field(point, "z")},
+		{Name: "field of non record", Expected: TypeError{Expected: RecordOf(map[string]Type{"x": &TypeVar{ID: 1}}, &TypeVar{ID: 2}), Found: Int},
+			Expr: Field(1, "x")},
+		{Name: "update", Expected: pointType, Expr: Update(point, ExprDict("x", Lit(5)))},
+		{Name: "update type", Expected: TypeError{Expected: Int, Found: Bool}, Expr: Update(point, ExprDict("x", Lit(false)))},
+		{Name: "update missing field", Expected: FieldError{Field: "z", Type: pointType}, Expr: Update(point, ExprDict("z", Lit(5)))},
+		// let p = {x = 1, y = true} in begin set p.x = 2; p.x end
+		{Name: "set field", Expected: Int, Expr: Let(ExprDict("p", point), Begin(SetField("p", "x", 2), Field("p", "x")))},
+		{Name: "set field type", Expected: TypeError{Expected: Bool, Found: Int}, Expr: SetField(point, "y", 2)},
+		// proc (p : {x : int, y : bool}) if p.y then p.x else 0
+		{Name: "record param", Expected: Func([]Type{pointType}, Int),
+			Expr: TypedProc([]string{"p"}, []Type{pointType}, nil, If(Field("p", "y"), Field("p", "x"), 0))},
+		{Name: "record arg", Expected: TypeError{Expected: pointType, Found: RecordOf(map[string]Type{"x": Int}, nil)},
+			Expr: Call(TypedProc([]string{"p"}, []Type{pointType}, nil, Field("p", "x")), Record(ExprDict("x", Lit(1))))},
+	}
+	for _, tc := range cases {
+		RunTypeTest(t, &tc)
+	}
+}
+
+func TestInferRecords(t *testing.T) {
+	getX := Proc([]string{"r"}, Field("r", "x"))
+	RunInferTest(t, "field", getX, "({x : t2, ..t3} -> t2)")
+	// proc (r) +(r.x, r.y) needs both fields
+	RunInferTest(t, "two fields", Proc([]string{"r"}, Op("+", Field("r", "x"), Field("r", "y"))), "({x : int, y : int, ..t6} -> int)")
+	RunInferTest(t, "update", Proc([]string{"r"}, Update("r", ExprDict("x", Lit(1)))), "({x : int, ..t3} -> {x : int, ..t3})")
+	RunInferTest(t, "set field", Proc([]string{"r", "v"}, SetField("r", "x", "v")), "({x : t2, ..t4} * t2 -> t2)")
+	RunInferTest(t, "missing field", Call(getX, Record(ExprDict("y", Lit(1)))),
+		TypeError{Expected: RecordOf(map[string]Type{"x": &TypeVar{ID: 2}}, &TypeVar{ID: 3}), Found: RecordOf(map[string]Type{"y": Int}, nil)})
+
+	// The same procedure applies to records with different fields
+	RunPolyTest(t, "row polymorphism", Let(ExprDict("getx", getX),
+		Tuple(Call("getx", Record(ExprDict("x", Lit(1)))), Call("getx", Record(ExprDict("x", Lit(true), "y", Lit("a")))))),
+		"(int, bool)")
+	RunPolyTest(t, "poly field", getX, "forall a b. ({x : a, ..b} -> a)")
+
+	assert.Equal(t, "{}", RecordOf(nil, nil).String())
+	assert.Equal(t, "{x : int, y : list bool}", RecordOf(map[string]Type{"y": ListOf(Bool), "x": Int}, nil).String())
+	assert.True(t, RecordOf(map[string]Type{"x": Int}, nil).Eq(RecordOf(map[string]Type{"x": Int}, nil)))
+	assert.False(t, RecordOf(map[string]Type{"x": Int}, nil).Eq(RecordOf(map[string]Type{"x": Int}, &TypeVar{ID: 1})))
+	assert.False(t, RecordOf(map[string]Type{"x": Int}, nil).Eq(Union(map[string]Type{"x": Int}, nil)))
+}
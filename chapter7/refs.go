@@ -0,0 +1,103 @@
+package chapter7
+
+// Typing rules for the chapter4 expressions - references, assignment, blocks
+// and laziness.  Like the rest of the checker, types are matched with Unify
+// so the same rules work when inferring.
+
+// 'newref(e)' is a 'refto T' where e is a T, and 'ref x' is a 'refto T'
+// where x is a T.
+func (c *TypeChecker) TypeOfRef(e *RefExpr, tenv *TypeEnv) (Type, error) {
+	if !e.IsVarRef {
+		t, err := c.TypeOf(e.ExprOrVar.(Expr), tenv)
+		if err != nil {
+			return nil, err
+		}
+		return RefTo(t), nil
+	}
+	t, err := c.mutableVar(e.ExprOrVar.(string), tenv)
+	if err != nil {
+		return nil, err
+	}
+	return RefTo(t), nil
+}
+
+func (c *TypeChecker) TypeOfDeRef(e *DeRefExpr, tenv *TypeEnv) (Type, error) {
+	ref, err := c.TypeOf(e.RefExpr, tenv)
+	if err != nil {
+		return nil, err
+	}
+	return c.taggedElem("refto", ref)
+}
+
+// 'setref(r, v)' needs v to be of the type r refers to and evaluates to v.
+func (c *TypeChecker) TypeOfSetRef(e *SetRefExpr, tenv *TypeEnv) (Type, error) {
+	ref, err := c.TypeOf(e.RefExpr, tenv)
+	if err != nil {
+		return nil, err
+	}
+	elem, err := c.taggedElem("refto", ref)
+	if err != nil {
+		return nil, err
+	}
+	val, err := c.TypeOf(e.ValueExpr, tenv)
+	if err != nil {
+		return nil, err
+	}
+	return elem, c.Unify(elem, val)
+}
+
+// A block has the type of its last expression, or int if it is empty as it
+// then evaluates to 0.
+func (c *TypeChecker) TypeOfBlock(e *BlockExpr, tenv *TypeEnv) (Type, error) {
+	types, err := c.TypeOfExprList(e.Exprs, tenv)
+	if err != nil {
+		return nil, err
+	}
+	if len(types) == 0 {
+		return Int, nil
+	}
+	return types[len(types)-1], nil
+}
+
+// 'set x = e' needs e to be of x's type and evaluates to e.
+func (c *TypeChecker) TypeOfAssign(e *AssignExpr, tenv *TypeEnv) (Type, error) {
+	t, err := c.mutableVar(e.Varname, tenv)
+	if err != nil {
+		return nil, err
+	}
+	val, err := c.TypeOf(e.Expr, tenv)
+	if err != nil {
+		return nil, err
+	}
+	return t, c.Unify(t, val)
+}
+
+// mutableVar returns the type of a variable that is assigned to or
+// referenced.  A variable with a Scheme could be changed to a value of just
+// one of its instances, so it cannot be.
+func (c *TypeChecker) mutableVar(name string, tenv *TypeEnv) (Type, error) {
+	t, found := tenv.Get(name)
+	if !found {
+		return nil, UnboundVariableError{Name: name}
+	}
+	if _, ok := t.(*Scheme); ok {
+		return nil, PolymorphicMutationError{Name: name}
+	}
+	return t, nil
+}
+
+func (c *TypeChecker) TypeOfLazy(e *LazyExpr, tenv *TypeEnv) (Type, error) {
+	t, err := c.TypeOf(e.Expr, tenv)
+	if err != nil {
+		return nil, err
+	}
+	return LazyOf(t), nil
+}
+
+func (c *TypeChecker) TypeOfThunk(e *ThunkExpr, tenv *TypeEnv) (Type, error) {
+	t, err := c.TypeOf(e.Expr, tenv)
+	if err != nil {
+		return nil, err
+	}
+	return c.taggedElem("lazy", t)
+}
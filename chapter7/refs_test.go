@@ -0,0 +1,104 @@
+package chapter7
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckedRefs(t *testing.T) {
+	// let r = newref(0) in begin setref(r, 5); deref(r) end
+	counter := func(val any) Expr {
+		return Let(ExprDict("r", NewRef(0)), Begin(SetRef("r", val), DeRef("r")))
+	}
+	cases := []TypeTestCase{
+		{Name: "newref", Expected: RefTo(Int), Expr: NewRef(1)},
+		{Name: "nested", Expected: RefTo(RefTo(Bool)), Expr: NewRef(NewRef(true))},
+		{Name: "counter", Expected: Int, Expr: counter(5)},
+		{Name: "setref type", Expected: TypeError{Expected: Int, Found: Bool}, Expr: counter(true)},
+		{Name: "setref result", Expected: String, Expr: SetRef(NewRef(Lit("a")), Lit("b"))},
+		{Name: "deref non ref", Expected: TypeError{Expected: RefTo(nil), Found: Int}, Expr: DeRef(1)},
+		{Name: "setref non ref", Expected: TypeError{Expected: RefTo(nil), Found: Bool}, Expr: SetRef(true, 1)},
+		// let x = 1 in deref(ref x)
+		{Name: "ref var", Expected: Int, Expr: Let(ExprDict("x", Lit(1)), DeRef(RefVar("x")))},
+		{Name: "ref unbound", Expected: UnboundVariableError{Name: "x"}, Expr: RefVar("x")},
+		// A procedure taking a reference - proc (r : refto int) setref(r, -(deref(r), 1))
+		{Name: "ref param", Expected: Func([]Type{RefTo(Int)}, Int),
+			Expr: TypedProc([]string{"r"}, []Type{RefTo(Int)}, nil, SetRef("r", Op("-", DeRef("r"), 1)))},
+	}
+	for _, tc := range cases {
+		RunTypeTest(t, &tc)
+	}
+}
+
+func TestCheckedAssignment(t *testing.T) {
+	cases := []TypeTestCase{
+		// let x = 1 in begin set x = 2; x end
+		{Name: "assign", Expected: Int, Expr: Let(ExprDict("x", Lit(1)), Begin(Assign("x", 2), "x"))},
+		{Name: "assign type", Expected: TypeError{Expected: Int, Found: Bool},
+			Expr: Let(ExprDict("x", Lit(1)), Assign("x", true))},
+		{Name: "assign unbound", Expected: UnboundVariableError{Name: "x"}, Expr: Assign("x", 1)},
+		{Name: "block", Expected: Bool, Expr: Begin(1, Lit("a"), true)},
+		{Name: "empty block", Expected: Int, Expr: Begin()},
+		{Name: "block error", Expected: TypeError{Expected: Bool, Found: Int}, Expr: Begin(If(1, 2, 3), true)},
+	}
+	for _, tc := range cases {
+		RunTypeTest(t, &tc)
+	}
+}
+
+func TestCheckedLaziness(t *testing.T) {
+	cases := []TypeTestCase{
+		{Name: "lazy", Expected: LazyOf(Int), Expr: Lazy(Op("-", 1, 2))},
+		{Name: "thunk", Expected: Int, Expr: ForceThunk(Lazy(Op("-", 1, 2)))},
+		{Name: "lazy body", Expected: TypeError{Expected: Int, Found: Bool}, Expr: Lazy(Op("-", 1, true))},
+		{Name: "thunk non lazy", Expected: TypeError{Expected: LazyOf(nil), Found: Int}, Expr: ForceThunk(1)},
+		// proc (l : lazy bool) if thunk l then 1 else 2
+		{Name: "lazy param", Expected: Func([]Type{LazyOf(Bool)}, Int),
+			Expr: TypedProc([]string{"l"}, []Type{LazyOf(Bool)}, nil, If(ForceThunk("l"), 1, 2))},
+	}
+	for _, tc := range cases {
+		RunTypeTest(t, &tc)
+	}
+}
+
+func TestInferRefs(t *testing.T) {
+	// proc (r) setref(r, isz(deref(r)))
+	RunInferTest(t, "set ref param", Proc([]string{"r"}, SetRef("r", IsZero(DeRef("r")))),
+		TypeError{Expected: Int, Found: Bool})
+	RunInferTest(t, "deref param", Proc([]string{"r"}, Op("-", DeRef("r"), 1)), "(refto int -> int)")
+	RunInferTest(t, "thunk param", Proc([]string{"l"}, ForceThunk("l")), "(lazy t2 -> t2)")
+	// proc (x) begin set x = 1; x end
+	RunInferTest(t, "assign param", Proc([]string{"x"}, Begin(Assign("x", 1), "x")), "(int -> int)")
+}
+
+func TestValueRestriction(t *testing.T) {
+	// let r = newref(proc (x) x)
+	// in begin setref(r, proc (x) -(x, 1)); (deref(r) true) end
+	RunPolyTest(t, "polymorphic ref",
+		Let(ExprDict("r", NewRef(identity)),
+			Begin(SetRef("r", Proc([]string{"x"}, Op("-", "x", 1))), Call(DeRef("r"), true))),
+		TypeError{Expected: Int, Found: Bool})
+
+	// Without a setref the ref is still monomorphic -
+	// let r = newref(proc (x) x) in ((deref(r) 1), (deref(r) true))
+	RunPolyTest(t, "expansive",
+		Let(ExprDict("r", NewRef(identity)), Tuple(Call(DeRef("r"), 1), Call(DeRef("r"), true))),
+		TypeError{Expected: Int, Found: Bool})
+
+	// let id = proc (x) x in begin set id = proc (x) -(x, 1); (id true) end
+	RunPolyTest(t, "assign polymorphic",
+		Let(ExprDict("id", identity), Begin(Assign("id", Proc([]string{"x"}, Op("-", "x", 1))), Call("id", true))),
+		PolymorphicMutationError{Name: "id"})
+	RunPolyTest(t, "ref polymorphic", Let(ExprDict("id", identity), RefVar("id")), PolymorphicMutationError{Name: "id"})
+
+	// Values are still generalized - let p = (proc (x) x, 1) in ...
+	RunPolyTest(t, "tuple value", Let(ExprDict("p", Tuple(identity, Lit(1))), Var("p")), "forall a. ((a -> a), int)")
+	// A ref of a monomorphic variable is fine
+	RunPolyTest(t, "ref monomorphic", Let(ExprDict("x", Lit(1)), DeRef(RefVar("x"))), "int")
+
+	assert.True(t, IsValue(List(1, identity)))
+	assert.False(t, IsValue(Tuple(Lit(1), NewRef(1))))
+	assert.False(t, IsValue(Lazy(1)))
+	assert.EqualError(t, PolymorphicMutationError{Name: "id"}, "'id' has a polymorphic type and cannot be assigned to")
+}
@@ -0,0 +1,38 @@
+package chapter7
+
+import (
+	"testing"
+)
+
+func TestCheckedTuples(t *testing.T) {
+	pair := Tuple(AnyToExpr(1), Lit(true))
+	cases := []TypeTestCase{
+		// unpack x y = (1, true) in if y then x else 0
+		{Name: "unpack", Expected: Int, Expr: Unpack([]string{"x", "y"}, pair, If("y", "x", 0))},
+		{Name: "unpack list", Expected: TupleOf(Int, Int), Expr: Unpack([]string{"x", "y"}, List(1, 2), Tuple(Var("y"), Var("x")))},
+		{Name: "unpack size", Expected: TypeError{Expected: TupleOf(&TypeVar{ID: 1}, &TypeVar{ID: 2}, &TypeVar{ID: 3}), Found: TupleOf(Int, Bool)},
+			Expr: Unpack([]string{"x", "y", "z"}, pair, "x")},
+		{Name: "unpack not a tuple", Expected: TypeError{Expected: TupleOf(&TypeVar{ID: 1}), Found: Int},
+			Expr: Unpack([]string{"x"}, 1, "x")},
+		{Name: "index", Expected: Bool, Expr: TupleRef(pair, 1)},
+		{Name: "index out of range", Expected: TupleIndexError{Index: 2, Type: TupleOf(Int, Bool)}, Expr: TupleRef(pair, 2)},
+		{Name: "index not a tuple", Expected: TupleIndexError{Index: 0, Type: ListOf(Int)}, Expr: TupleRef(List(1), 0)},
+		// proc (p : (int, int)) equal?(p, (p.1, p.0))
+		{Name: "equality", Expected: Func([]Type{TupleOf(Int, Int)}, Bool),
+			Expr: TypedProc([]string{"p"}, []Type{TupleOf(Int, Int)}, nil, Op("equal?", "p", Tuple(TupleRef("p", 1), TupleRef("p", 0))))},
+		{Name: "equality types", Expected: TypeError{Expected: Bool, Found: Int},
+			Expr: Op("equal?", pair, Tuple(AnyToExpr(1), AnyToExpr(2)))},
+	}
+	for _, tc := range cases {
+		RunTypeTest(t, &tc)
+	}
+}
+
+func TestInferTuples(t *testing.T) {
+	// proc (p) unpack x y = p in (y x)
+	RunInferTest(t, "unpack", Proc([]string{"p"}, Unpack([]string{"x", "y"}, "p", Call("y", "x"))), "((t2, (t2 -> t4)) -> t4)")
+	// Indexing needs the size of the tuple to be known
+	RunInferTest(t, "index unknown", Proc([]string{"p"}, TupleRef("p", 0)), TupleIndexError{Index: 0, Type: &TypeVar{ID: 1}})
+	RunInferTest(t, "index after unpack", Proc([]string{"p"}, Unpack([]string{"x", "y"}, "p", Op("+", TupleRef("p", 1), "x"))),
+		"((int, int) -> int)")
+}
@@ -0,0 +1,346 @@
+package chapter7
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	epl "github.com/panyam/eplgo"
+	gfn "github.com/panyam/goutils/fn"
+)
+
+// Type is the static type of an expression.  These are the variants of the
+// Type union in typed.py - leaf types (int, bool, string), tuples, functions,
+// tagged types like 'list int', unions of tagged values, datatypes and records.
+type Type interface {
+	String() string
+	Eq(another Type) bool
+}
+
+// TypeEq compares two types where either may be nil (ie unknown).
+func TypeEq(a, b Type) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Eq(b)
+}
+
+// typeString prints a type that may be nil (ie unknown) as "?".
+func typeString(t Type) string {
+	if t == nil {
+		return "?"
+	}
+	return t.String()
+}
+
+func typeListEq(a, b []Type) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, t := range a {
+		if !TypeEq(t, b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// IntType is 'int'.
+type IntType struct{}
+
+func (t IntType) String() string { return "int" }
+
+func (t IntType) Eq(another Type) bool {
+	_, ok := another.(IntType)
+	return ok
+}
+
+// BoolType is 'bool'.
+type BoolType struct{}
+
+func (t BoolType) String() string { return "bool" }
+
+func (t BoolType) Eq(another Type) bool {
+	_, ok := another.(BoolType)
+	return ok
+}
+
+// StringType is 'string'.
+type StringType struct{}
+
+func (t StringType) String() string { return "string" }
+
+func (t StringType) Eq(another Type) bool {
+	_, ok := another.(StringType)
+	return ok
+}
+
+// The leaf types
+var (
+	Int    Type = IntType{}
+	Bool   Type = BoolType{}
+	String Type = StringType{}
+)
+
+// FuncType is the type of procedures taking Args and returning Result,
+// written '(t1 * t2 ... -> result)'.
+type FuncType struct {
+	Args   []Type
+	Result Type
+}
+
+// Func is a constructor for FuncType.
+func Func(args []Type, result Type) *FuncType {
+	return &FuncType{Args: args, Result: result}
+}
+
+func (t *FuncType) String() string {
+	args := gfn.Map(t.Args, typeString)
+	if len(args) == 0 {
+		return fmt.Sprintf("(-> %s)", typeString(t.Result))
+	}
+	return fmt.Sprintf("(%s -> %s)", strings.Join(args, " * "), typeString(t.Result))
+}
+
+func (t *FuncType) Eq(another Type) bool {
+	a, ok := another.(*FuncType)
+	return ok && typeListEq(t.Args, a.Args) && TypeEq(t.Result, a.Result)
+}
+
+// TupleType is the type of tuples, written '(t1, t2, ...)'.
+type TupleType struct {
+	Children []Type
+}
+
+// TupleOf is a constructor for TupleType.
+func TupleOf(children ...Type) *TupleType {
+	return &TupleType{Children: children}
+}
+
+func (t *TupleType) String() string {
+	return "(" + strings.Join(gfn.Map(t.Children, typeString), ", ") + ")"
+}
+
+func (t *TupleType) Eq(another Type) bool {
+	a, ok := another.(*TupleType)
+	return ok && typeListEq(t.Children, a.Children)
+}
+
+// TaggedType is a type constructor applied to another type, eg 'list int'.
+type TaggedType struct {
+	Name string
+	Type Type
+}
+
+// Tagged is a constructor for TaggedType.
+func Tagged(name string, t Type) *TaggedType {
+	return &TaggedType{Name: name, Type: t}
+}
+
+// ListOf is the type of lists of t.
+func ListOf(t Type) *TaggedType {
+	return Tagged("list", t)
+}
+
+// RefTo is the type of references to values of t, made with newref.
+func RefTo(t Type) *TaggedType {
+	return Tagged("refto", t)
+}
+
+// LazyOf is the type of lazy expressions evaluating to values of t.
+func LazyOf(t Type) *TaggedType {
+	return Tagged("lazy", t)
+}
+
+func (t *TaggedType) String() string {
+	if _, nested := t.Type.(*TaggedType); nested {
+		return fmt.Sprintf("%s (%s)", t.Name, t.Type)
+	}
+	return fmt.Sprintf("%s %s", t.Name, typeString(t.Type))
+}
+
+func (t *TaggedType) Eq(another Type) bool {
+	a, ok := another.(*TaggedType)
+	return ok && t.Name == a.Name && TypeEq(t.Type, a.Type)
+}
+
+// DataType is the type of the values of a datatype declared with
+// define-datatype, applied to the types for its parameters, eg 'tree' or
+// 'option int'.  In a declaration a parameter of the datatype is a DataType
+// with its name and no Args.
+type DataType struct {
+	Name string
+	Args []Type
+}
+
+// Data is a constructor for DataType.
+func Data(name string, args ...Type) *DataType {
+	return &DataType{Name: name, Args: args}
+}
+
+func (t *DataType) String() string {
+	out := t.Name
+	for _, arg := range t.Args {
+		switch a := arg.(type) {
+		case *TaggedType:
+			out += " (" + a.String() + ")"
+		case *DataType:
+			if len(a.Args) > 0 {
+				out += " (" + a.String() + ")"
+			} else {
+				out += " " + a.String()
+			}
+		default:
+			out += " " + typeString(arg)
+		}
+	}
+	return out
+}
+
+func (t *DataType) Eq(another Type) bool {
+	a, ok := another.(*DataType)
+	return ok && t.Name == a.Name && typeListEq(t.Args, a.Args)
+}
+
+// UnionType is the type of tagged values, written '[Circle int | Square int]',
+// like UnionType in typed.py.  Each option is a tag with the type of the
+// values it is applied to.  An open union, like that of 'tag Circle 5', also
+// has a Rest - a type variable standing for any other options - and is
+// written '[Circle int | ..t1]'.  A closed union has a nil Rest.
+type UnionType struct {
+	Options map[string]Type
+	Rest    Type
+}
+
+// Union is a constructor for UnionType.
+func Union(options map[string]Type, rest Type) *UnionType {
+	return &UnionType{Options: options, Rest: rest}
+}
+
+func (t *UnionType) String() string {
+	options := gfn.Map(epl.SortedKeys(t.Options), func(tag string) string { return Tagged(tag, t.Options[tag]).String() })
+	if t.Rest != nil {
+		options = append(options, ".."+t.Rest.String())
+	}
+	return "[" + strings.Join(options, " | ") + "]"
+}
+
+func (t *UnionType) Eq(another Type) bool {
+	a, ok := another.(*UnionType)
+	return ok && rowEq(t, a)
+}
+
+// RecordType is the type of records, written '{x : int, y : bool}'.  Like a
+// union it is a row - the type of 'proc (r) r.x' takes an open record,
+// '{x : t1, ..t2}', whose Rest stands for any other fields.
+type RecordType struct {
+	Fields map[string]Type
+	Rest   Type
+}
+
+// RecordOf is a constructor for RecordType.
+func RecordOf(fields map[string]Type, rest Type) *RecordType {
+	return &RecordType{Fields: fields, Rest: rest}
+}
+
+func (t *RecordType) String() string {
+	fields := gfn.Map(epl.SortedKeys(t.Fields), func(name string) string { return name + " : " + t.Fields[name].String() })
+	if t.Rest != nil {
+		fields = append(fields, ".."+t.Rest.String())
+	}
+	return "{" + strings.Join(fields, ", ") + "}"
+}
+
+func (t *RecordType) Eq(another Type) bool {
+	a, ok := another.(*RecordType)
+	return ok && rowEq(t, a)
+}
+
+// rowType is a union or record type - labels with types and, if open, a Rest
+// for the labels not known yet.  They are unified the same way.
+type rowType interface {
+	Type
+	row() (labels map[string]Type, rest Type)
+	withRow(labels map[string]Type, rest Type) rowType
+}
+
+func (t *UnionType) row() (map[string]Type, Type) { return t.Options, t.Rest }
+
+func (t *UnionType) withRow(labels map[string]Type, rest Type) rowType {
+	return Union(labels, rest)
+}
+
+func (t *RecordType) row() (map[string]Type, Type) { return t.Fields, t.Rest }
+
+func (t *RecordType) withRow(labels map[string]Type, rest Type) rowType {
+	return RecordOf(labels, rest)
+}
+
+func rowEq(a, b rowType) bool {
+	aLabels, aRest := a.row()
+	bLabels, bRest := b.row()
+	if len(aLabels) != len(bLabels) || !TypeEq(aRest, bRest) {
+		return false
+	}
+	for label, t := range aLabels {
+		if other, ok := bLabels[label]; !ok || !TypeEq(t, other) {
+			return false
+		}
+	}
+	return true
+}
+
+// TypeVar is an unknown type to be solved for by inference.
+type TypeVar struct {
+	ID int
+
+	// The name to print the variable with, eg 'a' when quantified in a
+	// Scheme.  Variables with the same ID are the same whatever their names.
+	Name string
+}
+
+func (t *TypeVar) String() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return fmt.Sprintf("t%d", t.ID)
+}
+
+func (t *TypeVar) Eq(another Type) bool {
+	a, ok := another.(*TypeVar)
+	return ok && t.ID == a.ID
+}
+
+// Scheme is a polymorphic type - Type with the variables in Vars
+// universally quantified, written 'forall a b. (a -> b)'.  Each use of a name
+// with a Scheme type gets a fresh copy of its variables.
+type Scheme struct {
+	Vars []*TypeVar
+	Type Type
+}
+
+func (t *Scheme) String() string {
+	names := map[int]Type{}
+	vars := make([]string, len(t.Vars))
+	for i, v := range t.Vars {
+		vars[i] = schemeVarName(i)
+		names[v.ID] = &TypeVar{ID: v.ID, Name: vars[i]}
+	}
+	return fmt.Sprintf("forall %s. %s", strings.Join(vars, " "), replaceVars(t.Type, names))
+}
+
+// schemeVarName names the i'th quantified variable of a Scheme - a to z, then
+// a1 to z1 and so on.
+func schemeVarName(i int) string {
+	name := string(rune('a' + i%26))
+	if i >= 26 {
+		name += strconv.Itoa(i / 26)
+	}
+	return name
+}
+
+// Schemes are the same if they are the same up to renaming their variables.
+func (t *Scheme) Eq(another Type) bool {
+	a, ok := another.(*Scheme)
+	return ok && len(t.Vars) == len(a.Vars) && t.String() == a.String()
+}
@@ -0,0 +1,295 @@
+package chapter7
+
+import epl "github.com/panyam/eplgo"
+
+// Substitution maps the IDs of type variables to the types found for them,
+// like Substitutions in inferred.py.  Bindings may refer to other type
+// variables so use Apply to fully resolve a type.
+type Substitution map[int]Type
+
+// Resolve follows the bindings of a type variable until it reaches a type
+// that is not a bound variable.
+func (s Substitution) Resolve(t Type) Type {
+	for {
+		tv, ok := t.(*TypeVar)
+		if !ok {
+			return t
+		}
+		bound, ok := s[tv.ID]
+		if !ok {
+			return t
+		}
+		t = bound
+	}
+}
+
+// Apply replaces all the bound type variables in a type.
+func (s Substitution) Apply(t Type) Type {
+	switch t := s.Resolve(t).(type) {
+	case *FuncType:
+		args := make([]Type, len(t.Args))
+		for i, arg := range t.Args {
+			args[i] = s.Apply(arg)
+		}
+		return Func(args, s.Apply(t.Result))
+	case *TupleType:
+		children := make([]Type, len(t.Children))
+		for i, child := range t.Children {
+			children[i] = s.Apply(child)
+		}
+		return TupleOf(children...)
+	case *TaggedType:
+		return Tagged(t.Name, s.Apply(t.Type))
+	case rowType:
+		labels, rest := s.flatten(t).row()
+		applied := map[string]Type{}
+		for label, lt := range labels {
+			applied[label] = s.Apply(lt)
+		}
+		return t.withRow(applied, rest)
+	case *DataType:
+		args := make([]Type, len(t.Args))
+		for i, arg := range t.Args {
+			args[i] = s.Apply(arg)
+		}
+		return Data(t.Name, args...)
+	case *Scheme:
+		// The quantified variables are never bound
+		return &Scheme{Vars: t.Vars, Type: s.Apply(t.Type)}
+	default:
+		return t
+	}
+}
+
+// replaceVars replaces type variables with the types they map to.  Unlike a
+// Substitution the replacements are not themselves substituted, so a variable
+// can be renamed to one with the same ID or swapped with another.
+func replaceVars(t Type, vars map[int]Type) Type {
+	switch t := t.(type) {
+	case *TypeVar:
+		if replacement, ok := vars[t.ID]; ok {
+			return replacement
+		}
+	case *FuncType:
+		args := make([]Type, len(t.Args))
+		for i, arg := range t.Args {
+			args[i] = replaceVars(arg, vars)
+		}
+		return Func(args, replaceVars(t.Result, vars))
+	case *TupleType:
+		children := make([]Type, len(t.Children))
+		for i, child := range t.Children {
+			children[i] = replaceVars(child, vars)
+		}
+		return TupleOf(children...)
+	case *TaggedType:
+		return Tagged(t.Name, replaceVars(t.Type, vars))
+	case rowType:
+		labels, rest := t.row()
+		replaced := map[string]Type{}
+		for label, lt := range labels {
+			replaced[label] = replaceVars(lt, vars)
+		}
+		if rest != nil {
+			rest = replaceVars(rest, vars)
+		}
+		return t.withRow(replaced, rest)
+	case *DataType:
+		args := make([]Type, len(t.Args))
+		for i, arg := range t.Args {
+			args[i] = replaceVars(arg, vars)
+		}
+		return Data(t.Name, args...)
+	}
+	return t
+}
+
+// flatten merges the labels of the rows a row's Rest is bound to into it, so
+// its Rest is nil or an unbound type variable.
+func (s Substitution) flatten(r rowType) rowType {
+	labels, rest := r.row()
+	restRow, ok := s.Resolve(rest).(rowType)
+	if !ok {
+		return r.withRow(labels, s.Resolve(rest))
+	}
+	restLabels, restRest := s.flatten(restRow).row()
+	merged := map[string]Type{}
+	for label, t := range labels {
+		merged[label] = t
+	}
+	for label, t := range restLabels {
+		merged[label] = t
+	}
+	return r.withRow(merged, restRest)
+}
+
+// occurs checks whether a type variable appears in a type.
+func (s Substitution) occurs(tv *TypeVar, t Type) bool {
+	switch t := s.Resolve(t).(type) {
+	case *TypeVar:
+		return t.ID == tv.ID
+	case *FuncType:
+		for _, arg := range t.Args {
+			if s.occurs(tv, arg) {
+				return true
+			}
+		}
+		return s.occurs(tv, t.Result)
+	case *TupleType:
+		for _, child := range t.Children {
+			if s.occurs(tv, child) {
+				return true
+			}
+		}
+	case *TaggedType:
+		return s.occurs(tv, t.Type)
+	case rowType:
+		labels, rest := t.row()
+		for _, lt := range labels {
+			if s.occurs(tv, lt) {
+				return true
+			}
+		}
+		return rest != nil && s.occurs(tv, rest)
+	case *DataType:
+		for _, arg := range t.Args {
+			if s.occurs(tv, arg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s Substitution) bind(tv *TypeVar, t Type) error {
+	if other, ok := t.(*TypeVar); ok && other.ID == tv.ID {
+		return nil
+	}
+	if s.occurs(tv, t) {
+		return OccursError{Var: tv, Type: s.Apply(t)}
+	}
+	s[tv.ID] = t
+	return nil
+}
+
+// Unify extends the substitution so the two types are the same, returning a
+// TypeError if they cannot be.  fresh returns the new type variables needed
+// for the Rest of unions and records.
+func (s Substitution) Unify(expected, found Type, fresh func() *TypeVar) error {
+	expected, found = s.Resolve(expected), s.Resolve(found)
+	if tv, ok := expected.(*TypeVar); ok {
+		return s.bind(tv, found)
+	}
+	if tv, ok := found.(*TypeVar); ok {
+		return s.bind(tv, expected)
+	}
+	mismatch := func() error {
+		return TypeError{Expected: s.Apply(expected), Found: s.Apply(found)}
+	}
+	switch e := expected.(type) {
+	case *FuncType:
+		f, ok := found.(*FuncType)
+		if !ok || len(e.Args) != len(f.Args) {
+			return mismatch()
+		}
+		for i, arg := range e.Args {
+			if err := s.Unify(arg, f.Args[i], fresh); err != nil {
+				return err
+			}
+		}
+		return s.Unify(e.Result, f.Result, fresh)
+	case *TupleType:
+		f, ok := found.(*TupleType)
+		if !ok || len(e.Children) != len(f.Children) {
+			return mismatch()
+		}
+		for i, child := range e.Children {
+			if err := s.Unify(child, f.Children[i], fresh); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *TaggedType:
+		f, ok := found.(*TaggedType)
+		if !ok || e.Name != f.Name {
+			return mismatch()
+		}
+		return s.Unify(e.Type, f.Type, fresh)
+	case *DataType:
+		f, ok := found.(*DataType)
+		if !ok || e.Name != f.Name || len(e.Args) != len(f.Args) {
+			return mismatch()
+		}
+		for i, arg := range e.Args {
+			if err := s.Unify(arg, f.Args[i], fresh); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *UnionType:
+		f, ok := found.(*UnionType)
+		if !ok {
+			return mismatch()
+		}
+		return s.unifyRows(s.flatten(e), s.flatten(f), fresh, mismatch)
+	case *RecordType:
+		f, ok := found.(*RecordType)
+		if !ok {
+			return mismatch()
+		}
+		return s.unifyRows(s.flatten(e), s.flatten(f), fresh, mismatch)
+	}
+	if !TypeEq(expected, found) {
+		return mismatch()
+	}
+	return nil
+}
+
+// unifyRows unifies the labels two flattened rows - unions or records - have
+// in common.  The labels only one of them has must be in the Rest of the
+// other, so each Rest is bound to the other's extra labels over a new, shared,
+// Rest.
+func (s Substitution) unifyRows(e, f rowType, fresh func() *TypeVar, mismatch func() error) error {
+	eLabels, eRestType := e.row()
+	fLabels, fRestType := f.row()
+	onlyE, onlyF := map[string]Type{}, map[string]Type{}
+	for _, label := range epl.SortedKeys(eLabels) {
+		if t, ok := fLabels[label]; !ok {
+			onlyE[label] = eLabels[label]
+		} else if err := s.Unify(eLabels[label], t, fresh); err != nil {
+			return err
+		}
+	}
+	for label, t := range fLabels {
+		if _, ok := eLabels[label]; !ok {
+			onlyF[label] = t
+		}
+	}
+	eRest, _ := eRestType.(*TypeVar)
+	fRest, _ := fRestType.(*TypeVar)
+	if (len(onlyF) > 0 && eRest == nil) || (len(onlyE) > 0 && fRest == nil) {
+		return mismatch()
+	}
+	switch {
+	case eRest == nil && fRest == nil:
+		return nil
+	case eRest == nil:
+		return s.bind(fRest, e.withRow(onlyE, nil))
+	case fRest == nil:
+		return s.bind(eRest, e.withRow(onlyF, nil))
+	case eRest.ID == fRest.ID:
+		if len(onlyE) > 0 || len(onlyF) > 0 {
+			return mismatch()
+		}
+		return nil
+	case len(onlyE) == 0:
+		return s.bind(eRest, e.withRow(onlyF, fRest))
+	case len(onlyF) == 0:
+		return s.bind(fRest, e.withRow(onlyE, eRest))
+	}
+	rest := fresh()
+	if err := s.bind(eRest, e.withRow(onlyF, rest)); err != nil {
+		return err
+	}
+	return s.bind(fRest, e.withRow(onlyE, rest))
+}
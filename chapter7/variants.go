@@ -0,0 +1,298 @@
+package chapter7
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+	"github.com/panyam/eplgo/chapter5"
+	gfn "github.com/panyam/goutils/fn"
+)
+
+// TagExpr is 'tag Circle e' - the value of e tagged with the name of one of
+// the options of a union.
+type TagExpr struct {
+	Tag  string
+	Expr Expr
+}
+
+func Tag(tag string, expr any) *TagExpr {
+	return &TagExpr{Tag: tag, Expr: AnyToExpr(expr)}
+}
+
+func (e *TagExpr) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "Tag %s:", e.Tag)) {
+			return
+		}
+		cP := e.Expr.Printable()
+		cP.IndentLevel += 1
+		if !yield(cP) {
+			return
+		}
+	})
+}
+
+func (e *TagExpr) Repr() string {
+	return fmt.Sprintf("<Tag %s %s>", e.Tag, e.Expr.Repr())
+}
+
+func (e *TagExpr) Eq(another *TagExpr) bool {
+	return e.Tag == another.Tag && ExprEq(e.Expr, another.Expr)
+}
+
+// CaseArm is 'Circle (r) => body' in a cases expression - Body is evaluated
+// with Var bound to the value of a value tagged with Tag.
+type CaseArm struct {
+	Tag  string
+	Var  string
+	Body Expr
+}
+
+func Arm(tag string, varname string, body any) *CaseArm {
+	return &CaseArm{Tag: tag, Var: varname, Body: AnyToExpr(body)}
+}
+
+func (a *CaseArm) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "%s (%s) =>", a.Tag, a.Var)) {
+			return
+		}
+		bP := a.Body.Printable()
+		bP.IndentLevel += 1
+		if !yield(bP) {
+			return
+		}
+	})
+}
+
+func (a *CaseArm) Repr() string {
+	return fmt.Sprintf("%s(%s) => %s", a.Tag, a.Var, a.Body.Repr())
+}
+
+func (a *CaseArm) Eq(another *CaseArm) bool {
+	return a.Tag == another.Tag && a.Var == another.Var && ExprEq(a.Body, another.Body)
+}
+
+// CasesExpr is 'cases e of Circle (r) => ... Square (s) => ... else => ... end'.
+// It evaluates the arm for the tag of e's value, or Else (if not nil) when no
+// arm has the tag.
+type CasesExpr struct {
+	Expr Expr
+	Arms []*CaseArm
+	Else Expr
+}
+
+// Cases is a constructor for CasesExpr.  els may be nil for no else arm.
+func Cases(expr any, arms []*CaseArm, els any) *CasesExpr {
+	out := &CasesExpr{Expr: AnyToExpr(expr), Arms: arms}
+	if els != nil {
+		out.Else = AnyToExpr(els)
+	}
+	return out
+}
+
+func (e *CasesExpr) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "Cases:")) {
+			return
+		}
+		eP := e.Expr.Printable()
+		eP.IndentLevel += 1
+		if !yield(eP) {
+			return
+		}
+		for _, arm := range e.Arms {
+			aP := arm.Printable()
+			aP.IndentLevel += 1
+			if !yield(aP) {
+				return
+			}
+		}
+		if e.Else != nil {
+			if !yield(epl.Printablef(1, "else =>")) {
+				return
+			}
+			elseP := e.Else.Printable()
+			elseP.IndentLevel += 2
+			if !yield(elseP) {
+				return
+			}
+		}
+	})
+}
+
+func (e *CasesExpr) Repr() string {
+	arms := gfn.Map(e.Arms, (*CaseArm).Repr)
+	if e.Else != nil {
+		arms = append(arms, "else => "+e.Else.Repr())
+	}
+	return fmt.Sprintf("<Cases %s [%s]>", e.Expr.Repr(), strings.Join(arms, ", "))
+}
+
+func (e *CasesExpr) Eq(another *CasesExpr) bool {
+	return ExprEq(e.Expr, another.Expr) && ExprEq(e.Else, another.Else) &&
+		slices.EqualFunc(e.Arms, another.Arms, (*CaseArm).Eq)
+}
+
+// TaggedVal is the value of a TagExpr.
+type TaggedVal struct {
+	Tag   string
+	Value Value
+}
+
+func (v TaggedVal) String() string { return fmt.Sprintf("%s %s", v.Tag, v.Value) }
+func (v TaggedVal) Repr() string   { return fmt.Sprintf("Tagged(%s, %s)", v.Tag, v.Value.Repr()) }
+func (v TaggedVal) Eq(another Value) bool {
+	a, ok := another.(TaggedVal)
+	return ok && a.Tag == v.Tag && v.Value.Eq(a.Value)
+}
+
+// VariantLangEval extends TryLangEval with tagged values and cases.
+type VariantLangEval struct {
+	chapter5.TryLangEval
+}
+
+// NewVariantLangEval creates a new evaluator for the variants language.
+func NewVariantLangEval() *VariantLangEval {
+	out := &VariantLangEval{}
+	// CRITICAL: Set the Self pointer for the embedded BaseEval
+	out.BaseEval.Self = out
+	return out
+}
+
+// LocalEval handles tag and cases or delegates.
+func (l *VariantLangEval) LocalEval(expr Expr, env *epl.Env[any]) (any, error) {
+	switch n := expr.(type) {
+	case *TagExpr:
+		return l.ValueOfTag(n, env)
+	case *CasesExpr:
+		return l.ValueOfCases(n, env)
+	default:
+		return l.TryLangEval.LocalEval(expr, env)
+	}
+}
+
+func (l *VariantLangEval) ValueOfTag(e *TagExpr, env *epl.Env[any]) (any, error) {
+	val, err := l.Eval(e.Expr, env)
+	if err != nil {
+		return nil, err
+	}
+	v, err := chapter3.AsValue("tag "+e.Tag, val)
+	if err != nil {
+		return nil, err
+	}
+	return TaggedVal{Tag: e.Tag, Value: v}, nil
+}
+
+func (l *VariantLangEval) ValueOfCases(e *CasesExpr, env *epl.Env[any]) (any, error) {
+	val, err := l.Eval(e.Expr, env)
+	if err != nil {
+		return nil, err
+	}
+	tagged, ok := val.(TaggedVal)
+	if !ok {
+		return nil, chapter3.TypeMismatchError{Context: "cases", Expected: "a tagged value", Found: val}
+	}
+	for _, arm := range e.Arms {
+		if arm.Tag == tagged.Tag {
+			return l.Eval(arm.Body, env.Extend(epl.Dict[string, any](arm.Var, tagged.Value)))
+		}
+	}
+	if e.Else != nil {
+		return l.Eval(e.Else, env)
+	}
+	return nil, UnmatchedCaseError{Tag: tagged.Tag}
+}
+
+// 'tag C e' is in an open union with an option C for the type of e - it can
+// be used wherever a union with a C of that type is.
+func (c *TypeChecker) TypeOfTag(e *TagExpr, tenv *TypeEnv) (Type, error) {
+	t, err := c.TypeOf(e.Expr, tenv)
+	if err != nil {
+		return nil, err
+	}
+	return Union(map[string]Type{e.Tag: t}, c.Fresh()), nil
+}
+
+// The value a cases expression dispatches on must be a union of the tags of
+// its arms - closed unless there is an else arm - and all the arms must have
+// the same type.
+func (c *TypeChecker) TypeOfCases(e *CasesExpr, tenv *TypeEnv) (Type, error) {
+	t, err := c.TypeOf(e.Expr, tenv)
+	if err != nil {
+		return nil, err
+	}
+	options := map[string]Type{}
+	for _, arm := range e.Arms {
+		if _, ok := options[arm.Tag]; ok {
+			return nil, RedundantCaseError{Tag: arm.Tag}
+		}
+		options[arm.Tag] = c.Fresh()
+	}
+	var rest Type
+	if e.Else != nil {
+		rest = c.Fresh()
+	} else if err := c.checkExhaustive(e, t); err != nil {
+		return nil, err
+	}
+	if err := c.Unify(Union(options, rest), t); err != nil {
+		return nil, err
+	}
+
+	var result Type
+	addBody := func(body Expr, tenv *TypeEnv) error {
+		t, err := c.TypeOf(body, tenv)
+		if err != nil {
+			return err
+		}
+		if result == nil {
+			result = t
+			return nil
+		}
+		return c.Unify(result, t)
+	}
+	for _, arm := range e.Arms {
+		if err := addBody(arm.Body, tenv.Extend(map[string]Type{arm.Var: options[arm.Tag]})); err != nil {
+			return nil, err
+		}
+	}
+	if e.Else != nil {
+		if err := addBody(e.Else, tenv); err != nil {
+			return nil, err
+		}
+	}
+	if result == nil {
+		// No arms so it never returns
+		return c.Fresh(), nil
+	}
+	return result, nil
+}
+
+// checkExhaustive checks the arms of a cases expression without an else arm
+// against the union it dispatches on, if already known, returning a
+// MissingCasesError for options without an arm and a RedundantCaseError for
+// an arm that is not an option of a closed union.
+func (c *TypeChecker) checkExhaustive(e *CasesExpr, t Type) error {
+	union, ok := c.Subst.Apply(t).(*UnionType)
+	if !ok {
+		return nil
+	}
+	var missing []string
+	for _, tag := range epl.SortedKeys(union.Options) {
+		if !slices.ContainsFunc(e.Arms, func(a *CaseArm) bool { return a.Tag == tag }) {
+			missing = append(missing, tag)
+		}
+	}
+	if missing != nil {
+		return MissingCasesError{Tags: missing}
+	}
+	for _, arm := range e.Arms {
+		if _, ok := union.Options[arm.Tag]; !ok && union.Rest == nil {
+			return RedundantCaseError{Tag: arm.Tag}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package chapter7
+
+import (
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var shape = Union(map[string]Type{"Circle": Int, "Square": Int}, nil)
+
+// area is
+//
+//	proc (s : [Circle int | Square int])
+//	    cases s of Circle (r) => *(r, r) Square (x) => *(x, 4) end
+func area(param Type) *ProcExpr {
+	return TypedProc([]string{"s"}, []Type{param}, nil,
+		Cases("s", []*CaseArm{Arm("Circle", "r", Op("*", "r", "r")), Arm("Square", "x", Op("*", "x", 4))}, nil))
+}
+
+func TestCheckedVariants(t *testing.T) {
+	cases := []TypeTestCase{
+		{Name: "area", Expected: Func([]Type{shape}, Int), Expr: area(shape)},
+		{Name: "circle", Expected: Int, Expr: Call(area(shape), Tag("Circle", 3))},
+		{Name: "payload", Expected: TypeError{Expected: Int, Found: Bool}, Expr: Call(area(shape), Tag("Circle", true))},
+		// proc (s : [Circle int | Square int]) cases s of Circle (r) => r end
+		{Name: "missing", Expected: MissingCasesError{Tags: []string{"Square"}},
+			Expr: TypedProc([]string{"s"}, []Type{shape}, nil, Cases("s", []*CaseArm{Arm("Circle", "r", "r")}, nil))},
+		{Name: "duplicate", Expected: RedundantCaseError{Tag: "Circle"},
+			Expr: Cases(Tag("Circle", 1), []*CaseArm{Arm("Circle", "r", "r"), Arm("Circle", "x", "x")}, nil)},
+		{Name: "not an option", Expected: RedundantCaseError{Tag: "Triangle"},
+			Expr: TypedProc([]string{"s"}, []Type{shape}, nil, Cases("s", []*CaseArm{
+				Arm("Circle", "r", "r"), Arm("Square", "r", "r"), Arm("Triangle", "r", "r")}, nil))},
+		{Name: "arm types", Expected: TypeError{Expected: Int, Found: Bool},
+			Expr: Cases(Tag("Circle", 1), []*CaseArm{Arm("Circle", "r", "r")}, true)},
+		{Name: "not a union", Expected: TypeError{Expected: Union(map[string]Type{"Circle": &TypeVar{ID: 1}}, nil), Found: Int},
+			Expr: Cases(1, []*CaseArm{Arm("Circle", "r", "r")}, nil)},
+	}
+	for _, tc := range cases {
+		RunTypeTest(t, &tc)
+	}
+
+	// An option the union does not have
+	_, err := NewTypeChecker().Check(Call(area(shape), Tag("Triangle", 1)))
+	assert.EqualError(t, err, "expected type [Circle int | Square int], found [Triangle int | ..t3]")
+}
+
+func TestInferVariants(t *testing.T) {
+	RunInferTest(t, "tag", Tag("Circle", 5), "[Circle int | ..t1]")
+	RunInferTest(t, "area", area(nil), "([Circle int | Square int] -> int)")
+	RunInferTest(t, "mixed list", List(Tag("A", 1), Tag("B", true)), "list [A int | B bool | ..t3]")
+	// Cases with an else arm take any union with the tags of its arms
+	// - proc (s) cases s of A (x) => x else => 0 end
+	withElse := Proc([]string{"s"}, Cases("s", []*CaseArm{Arm("A", "x", "x")}, 0))
+	RunInferTest(t, "else", withElse, "([A int | ..t3] -> int)")
+	RunInferTest(t, "else applied", Let(ExprDict("f", withElse), Tuple(Call("f", Tag("A", 1)), Call("f", Tag("B", true)))),
+		"(int, int)")
+	RunInferTest(t, "nested", Call(area(nil), Tag("Circle", Tag("Inner", 1))),
+		TypeError{Expected: Int, Found: Union(map[string]Type{"Inner": Int}, &TypeVar{ID: 4})})
+	RunPolyTest(t, "poly else", withElse, "forall a. ([A int | ..a] -> int)")
+	RunPolyTest(t, "poly tag", Let(ExprDict("some", Proc([]string{"x"}, Tag("Some", "x"))), Var("some")),
+		"forall a b. (a -> [Some a | ..b])")
+
+	assert.Equal(t, "[]", Union(nil, nil).String())
+	assert.True(t, shape.Eq(Union(map[string]Type{"Square": Int, "Circle": Int}, nil)))
+	assert.False(t, shape.Eq(Union(map[string]Type{"Square": Int, "Circle": Int}, &TypeVar{ID: 1})))
+}
+
+func RunVariantTest(t *testing.T, tc *chapter3.TestCase) {
+	t.Helper()
+	value, err := chapter3.SetOpFuncs(NewVariantLangEval()).Eval(tc.Expr, epl.NewEnv[any](nil))
+	if expectedErr, ok := tc.Expected.(error); ok {
+		assert.ErrorIs(t, err, expectedErr, "Test %s", tc.Name)
+		return
+	}
+	require.NoError(t, err, "Test %s Failed - Unexpected error", tc.Name)
+	chapter3.AssertValue(t, tc.Name, tc.Expected, value)
+}
+
+func TestEvalVariants(t *testing.T) {
+	cases := []chapter3.TestCase{
+		{Name: "circle", Expected: 9, Expr: Call(area(nil), Tag("Circle", 3))},
+		{Name: "square", Expected: 8, Expr: Call(area(nil), Tag("Square", 2))},
+		{Name: "tagged", Expected: TaggedVal{Tag: "Some", Value: IntVal(3)}, Expr: Tag("Some", Op("-", 5, 2))},
+		{Name: "else", Expected: 0, Expr: Cases(Tag("B", 1), []*CaseArm{Arm("A", "x", "x")}, 0)},
+		{Name: "unmatched", Expected: UnmatchedCaseError{Tag: "B"}, Expr: Cases(Tag("B", 1), []*CaseArm{Arm("A", "x", "x")}, nil)},
+		{Name: "not tagged", Expected: chapter3.TypeMismatchError{Context: "cases", Expected: "a tagged value", Found: IntVal(1)},
+			Expr: Cases(1, []*CaseArm{Arm("A", "x", "x")}, nil)},
+	}
+	for _, tc := range cases {
+		RunVariantTest(t, &tc)
+	}
+
+	// A failed cases is a runtime error so try can catch it
+	e := NewVariantLangEval()
+	e.CatchRuntimeErrors = true
+	value, err := chapter3.SetOpFuncs(e).Eval(Try(Cases(Tag("B", 1), []*CaseArm{Arm("A", "x", "x")}, nil), "err", -1), epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	chapter3.AssertValue(t, "caught", -1, value)
+
+	assert.Equal(t, "Rect (2, 3)", TaggedVal{Tag: "Rect", Value: chapter3.TupleVal{IntVal(2), IntVal(3)}}.String())
+	assert.True(t, ExprEq(Cases("s", []*CaseArm{Arm("A", "x", "x")}, nil), Cases("s", []*CaseArm{Arm("A", "x", "x")}, nil)))
+	assert.False(t, ExprEq(Cases("s", []*CaseArm{Arm("A", "x", "x")}, nil), Cases("s", []*CaseArm{Arm("A", "x", "x")}, 0)))
+	assert.False(t, ExprEq(Tag("A", 1), Tag("B", 1)))
+}
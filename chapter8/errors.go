@@ -0,0 +1,57 @@
+package chapter8
+
+import (
+	"fmt"
+
+	"github.com/panyam/eplgo/chapter3"
+)
+
+// UnknownModuleError is returned by 'from m take x' when no module m has been
+// defined.
+type UnknownModuleError struct {
+	chapter3.RuntimeErrorBase
+	Name string
+}
+
+func (e UnknownModuleError) Error() string {
+	return fmt.Sprintf("module '%s' is not defined", e.Name)
+}
+
+// NotExportedError is returned by 'from m take x' when x is not declared in
+// the interface of m - names defined in a module body are private unless
+// declared.
+type NotExportedError struct {
+	chapter3.RuntimeErrorBase
+	Module string
+	Name   string
+}
+
+func (e NotExportedError) Error() string {
+	return fmt.Sprintf("module '%s' does not export '%s'", e.Module, e.Name)
+}
+
+// MissingExportError is returned when a module body does not define a name
+// declared in its interface.
+type MissingExportError struct {
+	chapter3.RuntimeErrorBase
+	Module string
+	Name   string
+}
+
+func (e MissingExportError) Error() string {
+	return fmt.Sprintf("module '%s' declares '%s' but its body does not define it", e.Module, e.Name)
+}
+
+// ExportMismatchError is returned when a module body defines a name with a
+// value that does not have the type declared in its interface.
+type ExportMismatchError struct {
+	chapter3.RuntimeErrorBase
+	Module   string
+	Name     string
+	Expected Type
+	Found    any
+}
+
+func (e ExportMismatchError) Error() string {
+	return fmt.Sprintf("module '%s' declares '%s' as %s but defines it as %s", e.Module, e.Name, e.Expected, ReprOf(e.Found))
+}
@@ -0,0 +1,44 @@
+package chapter8
+
+import (
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+)
+
+// A few imports to not avoid having to prefix with chapter3 all over the place
+type Expr = chapter3.Expr
+type LitExpr = chapter3.LitExpr
+type VarExpr = chapter3.VarExpr
+
+var ExprDict = epl.Dict[string, Expr]
+
+type Value = chapter3.Value
+type IntVal = chapter3.IntVal
+type BigIntVal = chapter3.BigIntVal
+type BoolVal = chapter3.BoolVal
+type ProcVal = chapter3.ProcVal
+type NativeProc = chapter3.NativeProc
+
+type TestCase = chapter3.TestCase
+type Evaluator = chapter3.Evaluator
+
+type UnboundVariableError = chapter3.UnboundVariableError
+type TypeMismatchError = chapter3.TypeMismatchError
+type RuntimeError = chapter3.RuntimeError
+type UnsupportedExprError = chapter3.UnsupportedExprError
+
+var SetOpFuncs = chapter3.SetOpFuncs
+var Lit = chapter3.Lit
+var Let = chapter3.Let
+var LetRec = chapter3.LetRec
+var Op = chapter3.Op
+var If = chapter3.If
+var IsZero = chapter3.IsZero
+var Proc = chapter3.Proc
+var Call = chapter3.Call
+var ProcMap = chapter3.ProcMap
+var Var = chapter3.Var
+var AnyToExpr = chapter3.AnyToExpr
+var ExprEq = chapter3.ExprEq
+var AssertValue = chapter3.AssertValue
+var ReprOf = chapter3.ReprOf
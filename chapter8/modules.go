@@ -0,0 +1,319 @@
+package chapter8
+
+import (
+	"fmt"
+	"strings"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+	gfn "github.com/panyam/goutils/fn"
+)
+
+// VarDecl declares a name exported by a module - 'x : int'.
+type VarDecl struct {
+	Name string
+	Type Type
+}
+
+func Decl(name string, t Type) *VarDecl {
+	return &VarDecl{Name: name, Type: t}
+}
+
+func (d *VarDecl) Repr() string {
+	return fmt.Sprintf("%s : %s", d.Name, d.Type)
+}
+
+func (d *VarDecl) Eq(another *VarDecl) bool {
+	return d.Name == another.Name && d.Type.Eq(another.Type)
+}
+
+// VarDefn defines a name in a module body - 'x = 3'.
+type VarDefn struct {
+	Name string
+	Expr Expr
+}
+
+func Defn(name string, expr any) *VarDefn {
+	return &VarDefn{Name: name, Expr: AnyToExpr(expr)}
+}
+
+func (d *VarDefn) Repr() string {
+	return fmt.Sprintf("%s = %s", d.Name, d.Expr.Repr())
+}
+
+func (d *VarDefn) Eq(another *VarDefn) bool {
+	return d.Name == another.Name && ExprEq(d.Expr, another.Expr)
+}
+
+// ModuleDefn is 'module m interface [decls] body [defns]'.  The definitions
+// in the body are evaluated in order, each one seeing those before it, and
+// only the names declared in the interface are visible outside the module.
+type ModuleDefn struct {
+	Name      string
+	Interface []*VarDecl
+	Body      []*VarDefn
+}
+
+func Module(name string, iface []*VarDecl, body []*VarDefn) *ModuleDefn {
+	return &ModuleDefn{Name: name, Interface: iface, Body: body}
+}
+
+func (m *ModuleDefn) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "Module %s:", m.Name)) {
+			return
+		}
+		if !yield(epl.Printablef(1, "Interface:")) {
+			return
+		}
+		for _, decl := range m.Interface {
+			if !yield(epl.Printablef(2, "%s", decl.Repr())) {
+				return
+			}
+		}
+		if !yield(epl.Printablef(1, "Body:")) {
+			return
+		}
+		for _, defn := range m.Body {
+			if !yield(epl.Printablef(2, "%s =", defn.Name)) {
+				return
+			}
+			exprPrintable := defn.Expr.Printable()
+			exprPrintable.IndentLevel += 3
+			if !yield(exprPrintable) {
+				return
+			}
+		}
+	})
+}
+
+func (m *ModuleDefn) Repr() string {
+	return fmt.Sprintf("<Module %s [%s] [%s]>", m.Name,
+		strings.Join(gfn.Map(m.Interface, (*VarDecl).Repr), ", "),
+		strings.Join(gfn.Map(m.Body, (*VarDefn).Repr), ", "))
+}
+
+func (m *ModuleDefn) Eq(another *ModuleDefn) bool {
+	if m.Name != another.Name || len(m.Interface) != len(another.Interface) || len(m.Body) != len(another.Body) {
+		return false
+	}
+	for i, decl := range m.Interface {
+		if !decl.Eq(another.Interface[i]) {
+			return false
+		}
+	}
+	for i, defn := range m.Body {
+		if !defn.Eq(another.Body[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ProgramExpr is a program in the modules language - a sequence of module
+// definitions followed by the expression that uses them.
+type ProgramExpr struct {
+	Modules []*ModuleDefn
+	Body    Expr
+}
+
+func Program(modules []*ModuleDefn, body any) *ProgramExpr {
+	return &ProgramExpr{Modules: modules, Body: AnyToExpr(body)}
+}
+
+func (e *ProgramExpr) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "Program:")) {
+			return
+		}
+		for _, m := range e.Modules {
+			mp := m.Printable()
+			mp.IndentLevel += 1
+			if !yield(mp) {
+				return
+			}
+		}
+		if !yield(epl.Printablef(1, "in:")) {
+			return
+		}
+		bodyPrintable := e.Body.Printable()
+		bodyPrintable.IndentLevel += 2
+		if !yield(bodyPrintable) {
+			return
+		}
+	})
+}
+
+func (e *ProgramExpr) Repr() string {
+	return fmt.Sprintf("<Program %s in %s>", strings.Join(gfn.Map(e.Modules, (*ModuleDefn).Repr), " "), e.Body.Repr())
+}
+
+func (e *ProgramExpr) Eq(another *ProgramExpr) bool {
+	if len(e.Modules) != len(another.Modules) {
+		return false
+	}
+	for i, m := range e.Modules {
+		if !m.Eq(another.Modules[i]) {
+			return false
+		}
+	}
+	return ExprEq(e.Body, another.Body)
+}
+
+// QualifiedVarExpr is 'from m take x'.
+type QualifiedVarExpr struct {
+	Module string
+	Name   string
+}
+
+func QualifiedVar(module string, name string) *QualifiedVarExpr {
+	return &QualifiedVarExpr{Module: module, Name: name}
+}
+
+func (e *QualifiedVarExpr) Printable() *epl.Printable {
+	return epl.Printablef(0, "from %s take %s", e.Module, e.Name)
+}
+
+func (e *QualifiedVarExpr) Repr() string {
+	return fmt.Sprintf("<QualifiedVar(%s.%s)>", e.Module, e.Name)
+}
+
+func (e *QualifiedVarExpr) Eq(another *QualifiedVarExpr) bool {
+	return e.Module == another.Module && e.Name == another.Name
+}
+
+// ModuleVal is an evaluated module holding the values of the names in its
+// interface.
+type ModuleVal struct {
+	Name    string
+	Exports *epl.Env[any]
+}
+
+func (v *ModuleVal) String() string {
+	return fmt.Sprintf("<module %s>", v.Name)
+}
+
+func (v *ModuleVal) Repr() string {
+	return fmt.Sprintf("Module(%s, [%s])", v.Name, strings.Join(v.Exports.Names(), ", "))
+}
+
+// Eq is true only for the same module.
+func (v *ModuleVal) Eq(another Value) bool {
+	a, ok := another.(*ModuleVal)
+	return ok && a == v
+}
+
+// moduleKey is the name a module is bound to in the environment.  Module
+// names are a separate namespace from variables so the key is not a valid
+// identifier.
+func moduleKey(name string) string {
+	return "module " + name
+}
+
+// ModuleLangEval extends LetRecLangEval with modules.
+type ModuleLangEval struct {
+	chapter3.LetRecLangEval
+}
+
+// NewModuleLangEval creates a new evaluator for the modules language.
+func NewModuleLangEval() *ModuleLangEval {
+	out := &ModuleLangEval{}
+	// CRITICAL: Set the Self pointer for the embedded BaseEval
+	out.BaseEval.Self = out
+	return out
+}
+
+// LocalEval handles expression types specific to the modules language or
+// delegates.
+func (l *ModuleLangEval) LocalEval(expr Expr, env *epl.Env[any]) (any, error) {
+	switch n := expr.(type) {
+	case *ProgramExpr:
+		return l.ValueOfProgram(n, env)
+	case *QualifiedVarExpr:
+		return l.ValueOfQualifiedVar(n, env)
+	default:
+		return l.LetRecLangEval.LocalEval(expr, env)
+	}
+}
+
+// ValueOfProgram evaluates each module in turn, so that a module can use
+// those defined before it, and then the body of the program.
+func (l *ModuleLangEval) ValueOfProgram(e *ProgramExpr, env *epl.Env[any]) (any, error) {
+	newenv := env.Push()
+	for _, m := range e.Modules {
+		mod, err := l.ValueOfModule(m, newenv)
+		if err != nil {
+			return nil, l.WrapError(err, "evaluating module '%s'", m.Name)
+		}
+		newenv.Set(moduleKey(m.Name), mod)
+	}
+	return l.Eval(e.Body, newenv)
+}
+
+// ValueOfModule evaluates the body of a module and checks that it provides
+// its interface.  Exported procedures are also checked each time they are
+// called.
+func (l *ModuleLangEval) ValueOfModule(m *ModuleDefn, env *epl.Env[any]) (*ModuleVal, error) {
+	defined := map[string]any{}
+	bodyenv := env
+	for _, defn := range m.Body {
+		val, err := l.Eval(defn.Expr, bodyenv)
+		if err != nil {
+			return nil, l.WrapError(err, "evaluating definition '%s'", defn.Name)
+		}
+		defined[defn.Name] = val
+		bodyenv = bodyenv.Extend(epl.Dict[string, any](defn.Name, val))
+	}
+
+	exports := epl.NewEnv[any](nil)
+	for _, decl := range m.Interface {
+		val, ok := defined[decl.Name]
+		if !ok {
+			return nil, MissingExportError{Module: m.Name, Name: decl.Name}
+		}
+		if v, isValue := val.(Value); !isValue || !decl.Type.Accepts(v) {
+			return nil, ExportMismatchError{Module: m.Name, Name: decl.Name, Expected: decl.Type, Found: val}
+		}
+		exports.Set(decl.Name, l.checked(m.Name+"."+decl.Name, decl.Type, val.(Value)))
+	}
+	return &ModuleVal{Name: m.Name, Exports: exports}, nil
+}
+
+// checked wraps an exported procedure so that every call checks its
+// arguments and result against the declared ProcType - checking the
+// procedure itself only tells us how many arguments it takes.  Other values
+// are returned as is.
+func (l *ModuleLangEval) checked(name string, t Type, val Value) Value {
+	procType, ok := t.(*ProcType)
+	if !ok {
+		return val
+	}
+	return &NativeProc{Name: name, Arity: len(procType.ArgTypes), Fn: func(args []Value) (Value, error) {
+		for i, arg := range args {
+			if !procType.ArgTypes[i].Accepts(arg) {
+				return nil, TypeMismatchError{Context: fmt.Sprintf("argument %d of %s", i+1, name), Expected: procType.ArgTypes[i].String(), Found: arg}
+			}
+		}
+		result, err := l.Eval(Call(Lit(val), gfn.Map(args, func(arg Value) any { return Lit(arg) })...), epl.NewEnv[any](nil))
+		if err != nil {
+			return nil, err
+		}
+		if v, isValue := result.(Value); !isValue || !procType.ResultType.Accepts(v) {
+			return nil, TypeMismatchError{Context: "result of " + name, Expected: procType.ResultType.String(), Found: result}
+		}
+		return l.checked(name, procType.ResultType, result.(Value)), nil
+	}}
+}
+
+// ValueOfQualifiedVar evaluates 'from m take x'.
+func (l *ModuleLangEval) ValueOfQualifiedVar(e *QualifiedVarExpr, env *epl.Env[any]) (any, error) {
+	mod, found := env.Get(moduleKey(e.Module))
+	if !found {
+		return nil, UnknownModuleError{Name: e.Module}
+	}
+	val, found := mod.(*ModuleVal).Exports.Get(e.Name)
+	if !found {
+		return nil, NotExportedError{Module: e.Module, Name: e.Name}
+	}
+	return val, nil
+}
@@ -0,0 +1,191 @@
+package chapter8
+
+import (
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func NewTestModuleLangEval() Evaluator {
+	return SetOpFuncs(NewModuleLangEval())
+}
+
+func RunModuleTest(t *testing.T, tc *TestCase) {
+	t.Helper()
+	value, err := NewTestModuleLangEval().Eval(tc.Expr, epl.NewEnv[any](nil))
+	if expectedErr, ok := tc.Expected.(error); ok {
+		assert.ErrorIs(t, err, expectedErr, "Test %s", tc.Name)
+		return
+	}
+	require.NoError(t, err, "Test %s Failed - Unexpected error", tc.Name)
+	AssertValue(t, tc.Name, tc.Expected, value)
+}
+
+var intType = IntType{}
+var boolType = BoolType{}
+
+// module m1
+//
+//	interface [a : int, b : int, c : int]
+//	body [a = 33, x = -(a, 1), b = -(a, x), c = -(x, b)]
+func m1() *ModuleDefn {
+	return Module("m1",
+		[]*VarDecl{Decl("a", intType), Decl("b", intType), Decl("c", intType)},
+		[]*VarDefn{
+			Defn("a", 33),
+			Defn("x", Op("-", "a", 1)),
+			Defn("b", Op("-", "a", "x")),
+			Defn("c", Op("-", "x", "b")),
+		})
+}
+
+// Ported from the SIMPLE-MODULES examples in EOPL chapter 8
+
+func TestModuleTake(t *testing.T) {
+	// let a = 10 in -(-(from m1 take a, from m1 take b), a)
+	RunModuleTest(t, &TestCase{Name: "take", Expected: 22, Expr: Program([]*ModuleDefn{m1()},
+		Let(ExprDict("a", Lit(10)),
+			Op("-", Op("-", QualifiedVar("m1", "a"), QualifiedVar("m1", "b")), "a")))})
+	RunModuleTest(t, &TestCase{Name: "sequential", Expected: 31, Expr: Program([]*ModuleDefn{m1()},
+		QualifiedVar("m1", "c"))})
+}
+
+func TestModuleUsesEarlierModule(t *testing.T) {
+	modules := []*ModuleDefn{
+		Module("m1", []*VarDecl{Decl("u", intType)}, []*VarDefn{Defn("u", 44)}),
+		Module("m2", []*VarDecl{Decl("v", intType)}, []*VarDefn{Defn("v", Op("-", QualifiedVar("m1", "u"), 11))}),
+	}
+	RunModuleTest(t, &TestCase{Name: "earlier", Expected: 11, Expr: Program(modules,
+		Op("-", QualifiedVar("m1", "u"), QualifiedVar("m2", "v")))})
+}
+
+func TestModuleProcedures(t *testing.T) {
+	// module evenodd
+	//   interface [even : (int -> bool), odd : (int -> bool)]
+	//   body [even = letrec even(x) = ... odd(x) = ... in even,
+	//         odd = letrec even(x) = ... odd(x) = ... in odd]
+	letrec := func(body string) Expr {
+		return LetRec(ProcMap(
+			"even", Proc([]string{"x"}, If(IsZero("x"), Lit(true), Call("odd", Op("-", "x", 1)))),
+			"odd", Proc([]string{"x"}, If(IsZero("x"), Lit(false), Call("even", Op("-", "x", 1))))),
+			Var(body))
+	}
+	evenodd := Module("evenodd",
+		[]*VarDecl{Decl("even", ProcOf(boolType, intType)), Decl("odd", ProcOf(boolType, intType))},
+		[]*VarDefn{Defn("even", letrec("even")), Defn("odd", letrec("odd"))})
+	RunModuleTest(t, &TestCase{Name: "evenodd", Expected: true, Expr: Program([]*ModuleDefn{evenodd},
+		Call(QualifiedVar("evenodd", "odd"), 13))})
+
+	// A procedure in a module closes over the module's private names
+	counter := Module("counter",
+		[]*VarDecl{Decl("next", ProcOf(intType, intType))},
+		[]*VarDefn{Defn("step", 5), Defn("next", Proc([]string{"x"}, Op("+", "x", "step")))})
+	RunModuleTest(t, &TestCase{Name: "closure", Expected: 8, Expr: Program([]*ModuleDefn{counter},
+		Let(ExprDict("step", Lit(100)), Call(QualifiedVar("counter", "next"), 3)))})
+}
+
+func TestModuleErrors(t *testing.T) {
+	cases := []TestCase{
+		{Name: "private", Expected: NotExportedError{Module: "m1", Name: "x"},
+			Expr: Program([]*ModuleDefn{m1()}, QualifiedVar("m1", "x"))},
+		{Name: "unknown", Expected: UnknownModuleError{Name: "m2"},
+			Expr: Program([]*ModuleDefn{m1()}, QualifiedVar("m2", "a"))},
+		{Name: "later", Expected: UnknownModuleError{Name: "m2"},
+			Expr: Program([]*ModuleDefn{
+				Module("m1", []*VarDecl{Decl("u", intType)}, []*VarDefn{Defn("u", QualifiedVar("m2", "v"))}),
+				Module("m2", []*VarDecl{Decl("v", intType)}, []*VarDefn{Defn("v", 1)}),
+			}, 0)},
+		{Name: "missing", Expected: MissingExportError{Module: "m", Name: "y"},
+			Expr: Program([]*ModuleDefn{
+				Module("m", []*VarDecl{Decl("x", intType), Decl("y", intType)}, []*VarDefn{Defn("x", 1)}),
+			}, 0)},
+		{Name: "mismatch", Expected: ExportMismatchError{Module: "m", Name: "x", Expected: boolType, Found: IntVal(1)},
+			Expr: Program([]*ModuleDefn{
+				Module("m", []*VarDecl{Decl("x", boolType)}, []*VarDefn{Defn("x", 1)}),
+			}, 0)},
+		{Name: "scope", Expected: UnboundVariableError{Name: "x"},
+			Expr: Program([]*ModuleDefn{m1()}, "x")},
+	}
+	for _, tc := range cases {
+		RunModuleTest(t, &tc)
+	}
+
+	// A procedure taking the wrong number of arguments
+	f := Proc([]string{"x", "y"}, Var("x"))
+	_, err := NewTestModuleLangEval().Eval(Program([]*ModuleDefn{
+		Module("m", []*VarDecl{Decl("f", ProcOf(intType, intType))}, []*VarDefn{Defn("f", f)}),
+	}, 0), epl.NewEnv[any](nil))
+	var mismatch ExportMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, "f", mismatch.Name)
+	assert.EqualError(t, mismatch, "module 'm' declares 'f' as (int -> int) but defines it as "+ReprOf(mismatch.Found))
+}
+
+func TestModuleErrorsAreRuntimeErrors(t *testing.T) {
+	_, err := NewTestModuleLangEval().Eval(Program([]*ModuleDefn{m1()}, QualifiedVar("m2", "a")), epl.NewEnv[any](nil))
+	var rt RuntimeError
+	assert.ErrorAs(t, err, &rt)
+	for _, e := range []error{NotExportedError{}, MissingExportError{}, ExportMismatchError{}} {
+		assert.ErrorAs(t, e, &rt)
+	}
+}
+
+func TestProcedureTypesCheckedOnCall(t *testing.T) {
+	// module m
+	//   interface [f : (int -> bool), g : (bool -> int), h : (int -> (int -> bool))]
+	//   body [f = proc (x) x, g = proc (x) 1, h = proc (x) proc (y) y]
+	m := Module("m",
+		[]*VarDecl{
+			Decl("f", ProcOf(boolType, intType)),
+			Decl("g", ProcOf(intType, boolType)),
+			Decl("h", ProcOf(ProcOf(boolType, intType), intType)),
+		},
+		[]*VarDefn{
+			Defn("f", Proc([]string{"x"}, Var("x"))),
+			Defn("g", Proc([]string{"x"}, Lit(1))),
+			Defn("h", Proc([]string{"x"}, Proc([]string{"y"}, Var("y")))),
+		})
+	eval := func(body Expr) error {
+		_, err := NewTestModuleLangEval().Eval(Program([]*ModuleDefn{m}, body), epl.NewEnv[any](nil))
+		return err
+	}
+
+	RunModuleTest(t, &TestCase{Name: "ok", Expected: 1, Expr: Program([]*ModuleDefn{m}, Call(QualifiedVar("m", "g"), Lit(true)))})
+
+	var mismatch TypeMismatchError
+	require.ErrorAs(t, eval(Call(QualifiedVar("m", "f"), 1)), &mismatch)
+	assert.Equal(t, "result of m.f", mismatch.Context)
+	assert.Equal(t, "bool", mismatch.Expected)
+
+	require.ErrorAs(t, eval(Call(QualifiedVar("m", "g"), 1)), &mismatch)
+	assert.Equal(t, "argument 1 of m.g", mismatch.Context)
+	assert.Equal(t, IntVal(1), mismatch.Found)
+
+	// Procedures returned are checked too
+	require.ErrorAs(t, eval(Call(Call(QualifiedVar("m", "h"), 1), 2)), &mismatch)
+	assert.Equal(t, "result of m.h", mismatch.Context)
+	require.ErrorAs(t, eval(Call(QualifiedVar("m", "h"), 1, Lit(true))), &mismatch)
+	assert.Equal(t, "argument 1 of m.h", mismatch.Context)
+}
+
+func TestTypes(t *testing.T) {
+	assert.Equal(t, "(int * bool -> (-> int))", ProcOf(ProcOf(intType), intType, boolType).String())
+	assert.True(t, ProcOf(intType, intType).Eq(ProcOf(intType, intType)))
+	assert.False(t, ProcOf(intType, intType).Eq(ProcOf(boolType, intType)))
+	assert.True(t, intType.Accepts(IntVal(1)))
+	assert.False(t, intType.Accepts(BoolVal(true)))
+
+	native := &NativeProc{Name: "add", Arity: 2}
+	assert.True(t, ProcOf(intType, intType, intType).Accepts(native))
+	assert.False(t, ProcOf(intType, intType).Accepts(native))
+}
+
+func TestModuleEq(t *testing.T) {
+	p1 := Program([]*ModuleDefn{m1()}, QualifiedVar("m1", "a"))
+	assert.True(t, ExprEq(p1, Program([]*ModuleDefn{m1()}, QualifiedVar("m1", "a"))))
+	assert.False(t, ExprEq(p1, Program([]*ModuleDefn{m1()}, QualifiedVar("m1", "b"))))
+	assert.False(t, ExprEq(p1, Program(nil, QualifiedVar("m1", "a"))))
+	assert.Equal(t, "<Module m1 [a : int, b : int, c : int] [a = Val(33:int), x = <Op(-, [<Var(a)>, Val(1:int)])>, b = <Op(-, [<Var(a)>, <Var(x)>])>, c = <Op(-, [<Var(x)>, <Var(b)>])>]>", m1().Repr())
+}
@@ -0,0 +1,96 @@
+package chapter8
+
+import (
+	"fmt"
+	"strings"
+
+	gfn "github.com/panyam/goutils/fn"
+)
+
+// Type is the type of a name declared in a module interface.  Modules are
+// checked as they are evaluated so a type is a test on values.  A procedure
+// type can only check the number of arguments - its argument and result
+// types are checked when the exported procedure is called.
+type Type interface {
+	String() string
+	Accepts(val Value) bool
+	Eq(another Type) bool
+}
+
+// IntType is 'int'.
+type IntType struct{}
+
+func (t IntType) String() string { return "int" }
+
+func (t IntType) Accepts(val Value) bool {
+	switch val.(type) {
+	case IntVal, BigIntVal:
+		return true
+	}
+	return false
+}
+
+func (t IntType) Eq(another Type) bool {
+	_, ok := another.(IntType)
+	return ok
+}
+
+// BoolType is 'bool'.
+type BoolType struct{}
+
+func (t BoolType) String() string { return "bool" }
+
+func (t BoolType) Accepts(val Value) bool {
+	_, ok := val.(BoolVal)
+	return ok
+}
+
+func (t BoolType) Eq(another Type) bool {
+	_, ok := another.(BoolType)
+	return ok
+}
+
+// ProcType is '(t1 * t2 ... -> result)'.
+type ProcType struct {
+	ArgTypes   []Type
+	ResultType Type
+}
+
+// ProcOf is a constructor for ProcType.
+func ProcOf(result Type, args ...Type) *ProcType {
+	return &ProcType{ArgTypes: args, ResultType: result}
+}
+
+func (t *ProcType) String() string {
+	args := gfn.Map(t.ArgTypes, Type.String)
+	if len(args) == 0 {
+		return fmt.Sprintf("(-> %s)", t.ResultType)
+	}
+	return fmt.Sprintf("(%s -> %s)", strings.Join(args, " * "), t.ResultType)
+}
+
+func (t *ProcType) Accepts(val Value) bool {
+	switch p := val.(type) {
+	case ProcVal:
+		return len(p.ProcExpr.Varnames) == len(t.ArgTypes)
+	case *NativeProc:
+		if p.Variadic {
+			return p.Pending() <= len(t.ArgTypes)
+		}
+		return p.Pending() == len(t.ArgTypes)
+	}
+	return false
+}
+
+func (t *ProcType) Eq(another Type) bool {
+	a, ok := another.(*ProcType)
+	if !ok || len(t.ArgTypes) != len(a.ArgTypes) || !t.ResultType.Eq(a.ResultType) {
+		return false
+	}
+	for i, arg := range t.ArgTypes {
+		if !arg.Eq(a.ArgTypes[i]) {
+			return false
+		}
+	}
+	return true
+}
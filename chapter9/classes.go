@@ -0,0 +1,504 @@
+package chapter9
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+	"github.com/panyam/eplgo/chapter4"
+	gfn "github.com/panyam/goutils/fn"
+)
+
+// MethodDecl is 'method m (params) body'.
+type MethodDecl struct {
+	Name   string
+	Params []string
+	Body   Expr
+}
+
+func Method(name string, params []string, body any) *MethodDecl {
+	return &MethodDecl{Name: name, Params: params, Body: AnyToExpr(body)}
+}
+
+func (m *MethodDecl) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "method %s (%s)", m.Name, strings.Join(m.Params, ", "))) {
+			return
+		}
+		bodyPrintable := m.Body.Printable()
+		bodyPrintable.IndentLevel += 1
+		if !yield(bodyPrintable) {
+			return
+		}
+	})
+}
+
+func (m *MethodDecl) Repr() string {
+	return fmt.Sprintf("<Method %s(%s) %s>", m.Name, strings.Join(m.Params, ", "), m.Body.Repr())
+}
+
+func (m *MethodDecl) Eq(another *MethodDecl) bool {
+	return m.Name == another.Name && slices.Equal(m.Params, another.Params) && ExprEq(m.Body, another.Body)
+}
+
+// ClassDecl is 'class c extends d field x ... method m (...) ...'.  Every
+// class extends another, with "object" at the root of the hierarchy.
+type ClassDecl struct {
+	Name    string
+	Super   string
+	Fields  []string
+	Methods []*MethodDecl
+}
+
+func Class(name string, super string, fields []string, methods ...*MethodDecl) *ClassDecl {
+	return &ClassDecl{Name: name, Super: super, Fields: fields, Methods: methods}
+}
+
+func (c *ClassDecl) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "class %s extends %s", c.Name, c.Super)) {
+			return
+		}
+		for _, f := range c.Fields {
+			if !yield(epl.Printablef(1, "field %s", f)) {
+				return
+			}
+		}
+		for _, m := range c.Methods {
+			mp := m.Printable()
+			mp.IndentLevel += 1
+			if !yield(mp) {
+				return
+			}
+		}
+	})
+}
+
+func (c *ClassDecl) Repr() string {
+	return fmt.Sprintf("<Class %s extends %s [%s] %s>", c.Name, c.Super, strings.Join(c.Fields, ", "),
+		strings.Join(gfn.Map(c.Methods, (*MethodDecl).Repr), " "))
+}
+
+func (c *ClassDecl) Eq(another *ClassDecl) bool {
+	if c.Name != another.Name || c.Super != another.Super || !slices.Equal(c.Fields, another.Fields) ||
+		len(c.Methods) != len(another.Methods) {
+		return false
+	}
+	for i, m := range c.Methods {
+		if !m.Eq(another.Methods[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ProgramExpr is a program in the classes language - a sequence of class
+// declarations followed by the expression that uses them.
+type ProgramExpr struct {
+	Classes []*ClassDecl
+	Body    Expr
+}
+
+func Program(classes []*ClassDecl, body any) *ProgramExpr {
+	return &ProgramExpr{Classes: classes, Body: AnyToExpr(body)}
+}
+
+func (e *ProgramExpr) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "Program:")) {
+			return
+		}
+		for _, c := range e.Classes {
+			cp := c.Printable()
+			cp.IndentLevel += 1
+			if !yield(cp) {
+				return
+			}
+		}
+		if !yield(epl.Printablef(1, "in:")) {
+			return
+		}
+		bodyPrintable := e.Body.Printable()
+		bodyPrintable.IndentLevel += 2
+		if !yield(bodyPrintable) {
+			return
+		}
+	})
+}
+
+func (e *ProgramExpr) Repr() string {
+	return fmt.Sprintf("<Program %s in %s>", strings.Join(gfn.Map(e.Classes, (*ClassDecl).Repr), " "), e.Body.Repr())
+}
+
+func (e *ProgramExpr) Eq(another *ProgramExpr) bool {
+	if len(e.Classes) != len(another.Classes) {
+		return false
+	}
+	for i, c := range e.Classes {
+		if !c.Eq(another.Classes[i]) {
+			return false
+		}
+	}
+	return ExprEq(e.Body, another.Body)
+}
+
+// NewObjectExpr is 'new c (args)'.
+type NewObjectExpr struct {
+	Class string
+	Args  []Expr
+}
+
+func New(class string, args ...any) *NewObjectExpr {
+	return &NewObjectExpr{Class: class, Args: gfn.Map(args, AnyToExpr)}
+}
+
+func (e *NewObjectExpr) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "New %s", e.Class)) {
+			return
+		}
+		ExprListPrintable(1, e.Args, yield)
+	})
+}
+
+func (e *NewObjectExpr) Repr() string {
+	return fmt.Sprintf("<New %s(%s)>", e.Class, ExprListRepr(e.Args))
+}
+
+func (e *NewObjectExpr) Eq(another *NewObjectExpr) bool {
+	return e.Class == another.Class && ExprListEq(e.Args, another.Args)
+}
+
+// MethodCallExpr is 'send obj m (args)'.
+type MethodCallExpr struct {
+	Object Expr
+	Method string
+	Args   []Expr
+}
+
+func Send(object any, method string, args ...any) *MethodCallExpr {
+	return &MethodCallExpr{Object: AnyToExpr(object), Method: method, Args: gfn.Map(args, AnyToExpr)}
+}
+
+func (e *MethodCallExpr) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "Send %s", e.Method)) {
+			return
+		}
+		op := e.Object.Printable()
+		op.IndentLevel += 1
+		if !yield(op) {
+			return
+		}
+		ExprListPrintable(1, e.Args, yield)
+	})
+}
+
+func (e *MethodCallExpr) Repr() string {
+	return fmt.Sprintf("<Send %s.%s(%s)>", e.Object.Repr(), e.Method, ExprListRepr(e.Args))
+}
+
+func (e *MethodCallExpr) Eq(another *MethodCallExpr) bool {
+	return e.Method == another.Method && ExprEq(e.Object, another.Object) && ExprListEq(e.Args, another.Args)
+}
+
+// SuperCallExpr is 'super m (args)' - a call to the method of the superclass
+// of the class the current method was declared in, on the current object.
+type SuperCallExpr struct {
+	Method string
+	Args   []Expr
+}
+
+func Super(method string, args ...any) *SuperCallExpr {
+	return &SuperCallExpr{Method: method, Args: gfn.Map(args, AnyToExpr)}
+}
+
+func (e *SuperCallExpr) Printable() *epl.Printable {
+	return epl.PrintableIter(func(yield func(v *epl.Printable) bool) {
+		if !yield(epl.Printablef(0, "Super %s", e.Method)) {
+			return
+		}
+		ExprListPrintable(1, e.Args, yield)
+	})
+}
+
+func (e *SuperCallExpr) Repr() string {
+	return fmt.Sprintf("<Super %s(%s)>", e.Method, ExprListRepr(e.Args))
+}
+
+func (e *SuperCallExpr) Eq(another *SuperCallExpr) bool {
+	return e.Method == another.Method && ExprListEq(e.Args, another.Args)
+}
+
+// SelfExpr is 'self', the object the current method was called on.
+type SelfExpr struct{}
+
+func Self() *SelfExpr {
+	return &SelfExpr{}
+}
+
+func (e *SelfExpr) Printable() *epl.Printable {
+	return epl.Printablef(0, "self")
+}
+
+func (e *SelfExpr) Repr() string {
+	return "<Self>"
+}
+
+func (e *SelfExpr) Eq(another *SelfExpr) bool {
+	return true
+}
+
+// ClassVal is a declared class.  Fields holds the names of all the fields of
+// its objects, starting with those of its superclass, and Methods every
+// method its objects respond to, including inherited ones.
+type ClassVal struct {
+	Name    string
+	Super   *ClassVal
+	Fields  []string
+	Methods map[string]*BoundMethod
+
+	// The environment the class was declared in, for the free variables of
+	// its methods
+	Env *epl.Env[any]
+}
+
+// ObjectClass is the root of the class hierarchy.
+var ObjectClass = &ClassVal{Name: "object", Methods: map[string]*BoundMethod{}}
+
+func (v *ClassVal) String() string {
+	return fmt.Sprintf("<class %s>", v.Name)
+}
+
+func (v *ClassVal) Repr() string {
+	return fmt.Sprintf("Class(%s, Fields:[%s], Methods:[%s])", v.Name, strings.Join(v.Fields, ", "),
+		strings.Join(epl.SortedKeys(v.Methods), ", "))
+}
+
+// Eq is true only for the same class.
+func (v *ClassVal) Eq(another Value) bool {
+	a, ok := another.(*ClassVal)
+	return ok && a == v
+}
+
+// BoundMethod is a method along with the class it was declared in, which
+// decides the fields it can see and what 'super' refers to.
+type BoundMethod struct {
+	Decl *MethodDecl
+	Host *ClassVal
+}
+
+func (m *BoundMethod) String() string {
+	return m.Host.Name + "." + m.Decl.Name
+}
+
+// ObjectVal is an instance of a class.  Each field is a mutable reference
+// shared by the environments of the object's methods, so 'set' on a field
+// updates the object.
+type ObjectVal struct {
+	Class  *ClassVal
+	Fields []*epl.Ref[any]
+}
+
+func (v *ObjectVal) String() string {
+	return fmt.Sprintf("<object %s>", v.Class.Name)
+}
+
+func (v *ObjectVal) Repr() string {
+	fields := make([]string, len(v.Fields))
+	for i, f := range v.Fields {
+		fields[i] = fmt.Sprintf("%s = %s", v.Class.Fields[i], ReprOf(f.Value))
+	}
+	return fmt.Sprintf("Object(%s, {%s})", v.Class.Name, strings.Join(fields, ", "))
+}
+
+// Eq is true only for the same object.
+func (v *ObjectVal) Eq(another Value) bool {
+	a, ok := another.(*ObjectVal)
+	return ok && a == v
+}
+
+// Names bound in method environments.  Neither is a valid identifier so
+// programs cannot shadow them.
+const (
+	selfKey = "%self"
+	hostKey = "%host"
+)
+
+// classKey is the name a class is bound to in the environment.  Class names
+// are a separate namespace from variables.
+func classKey(name string) string {
+	return "class " + name
+}
+
+// ClassLangEval extends ImpRefLangEval with classes and objects.
+type ClassLangEval struct {
+	chapter4.ImpRefLangEval
+}
+
+// NewClassLangEval creates a new evaluator for the classes language.
+func NewClassLangEval() *ClassLangEval {
+	out := &ClassLangEval{}
+	// CRITICAL: Set the Self pointer for the embedded BaseEval
+	out.BaseEval.Self = out
+	return out
+}
+
+// LocalEval handles expression types specific to the classes language or
+// delegates.
+func (l *ClassLangEval) LocalEval(expr Expr, env *epl.Env[any]) (any, error) {
+	switch n := expr.(type) {
+	case *ProgramExpr:
+		return l.ValueOfProgram(n, env)
+	case *NewObjectExpr:
+		return l.ValueOfNew(n, env)
+	case *MethodCallExpr:
+		return l.ValueOfMethodCall(n, env)
+	case *SuperCallExpr:
+		return l.ValueOfSuperCall(n, env)
+	case *SelfExpr:
+		self, found := env.Get(selfKey)
+		if !found {
+			return nil, UnboundVariableError{Name: "self"}
+		}
+		return self, nil
+	default:
+		return l.ImpRefLangEval.LocalEval(expr, env)
+	}
+}
+
+// ValueOfProgram declares each class in turn, so that a class can only
+// extend those declared before it, and then evaluates the body.
+func (l *ClassLangEval) ValueOfProgram(e *ProgramExpr, env *epl.Env[any]) (any, error) {
+	newenv := env.Push()
+	for _, decl := range e.Classes {
+		class, err := l.DeclareClass(decl, newenv)
+		if err != nil {
+			return nil, err
+		}
+		newenv.Set(classKey(decl.Name), class)
+	}
+	return l.Eval(e.Body, newenv)
+}
+
+// DeclareClass builds a class from its declaration.  A field with the same
+// name as one in the superclass shadows it - the superclass's field is
+// renamed, as in EOPL, so that its methods still see their own field.
+func (l *ClassLangEval) DeclareClass(d *ClassDecl, env *epl.Env[any]) (*ClassVal, error) {
+	super, err := l.lookupClass(d.Super, env)
+	if err != nil {
+		return nil, err
+	}
+	class := &ClassVal{Name: d.Name, Super: super, Fields: slices.Clone(super.Fields), Env: env}
+	for _, f := range d.Fields {
+		if i := slices.Index(class.Fields, f); i >= 0 {
+			class.Fields[i] = fmt.Sprintf("%s%%%d", f, i)
+		}
+		class.Fields = append(class.Fields, f)
+	}
+	class.Methods = maps.Clone(super.Methods)
+	for _, m := range d.Methods {
+		class.Methods[m.Name] = &BoundMethod{Decl: m, Host: class}
+	}
+	return class, nil
+}
+
+func (l *ClassLangEval) lookupClass(name string, env *epl.Env[any]) (*ClassVal, error) {
+	if name == ObjectClass.Name {
+		return ObjectClass, nil
+	}
+	class, found := env.Get(classKey(name))
+	if !found {
+		return nil, UnknownClassError{Name: name}
+	}
+	return class.(*ClassVal), nil
+}
+
+// ValueOfNew creates an object and calls its initialize method with the
+// arguments.  Fields start as 0 until initialized.
+func (l *ClassLangEval) ValueOfNew(e *NewObjectExpr, env *epl.Env[any]) (any, error) {
+	class, err := l.lookupClass(e.Class, env)
+	if err != nil {
+		return nil, err
+	}
+	args, err := l.EvalExprList(e.Args, env)
+	if err != nil {
+		return nil, l.WrapError(err, "evaluating arguments for new %s", e.Class)
+	}
+	obj := &ObjectVal{Class: class, Fields: make([]*epl.Ref[any], len(class.Fields))}
+	for i := range obj.Fields {
+		obj.Fields[i] = &epl.Ref[any]{Value: IntVal(0)}
+	}
+	if init, ok := class.Methods["initialize"]; ok {
+		if _, err := l.ApplyMethod(init, obj, args); err != nil {
+			return nil, err
+		}
+	} else if len(args) > 0 {
+		return nil, ArityError{Context: "new " + class.Name, Expected: 0, Found: len(args)}
+	}
+	return obj, nil
+}
+
+// ValueOfMethodCall evaluates 'send obj m (args)', finding m in the class of
+// the object.
+func (l *ClassLangEval) ValueOfMethodCall(e *MethodCallExpr, env *epl.Env[any]) (any, error) {
+	val, err := l.Eval(e.Object, env)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := val.(*ObjectVal)
+	if !ok {
+		return nil, TypeMismatchError{Context: "send " + e.Method, Expected: "an object", Found: val}
+	}
+	args, err := l.EvalExprList(e.Args, env)
+	if err != nil {
+		return nil, l.WrapError(err, "evaluating arguments for send %s", e.Method)
+	}
+	method, ok := obj.Class.Methods[e.Method]
+	if !ok {
+		return nil, UnknownMethodError{Class: obj.Class.Name, Method: e.Method}
+	}
+	return l.ApplyMethod(method, obj, args)
+}
+
+// ValueOfSuperCall evaluates 'super m (args)' on the current object.
+func (l *ClassLangEval) ValueOfSuperCall(e *SuperCallExpr, env *epl.Env[any]) (any, error) {
+	self, found := env.Get(selfKey)
+	if !found {
+		return nil, UnboundVariableError{Name: "self"}
+	}
+	host, _ := env.Get(hostKey)
+	super := host.(*ClassVal).Super
+	args, err := l.EvalExprList(e.Args, env)
+	if err != nil {
+		return nil, l.WrapError(err, "evaluating arguments for super %s", e.Method)
+	}
+	method, ok := super.Methods[e.Method]
+	if !ok {
+		return nil, UnknownMethodError{Class: super.Name, Method: e.Method}
+	}
+	return l.ApplyMethod(method, self.(*ObjectVal), args)
+}
+
+// ApplyMethod evaluates the body of a method with self bound to an object.
+// The method sees the fields of the class it was declared in, its parameters
+// and the variables in scope where its class was declared.
+func (l *ClassLangEval) ApplyMethod(method *BoundMethod, self *ObjectVal, args []any) (any, error) {
+	if len(args) != len(method.Decl.Params) {
+		return nil, ArityError{Context: "Method " + method.String(), Expected: len(method.Decl.Params), Found: len(args)}
+	}
+	env := method.Host.Env.Push()
+	for i, f := range method.Host.Fields {
+		env.SetRef(f, self.Fields[i])
+	}
+	env.Set(selfKey, self)
+	env.Set(hostKey, method.Host)
+	env = env.Extend(epl.DictZip(method.Decl.Params, args))
+
+	l.PushFrame(chapter3.Frame{ProcName: method.String()})
+	defer l.PopFrame()
+	return l.Eval(method.Decl.Body, env)
+}
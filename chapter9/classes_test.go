@@ -0,0 +1,222 @@
+package chapter9
+
+import (
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func NewTestClassLangEval() Evaluator {
+	return SetListOpFuncs(SetOpFuncs(NewClassLangEval()))
+}
+
+func evalProgram(t *testing.T, expr Expr) (any, error) {
+	t.Helper()
+	return NewTestClassLangEval().Eval(expr, epl.NewEnv[any](nil))
+}
+
+// RunClassTest evaluates a test case expecting either a value or, if
+// Expected is an error, that error.
+func RunClassTest(t *testing.T, tc *TestCase) {
+	t.Helper()
+	value, err := evalProgram(t, tc.Expr)
+	if expectedErr, ok := tc.Expected.(error); ok {
+		assert.ErrorIs(t, err, expectedErr, "Test %s", tc.Name)
+		return
+	}
+	require.NoError(t, err, "Test %s Failed - Unexpected error", tc.Name)
+	if expected, ok := tc.Expected.(string); ok {
+		// Compare structured results by how they print
+		assert.Equal(t, expected, value.(Value).String(), "Test %s", tc.Name)
+		return
+	}
+	AssertValue(t, tc.Name, tc.Expected, value)
+}
+
+// Ported from the CLASSES examples in EOPL chapter 9
+
+// class point extends object
+//
+//	field x
+//	field y
+//	method initialize (initx, inity) begin set x = initx; set y = inity end
+//	method move (dx, dy) begin set x = +(x, dx); set y = +(y, dy) end
+//	method get_location () list(x, y)
+//
+// class colorpoint extends point
+//
+//	field color
+//	method set_color (c) set color = c
+//	method get_color () color
+func pointClasses() []*ClassDecl {
+	return []*ClassDecl{
+		Class("point", "object", []string{"x", "y"},
+			Method("initialize", []string{"initx", "inity"}, Begin(Assign("x", "initx"), Assign("y", "inity"))),
+			Method("move", []string{"dx", "dy"}, Begin(Assign("x", Op("+", "x", "dx")), Assign("y", Op("+", "y", "dy")))),
+			Method("get_location", nil, List("x", "y"))),
+		Class("colorpoint", "point", []string{"color"},
+			Method("set_color", []string{"c"}, Assign("color", "c")),
+			Method("get_color", nil, "color")),
+	}
+}
+
+func TestPointColorPoint(t *testing.T) {
+	// let p = new point(3, 4)
+	//     cp = new colorpoint(10, 20)
+	// in begin
+	//     send p move(3, 4);
+	//     send cp set_color(87);
+	//     send cp move(10, 20);
+	//     list(send p get_location(), send cp get_location(), send cp get_color())
+	// end
+	expr := Program(pointClasses(),
+		Let(ExprDict("p", New("point", 3, 4), "cp", New("colorpoint", 10, 20)),
+			Begin(
+				Send("p", "move", 3, 4),
+				Send("cp", "set_color", 87),
+				Send("cp", "move", 10, 20),
+				List(Send("p", "get_location"), Send("cp", "get_location"), Send("cp", "get_color")))))
+	RunClassTest(t, &TestCase{Name: "colorpoint", Expected: "[[6, 8], [20, 40], 87]", Expr: expr})
+}
+
+func TestOddEven(t *testing.T) {
+	// class oddeven extends object
+	//   method initialize () 1
+	//   method even (n) if isz(n) then 1 else send self odd(-(n, 1))
+	//   method odd (n) if isz(n) then 0 else send self even(-(n, 1))
+	// let o1 = new oddeven() in send o1 odd(13)
+	expr := Program([]*ClassDecl{
+		Class("oddeven", "object", nil,
+			Method("initialize", nil, 1),
+			Method("even", []string{"n"}, If(IsZero("n"), 1, Send(Self(), "odd", Op("-", "n", 1)))),
+			Method("odd", []string{"n"}, If(IsZero("n"), 0, Send(Self(), "even", Op("-", "n", 1))))),
+	}, Let(ExprDict("o1", New("oddeven")), Send("o1", "odd", 13)))
+	RunClassTest(t, &TestCase{Name: "oddeven", Expected: 1, Expr: expr})
+}
+
+func TestDynamicDispatchAndSuper(t *testing.T) {
+	// class c1 extends object
+	//   method initialize () 1
+	//   method m1 () send self m2()
+	//   method m2 () 13
+	// class c2 extends c1
+	//   method m1 () 22
+	//   method m2 () 23
+	//   method m3 () super m1()
+	// class c3 extends c2
+	//   method m1 () 32
+	//   method m2 () 33
+	classes := []*ClassDecl{
+		Class("c1", "object", nil,
+			Method("initialize", nil, 1),
+			Method("m1", nil, Send(Self(), "m2")),
+			Method("m2", nil, 13)),
+		Class("c2", "c1", nil,
+			Method("m1", nil, 22),
+			Method("m2", nil, 23),
+			Method("m3", nil, Super("m1"))),
+		Class("c3", "c2", nil,
+			Method("m1", nil, 32),
+			Method("m2", nil, 33)),
+	}
+	// super m1 is c1's m1 which sends m2 to self - a c3
+	RunClassTest(t, &TestCase{Name: "super", Expected: 33,
+		Expr: Program(classes, Let(ExprDict("o3", New("c3")), Send("o3", "m3")))})
+	RunClassTest(t, &TestCase{Name: "inherited", Expected: 23,
+		Expr: Program(classes, Send(New("c2"), "m3"))})
+}
+
+func TestFieldShadowing(t *testing.T) {
+	// class c1 extends object
+	//   field x
+	//   field y
+	//   method initialize () begin set x = 11; set y = 12 end
+	//   method m1 () list(x, y)
+	// class c2 extends c1
+	//   field y
+	//   method initialize () begin super initialize(); set y = 22 end
+	//   method m2 () list(x, y)
+	// class c3 extends c2
+	//   field x
+	//   field z
+	//   method initialize () begin super initialize(); set x = 31; set z = 32 end
+	//   method m3 () list(x, y, z)
+	classes := []*ClassDecl{
+		Class("c1", "object", []string{"x", "y"},
+			Method("initialize", nil, Begin(Assign("x", 11), Assign("y", 12))),
+			Method("m1", nil, List("x", "y"))),
+		Class("c2", "c1", []string{"y"},
+			Method("initialize", nil, Begin(Super("initialize"), Assign("y", 22))),
+			Method("m2", nil, List("x", "y"))),
+		Class("c3", "c2", []string{"x", "z"},
+			Method("initialize", nil, Begin(Super("initialize"), Assign("x", 31), Assign("z", 32))),
+			Method("m3", nil, List("x", "y", "z"))),
+	}
+	expr := Program(classes, Let(ExprDict("o3", New("c3")),
+		List(Send("o3", "m1"), Send("o3", "m2"), Send("o3", "m3"))))
+	RunClassTest(t, &TestCase{Name: "shadowing", Expected: "[[11, 12], [11, 22], [31, 22, 32]]", Expr: expr})
+
+	val, err := evalProgram(t, Program(classes, New("c3")))
+	require.NoError(t, err)
+	assert.Equal(t, "Object(c3, {x%0 = Int(11), y%1 = Int(12), y = Int(22), x = Int(31), z = Int(32)})", val.(Value).Repr())
+}
+
+func TestMethodsSeeDeclarationScope(t *testing.T) {
+	// Methods close over the variables in scope where the class is declared,
+	// not where they are called
+	classes := []*ClassDecl{
+		Class("counter", "object", []string{"n"},
+			Method("inc", nil, Begin(Assign("n", Op("+", "n", "step")), "n"))),
+	}
+	expr := Let(ExprDict("step", Lit(5)), Program(classes,
+		Let(ExprDict("c", New("counter"), "step", Lit(100)),
+			Begin(Send("c", "inc"), Send("c", "inc")))))
+	RunClassTest(t, &TestCase{Name: "scope", Expected: 10, Expr: expr})
+}
+
+func TestClassErrors(t *testing.T) {
+	cases := []TestCase{
+		{Name: "unknown class", Expected: UnknownClassError{Name: "point3d"},
+			Expr: Program(pointClasses(), New("point3d", 1, 2, 3))},
+		{Name: "unknown superclass", Expected: UnknownClassError{Name: "shape"},
+			Expr: Program([]*ClassDecl{Class("square", "shape", nil)}, 1)},
+		{Name: "unknown method", Expected: UnknownMethodError{Class: "point", Method: "get_color"},
+			Expr: Program(pointClasses(), Send(New("point", 1, 2), "get_color"))},
+		{Name: "unknown super method", Expected: UnknownMethodError{Class: "object", Method: "initialize"},
+			Expr: Program([]*ClassDecl{Class("c", "object", nil, Method("initialize", nil, Super("initialize")))}, New("c"))},
+		{Name: "arity", Expected: ArityError{Context: "Method point.move", Expected: 2, Found: 1},
+			Expr: Program(pointClasses(), Send(New("point", 1, 2), "move", 1))},
+		{Name: "no initialize", Expected: ArityError{Context: "new c", Expected: 0, Found: 1},
+			Expr: Program([]*ClassDecl{Class("c", "object", nil)}, New("c", 1))},
+		{Name: "self outside method", Expected: UnboundVariableError{Name: "self"},
+			Expr: Program(nil, Self())},
+		{Name: "private field", Expected: UnboundVariableError{Name: "x"},
+			Expr: Program(pointClasses(), Begin(New("point", 1, 2), "x"))},
+	}
+	for _, tc := range cases {
+		RunClassTest(t, &tc)
+	}
+
+	_, err := evalProgram(t, Program(nil, Send(3, "m")))
+	var mismatch TypeMismatchError
+	assert.ErrorAs(t, err, &mismatch)
+}
+
+func TestClassErrorsAreRuntimeErrors(t *testing.T) {
+	_, err := evalProgram(t, Program(pointClasses(), Send(New("point", 1, 2), "get_color")))
+	var rt RuntimeError
+	assert.ErrorAs(t, err, &rt)
+	_, err = evalProgram(t, Program(pointClasses(), New("point3d")))
+	assert.ErrorAs(t, err, &rt)
+}
+
+func TestClassEq(t *testing.T) {
+	p1 := Program(pointClasses(), Send(New("point", 1, 2), "move", 3, 4))
+	assert.True(t, ExprEq(p1, Program(pointClasses(), Send(New("point", 1, 2), "move", 3, 4))))
+	assert.False(t, ExprEq(p1, Program(pointClasses(), Send(New("point", 1, 2), "move", 3, 5))))
+	assert.False(t, ExprEq(p1, Program(pointClasses()[:1], Send(New("point", 1, 2), "move", 3, 4))))
+	assert.True(t, ExprEq(Super("m", Self()), Super("m", Self())))
+	assert.Equal(t, "<Send <New point(Val(1:int), Val(2:int))>.move(Val(3:int))>", Send(New("point", 1, 2), "move", 3).Repr())
+}
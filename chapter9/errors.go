@@ -0,0 +1,30 @@
+package chapter9
+
+import (
+	"fmt"
+
+	"github.com/panyam/eplgo/chapter3"
+)
+
+// UnknownClassError is returned for a class that has not been declared, by
+// 'new' or as the superclass in a declaration.
+type UnknownClassError struct {
+	chapter3.RuntimeErrorBase
+	Name string
+}
+
+func (e UnknownClassError) Error() string {
+	return fmt.Sprintf("class '%s' is not defined", e.Name)
+}
+
+// UnknownMethodError is returned when neither a class nor any of its
+// superclasses define a method.
+type UnknownMethodError struct {
+	chapter3.RuntimeErrorBase
+	Class  string
+	Method string
+}
+
+func (e UnknownMethodError) Error() string {
+	return fmt.Sprintf("class '%s' has no method '%s'", e.Class, e.Method)
+}
@@ -0,0 +1,49 @@
+package chapter9
+
+import (
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+	"github.com/panyam/eplgo/chapter4"
+)
+
+// A few imports to not avoid having to prefix with chapter3 all over the place
+type Expr = chapter3.Expr
+type LitExpr = chapter3.LitExpr
+type VarExpr = chapter3.VarExpr
+
+var ExprDict = epl.Dict[string, Expr]
+
+type Value = chapter3.Value
+type IntVal = chapter3.IntVal
+type BoolVal = chapter3.BoolVal
+type ListVal = chapter3.ListVal
+
+type TestCase = chapter3.TestCase
+type Evaluator = chapter3.Evaluator
+
+type UnboundVariableError = chapter3.UnboundVariableError
+type TypeMismatchError = chapter3.TypeMismatchError
+type RuntimeError = chapter3.RuntimeError
+type ArityError = chapter3.ArityError
+
+var SetOpFuncs = chapter3.SetOpFuncs
+var SetListOpFuncs = chapter3.SetListOpFuncs
+var List = chapter3.List
+var Lit = chapter3.Lit
+var Let = chapter3.Let
+var Op = chapter3.Op
+var If = chapter3.If
+var IsZero = chapter3.IsZero
+var Proc = chapter3.Proc
+var Call = chapter3.Call
+var Var = chapter3.Var
+var AnyToExpr = chapter3.AnyToExpr
+var ExprEq = chapter3.ExprEq
+var ExprListEq = chapter3.ExprListEq
+var ExprListRepr = chapter3.ExprListRepr
+var ExprListPrintable = chapter3.ExprListPrintable
+var AssertValue = chapter3.AssertValue
+var ReprOf = chapter3.ReprOf
+
+var Begin = chapter4.Begin
+var Assign = chapter4.Assign
@@ -0,0 +1,219 @@
+// Package coverage records how often each AST node of an EPL program is
+// evaluated and reports which branches, procedures and handlers were never
+// reached.  Reports are rendered as an annotated Printable tree or in the lcov
+// tracefile format.
+package coverage
+
+import (
+	"fmt"
+	"io"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+	"github.com/panyam/eplgo/chapter5"
+	"github.com/panyam/eplgo/chapter7"
+)
+
+// Coverable is the part of an evaluator coverage needs.  All evaluators
+// embedding chapter3.BaseEval satisfy it.
+type Coverable interface {
+	AddObserver(chapter3.Observer)
+	RemoveObserver(chapter3.Observer)
+}
+
+// Coverage is a chapter3.Observer that counts evaluations of every node.
+// Nodes are identified by pointer so the same program must be passed to the
+// report methods as was evaluated.
+type Coverage struct {
+	chapter3.NopObserver
+	eval Coverable
+	hits map[Expr]int
+}
+
+// New creates a Coverage and starts recording hits on the given evaluator.
+func New(eval Coverable) *Coverage {
+	c := &Coverage{eval: eval, hits: map[Expr]int{}}
+	eval.AddObserver(c)
+	return c
+}
+
+// Stop detaches the Coverage from its evaluator.  Recorded hits are kept.
+func (c *Coverage) Stop() {
+	c.eval.RemoveObserver(c)
+}
+
+// Reset clears all recorded hits.
+func (c *Coverage) Reset() {
+	c.hits = map[Expr]int{}
+}
+
+func (c *Coverage) EnterExpr(expr Expr, env *epl.Env[any]) {
+	c.hits[expr]++
+}
+
+// Hits returns the number of times a node was evaluated.
+func (c *Coverage) Hits(expr Expr) int {
+	return c.hits[expr]
+}
+
+// Uncovered returns the nodes under root that were never evaluated, in
+// pre-order.
+func (c *Coverage) Uncovered(root Expr) (out []Expr) {
+	for e := range Walk(root) {
+		if c.hits[e] == 0 {
+			out = append(out, e)
+		}
+	}
+	return
+}
+
+// Branch is one arm of a node that chooses between alternatives - the then
+// and else of an IfExpr, the body and handler of a TryExpr or an arm of a
+// CasesExpr or MatchExpr.
+type Branch struct {
+	Node Expr
+	// "then", "else", "try", "catch", the tag of a cases arm or the pattern
+	// of a match arm
+	Name string
+	Expr Expr
+	Hits int
+}
+
+// Branches returns the arms of all If, Try, Cases and Match nodes under root
+// in pre-order.
+func (c *Coverage) Branches(root Expr) (out []Branch) {
+	add := func(node Expr, name string, arm Expr) {
+		out = append(out, Branch{node, name, arm, c.hits[arm]})
+	}
+	for e := range Walk(root) {
+		switch n := e.(type) {
+		case *chapter3.IfExpr:
+			add(n, "then", n.Then)
+			add(n, "else", n.Else)
+		case *chapter5.TryExpr:
+			add(n, "try", n.TryBody)
+			add(n, "catch", n.HandlerExpr)
+		case *chapter7.CasesExpr:
+			for _, arm := range n.Arms {
+				add(n, arm.Tag, arm.Body)
+			}
+			if n.Else != nil {
+				add(n, "else", n.Else)
+			}
+		case *chapter7.MatchExpr:
+			for _, arm := range n.Arms {
+				add(n, arm.Pattern.String(), arm.Body)
+			}
+		}
+	}
+	return
+}
+
+// Function is a procedure bound by a LetRecExpr.  Its hits are the number of
+// times its body was evaluated, ie the number of complete applications.
+type Function struct {
+	Name string
+	Proc *chapter3.ProcExpr
+	Hits int
+}
+
+// Functions returns the letrec bound procedures under root in pre-order.
+func (c *Coverage) Functions(root Expr) (out []Function) {
+	for e := range Walk(root) {
+		if n, ok := e.(*chapter3.LetRecExpr); ok {
+			for _, name := range epl.SortedKeys(n.Procs) {
+				proc := n.Procs[name]
+				out = append(out, Function{name, proc, c.hits[proc.Body]})
+			}
+		}
+	}
+	return
+}
+
+// Annotate returns the program as a tree with one line per node, prefixed by
+// the number of times the node was evaluated ("#####" if never).
+func (c *Coverage) Annotate(root Expr) *epl.Printable {
+	return c.annotate("", root)
+}
+
+func (c *Coverage) annotate(role string, expr Expr) *epl.Printable {
+	count := "#####"
+	if hits := c.hits[expr]; hits > 0 {
+		count = fmt.Sprintf("%5d", hits)
+	}
+	if role != "" {
+		role += " "
+	}
+	line := epl.Printablef(0, "%s: %s%s", count, role, Header(expr))
+	children := Children(expr)
+	if len(children) == 0 {
+		return line
+	}
+	return epl.PrintableIter(func(yield func(*epl.Printable) bool) {
+		if !yield(line) {
+			return
+		}
+		for _, child := range children {
+			p := c.annotate(child.Role+":", child.Expr)
+			p.IndentLevel = 1
+			if !yield(p) {
+				return
+			}
+		}
+	})
+}
+
+// WriteLCOV writes an lcov tracefile record for the program rooted at root
+// under the given source file name.  EPL nodes carry no source positions so
+// "line" numbers are the 1 based pre-order indexes of nodes - the same order
+// the lines of Annotate appear in.
+func (c *Coverage) WriteLCOV(w io.Writer, sourceFile string, root Expr) {
+	lines := map[Expr]int{}
+	var nodes []Expr
+	for e := range Walk(root) {
+		nodes = append(nodes, e)
+		lines[e] = len(nodes)
+	}
+
+	fmt.Fprintln(w, "TN:")
+	fmt.Fprintf(w, "SF:%s\n", sourceFile)
+
+	funcs := c.Functions(root)
+	fnHit := 0
+	for _, f := range funcs {
+		fmt.Fprintf(w, "FN:%d,%s\n", lines[f.Proc.Body], f.Name)
+	}
+	for _, f := range funcs {
+		fmt.Fprintf(w, "FNDA:%d,%s\n", f.Hits, f.Name)
+		if f.Hits > 0 {
+			fnHit++
+		}
+	}
+	fmt.Fprintf(w, "FNF:%d\nFNH:%d\n", len(funcs), fnHit)
+
+	branches := c.Branches(root)
+	brHit := 0
+	armIndex := map[Expr]int{}
+	for _, b := range branches {
+		taken := "-"
+		if c.hits[b.Node] > 0 {
+			taken = fmt.Sprintf("%d", b.Hits)
+		}
+		if b.Hits > 0 {
+			brHit++
+		}
+		fmt.Fprintf(w, "BRDA:%d,0,%d,%s\n", lines[b.Node], armIndex[b.Node], taken)
+		armIndex[b.Node]++
+	}
+	fmt.Fprintf(w, "BRF:%d\nBRH:%d\n", len(branches), brHit)
+
+	linesHit := 0
+	for i, e := range nodes {
+		fmt.Fprintf(w, "DA:%d,%d\n", i+1, c.hits[e])
+		if c.hits[e] > 0 {
+			linesHit++
+		}
+	}
+	fmt.Fprintf(w, "LF:%d\nLH:%d\n", len(nodes), linesHit)
+	fmt.Fprintln(w, "end_of_record")
+}
@@ -0,0 +1,237 @@
+package coverage
+
+import (
+	"bytes"
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+	"github.com/panyam/eplgo/chapter4"
+	"github.com/panyam/eplgo/chapter5"
+	"github.com/panyam/eplgo/chapter7"
+	"github.com/panyam/eplgo/chapter8"
+	"github.com/panyam/eplgo/chapter9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	Call   = chapter3.Call
+	If     = chapter3.If
+	IsZero = chapter3.IsZero
+	LetRec = chapter3.LetRec
+	Op     = chapter3.Op
+	Proc   = chapter3.Proc
+	Raise  = chapter5.Raise
+	Try    = chapter5.Try
+)
+
+type coverableEval interface {
+	chapter3.Evaluator
+	Coverable
+}
+
+func run(t *testing.T, expr Expr) *Coverage {
+	e := chapter5.NewTryLangEval()
+	chapter3.SetOpFuncs(e)
+	return runOn(t, e, expr)
+}
+
+func runOn(t *testing.T, e coverableEval, expr Expr) *Coverage {
+	c := New(e)
+	_, err := e.Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	c.Stop()
+	return c
+}
+
+// letrec double(x) = if isz(x) then 0 else -((double -(x,1)), -2)
+//
+//	unused(x) = x
+//
+// in (double 1)
+func doubleProgram() *chapter3.LetRecExpr {
+	return LetRec(chapter3.ProcMap(
+		"double", Proc([]string{"x"}, If(IsZero("x"), 0, Op("-", Call("double", Op("-", "x", 1)), -2))),
+		"unused", Proc([]string{"x"}, chapter3.Var("x"))),
+		Call("double", 1))
+}
+
+func TestFunctionsAndBranches(t *testing.T) {
+	prog := doubleProgram()
+	c := run(t, prog)
+
+	funcs := c.Functions(prog)
+	require.Len(t, funcs, 2)
+	assert.Equal(t, "double", funcs[0].Name)
+	assert.Equal(t, 2, funcs[0].Hits)
+	assert.Equal(t, "unused", funcs[1].Name)
+	assert.Equal(t, 0, funcs[1].Hits)
+
+	ifExpr := prog.Procs["double"].Body.(*chapter3.IfExpr)
+	assert.Equal(t, 2, c.Hits(ifExpr))
+	branches := c.Branches(prog)
+	require.Len(t, branches, 2)
+	assert.Equal(t, Branch{ifExpr, "then", ifExpr.Then, 1}, branches[0])
+	assert.Equal(t, Branch{ifExpr, "else", ifExpr.Else, 1}, branches[1])
+
+	assert.Equal(t, []Expr{prog.Procs["unused"].Body}, c.Uncovered(prog))
+}
+
+func TestTryHandlers(t *testing.T) {
+	caught := Try(Raise(1), "e", "e")
+	notCaught := Try(5, "e", 0)
+	prog := Op("+", caught, notCaught)
+	c := run(t, prog)
+
+	branches := c.Branches(prog)
+	require.Len(t, branches, 4)
+	assert.Equal(t, "catch", branches[1].Name)
+	assert.Equal(t, 1, branches[1].Hits)
+	assert.Equal(t, "catch", branches[3].Name)
+	assert.Equal(t, 0, branches[3].Hits)
+	assert.Equal(t, []Expr{notCaught.HandlerExpr}, c.Uncovered(prog))
+}
+
+func TestTuples(t *testing.T) {
+	// unpack x y = (0, 1) in if isz(x) then y else (x, y).1
+	tuple := chapter3.Tuple(chapter3.Var("x"), chapter3.Var("y"))
+	notTaken := chapter3.TupleRef(tuple, 1)
+	prog := chapter3.Unpack([]string{"x", "y"}, chapter3.Tuple(chapter3.Lit(0), chapter3.Lit(1)), If(IsZero("x"), "y", notTaken))
+	c := run(t, prog)
+	assert.Equal(t, []Expr{notTaken, tuple, tuple.Children[0], tuple.Children[1]}, c.Uncovered(prog))
+	assert.Equal(t, "x y", Children(prog)[0].Role)
+}
+
+func TestRecords(t *testing.T) {
+	// let r = {x = 0} in if isz(r.x) then {r with x = 1} else set r.x = 2
+	set := chapter4.SetField("r", "x", 2)
+	update := chapter3.Update("r", chapter3.ExprDict("x", chapter3.Lit(1)))
+	prog := chapter3.Let(chapter3.ExprDict("r", chapter3.Record(chapter3.ExprDict("x", chapter3.Lit(0)))),
+		If(IsZero(chapter3.Field("r", "x")), update, set))
+	c := run(t, prog)
+	assert.Equal(t, []Expr{set, set.Record, set.Expr}, c.Uncovered(prog))
+	assert.Equal(t, 1, c.Hits(update.Fields["x"]))
+}
+
+func TestCases(t *testing.T) {
+	// cases Circle 3 of Circle (r) => r Square (s) => -(s, 1) else 0
+	circle := chapter7.Arm("Circle", "r", "r")
+	square := chapter7.Arm("Square", "s", Op("-", "s", 1))
+	prog := chapter7.Cases(chapter7.Tag("Circle", 3), []*chapter7.CaseArm{circle, square}, 0)
+	e := chapter7.NewVariantLangEval()
+	chapter3.SetOpFuncs(e)
+	c := runOn(t, e, prog)
+
+	assert.Equal(t, []Expr{square.Body, square.Body.(*chapter3.OpExpr).Args[0], square.Body.(*chapter3.OpExpr).Args[1], prog.Else}, c.Uncovered(prog))
+	assert.Equal(t, []Branch{
+		{prog, "Circle", circle.Body, 1},
+		{prog, "Square", square.Body, 0},
+		{prog, "else", prog.Else, 0},
+	}, c.Branches(prog))
+	assert.Equal(t, "Circle (r)", Children(prog)[1].Role)
+}
+
+func TestMatch(t *testing.T) {
+	// define-datatype shape = Circle (int) | Square (int)
+	// in match Square(2) with Circle (r) => r | Square (s) => -(s, 1) end
+	circle := chapter7.MatchCase(chapter7.PatCons("Circle", chapter7.PatVar("r")), "r")
+	square := chapter7.MatchCase(chapter7.PatCons("Square", chapter7.PatVar("s")), Op("-", "s", 1))
+	match := chapter7.Match(Call("Square", 2), circle, square)
+	prog := chapter7.DataProgram([]*chapter7.DatatypeDecl{
+		chapter7.Datatype("shape", nil, chapter7.Variant("Circle", chapter7.Int), chapter7.Variant("Square", chapter7.Int)),
+	}, match)
+	e := chapter7.NewDataLangEval()
+	chapter3.SetOpFuncs(e)
+	c := runOn(t, e, prog)
+
+	assert.Equal(t, []Expr{circle.Body}, c.Uncovered(prog))
+	assert.Equal(t, []Branch{
+		{match, "Circle (r)", circle.Body, 0},
+		{match, "Square (s)", square.Body, 1},
+	}, c.Branches(prog))
+}
+
+func TestModules(t *testing.T) {
+	// module m interface [f : (int -> int)]
+	//   body [unused = proc (x) x, f = proc (x) -(x, 1)]
+	// in (from m take f 3)
+	unused := Proc([]string{"x"}, chapter3.Var("x"))
+	f := Proc([]string{"x"}, Op("-", "x", 1))
+	m := chapter8.Module("m", []*chapter8.VarDecl{chapter8.Decl("f", chapter8.ProcOf(chapter8.IntType{}, chapter8.IntType{}))},
+		[]*chapter8.VarDefn{chapter8.Defn("unused", unused), chapter8.Defn("f", f)})
+	prog := chapter8.Program([]*chapter8.ModuleDefn{m}, Call(chapter8.QualifiedVar("m", "f"), 3))
+	e := chapter8.NewModuleLangEval()
+	chapter3.SetOpFuncs(e)
+	c := runOn(t, e, prog)
+
+	assert.Equal(t, []Expr{unused.Body}, c.Uncovered(prog))
+	assert.Equal(t, 1, c.Hits(f.Body))
+	assert.Equal(t, "m.unused", Children(prog)[0].Role)
+}
+
+func TestClasses(t *testing.T) {
+	// class c extends object
+	//   method initialize () 0
+	//   method m (x) -(x, 1)
+	//   method unused () 0
+	// class d extends c
+	//   method m (x) super m(x)
+	// in send new d() m(5)
+	super := chapter9.Super("m", "x")
+	unused := chapter9.Method("unused", nil, 0)
+	prog := chapter9.Program([]*chapter9.ClassDecl{
+		chapter9.Class("c", "object", nil,
+			chapter9.Method("initialize", nil, 0),
+			chapter9.Method("m", []string{"x"}, Op("-", "x", 1)),
+			unused),
+		chapter9.Class("d", "c", nil, chapter9.Method("m", []string{"x"}, super)),
+	}, chapter9.Send(chapter9.New("d"), "m", 5))
+	e := chapter9.NewClassLangEval()
+	chapter3.SetOpFuncs(e)
+	c := runOn(t, e, prog)
+
+	assert.Equal(t, []Expr{unused.Body}, c.Uncovered(prog))
+	assert.Equal(t, 1, c.Hits(super.Args[0]))
+	assert.Equal(t, "c.m(x)", Children(prog)[1].Role)
+}
+
+func TestAnnotate(t *testing.T) {
+	prog := doubleProgram()
+	c := run(t, prog)
+	var out bytes.Buffer
+	c.Annotate(prog).Fprint(&out)
+	assert.Equal(t, `    1: LetRec
+      2: double(x): If
+        2: cond: IsZero
+          2: expr: var x
+        1: then: Val(0:int)
+        1: else: Op<->
+          1: arg 0: Call
+            1: operator: var double
+            1: arg 0: Op<->
+              1: arg 0: var x
+              1: arg 1: Val(1:int)
+          1: arg 1: Val(-2:int)
+  #####: unused(x): var x
+      1: in: Call
+        1: operator: var double
+        1: arg 0: Val(1:int)
+`, out.String())
+}
+
+func TestWriteLCOV(t *testing.T) {
+	prog := doubleProgram()
+	c := run(t, prog)
+	var out bytes.Buffer
+	c.WriteLCOV(&out, "double.epl", prog)
+	lcov := out.String()
+	assert.Contains(t, lcov, "SF:double.epl\n")
+	assert.Contains(t, lcov, "FN:2,double\nFN:13,unused\n")
+	assert.Contains(t, lcov, "FNDA:2,double\nFNDA:0,unused\nFNF:2\nFNH:1\n")
+	assert.Contains(t, lcov, "BRDA:2,0,0,1\nBRDA:2,0,1,1\nBRF:2\nBRH:2\n")
+	assert.Contains(t, lcov, "DA:1,1\nDA:2,2\n")
+	assert.Contains(t, lcov, "DA:13,0\n")
+	assert.Contains(t, lcov, "LF:16\nLH:15\n")
+	assert.True(t, bytes.HasSuffix(out.Bytes(), []byte("end_of_record\n")))
+}
@@ -0,0 +1,184 @@
+package coverage
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+	"github.com/panyam/eplgo/chapter4"
+	"github.com/panyam/eplgo/chapter5"
+	"github.com/panyam/eplgo/chapter7"
+	"github.com/panyam/eplgo/chapter8"
+	"github.com/panyam/eplgo/chapter9"
+)
+
+type Expr = chapter3.Expr
+
+// Child is a sub expression of a node along with the role it plays in it, eg
+// "then" for the then branch of an IfExpr.
+type Child struct {
+	Role string
+	Expr Expr
+}
+
+// Children returns the sub expressions of a node in evaluation order.  Nodes
+// this package does not know about are treated as leaves.
+func Children(expr Expr) (out []Child) {
+	add := func(role string, e Expr) {
+		out = append(out, Child{role, e})
+	}
+	addList := func(role string, exprs []Expr) {
+		for i, e := range exprs {
+			add(fmt.Sprintf("%s %d", role, i), e)
+		}
+	}
+	switch n := expr.(type) {
+	case *chapter3.TupleExpr:
+		addList("item", n.Children)
+	case *chapter3.ListExpr:
+		addList("item", n.Items)
+	case *chapter3.UnpackExpr:
+		add(strings.Join(n.Names, " "), n.Expr)
+		add("in", n.Body)
+	case *chapter3.TupleRefExpr:
+		add("tuple", n.Tuple)
+	case *chapter3.RecordExpr:
+		for _, name := range epl.SortedKeys(n.Fields) {
+			add(name, n.Fields[name])
+		}
+	case *chapter3.FieldExpr:
+		add("record", n.Record)
+	case *chapter3.UpdateExpr:
+		add("record", n.Record)
+		for _, name := range epl.SortedKeys(n.Fields) {
+			add(name, n.Fields[name])
+		}
+	case *chapter3.OpExpr:
+		addList("arg", n.Args)
+	case *chapter3.IfExpr:
+		add("cond", n.Cond)
+		add("then", n.Then)
+		add("else", n.Else)
+	case *chapter3.IsZeroExpr:
+		add("expr", n.Expr)
+	case *chapter3.LetExpr:
+		for _, name := range epl.SortedKeys(n.Mappings) {
+			add(name, n.Mappings[name])
+		}
+		add("in", n.Body)
+	case *chapter3.ProcExpr:
+		add("body", n.Body)
+	case *chapter3.CallExpr:
+		add("operator", n.Operator)
+		addList("arg", n.Args)
+	case *chapter3.LetRecExpr:
+		for _, name := range epl.SortedKeys(n.Procs) {
+			proc := n.Procs[name]
+			add(fmt.Sprintf("%s(%s)", name, strings.Join(proc.Varnames, ", ")), proc.Body)
+		}
+		add("in", n.Body)
+	case *chapter4.RefExpr:
+		if !n.IsVarRef {
+			add("init", n.ExprOrVar.(Expr))
+		}
+	case *chapter4.DeRefExpr:
+		add("ref", n.RefExpr)
+	case *chapter4.SetRefExpr:
+		add("ref", n.RefExpr)
+		add("value", n.ValueExpr)
+	case *chapter4.BlockExpr:
+		addList("expr", n.Exprs)
+	case *chapter4.AssignExpr:
+		add("value", n.Expr)
+	case *chapter4.SetFieldExpr:
+		add("record", n.Record)
+		add(n.Field, n.Expr)
+	case *chapter4.LazyExpr:
+		add("expr", n.Expr)
+	case *chapter4.ThunkExpr:
+		add("expr", n.Expr)
+	case *chapter5.TryExpr:
+		add("try", n.TryBody)
+		add(fmt.Sprintf("catch (%s)", n.VarName), n.HandlerExpr)
+	case *chapter5.RaiseExpr:
+		add("value", n.RaiseValueExpr)
+	case *chapter7.TagExpr:
+		add(n.Tag, n.Expr)
+	case *chapter7.CasesExpr:
+		add("expr", n.Expr)
+		for _, arm := range n.Arms {
+			add(fmt.Sprintf("%s (%s)", arm.Tag, arm.Var), arm.Body)
+		}
+		if n.Else != nil {
+			add("else", n.Else)
+		}
+	case *chapter7.DataProgramExpr:
+		add("in", n.Body)
+	case *chapter7.MatchExpr:
+		add("expr", n.Expr)
+		for _, arm := range n.Arms {
+			add(arm.Pattern.String(), arm.Body)
+		}
+	case *chapter8.ProgramExpr:
+		for _, m := range n.Modules {
+			for _, defn := range m.Body {
+				add(m.Name+"."+defn.Name, defn.Expr)
+			}
+		}
+		add("in", n.Body)
+	case *chapter9.ProgramExpr:
+		for _, class := range n.Classes {
+			for _, m := range class.Methods {
+				add(fmt.Sprintf("%s.%s(%s)", class.Name, m.Name, strings.Join(m.Params, ", ")), m.Body)
+			}
+		}
+		add("in", n.Body)
+	case *chapter9.NewObjectExpr:
+		addList("arg", n.Args)
+	case *chapter9.MethodCallExpr:
+		add("object", n.Object)
+		addList("arg", n.Args)
+	case *chapter9.SuperCallExpr:
+		addList("arg", n.Args)
+	}
+	return
+}
+
+// Walk visits every node under (and including) root in pre-order.
+func Walk(root Expr) iter.Seq[Expr] {
+	return func(yield func(Expr) bool) {
+		var visit func(e Expr) bool
+		visit = func(e Expr) bool {
+			if !yield(e) {
+				return false
+			}
+			for _, c := range Children(e) {
+				if !visit(c.Expr) {
+					return false
+				}
+			}
+			return true
+		}
+		visit(root)
+	}
+}
+
+// Header returns a one line description of a node - the first line of its
+// Printable.
+func Header(expr Expr) string {
+	p := expr.Printable()
+	for p.Leaf == "" && p.Iter != nil {
+		var first *epl.Printable
+		for child := range p.Iter {
+			first = child
+			break
+		}
+		if first == nil {
+			break
+		}
+		p = first
+	}
+	return strings.TrimSuffix(strings.TrimSpace(p.Leaf), ":")
+}
@@ -0,0 +1,104 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const consoleHelp = `Commands:
+  s, step          Step into the next expression
+  n, next          Step over the current expression
+  o, out           Step out of the current procedure
+  c, continue      Continue to the next breakpoint
+  q, quit          Abort evaluation
+  b, break <proc>  Break on entry to the named procedure
+  d, delete <proc> Remove the breakpoint on the named procedure
+  l, list          Show the current expression
+  bt, where        Show the call stack
+  env              Show the environment chain
+  store            Show the reference cells reachable from the environment
+  p, print <name>  Show the value of a variable
+  h, help          Show this help`
+
+// Console is a simple line oriented front end for a Debugger.  Use its
+// OnPause method as the Debugger's OnPause callback.
+type Console struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// NewConsole creates a console reading commands from in and writing to out.
+func NewConsole(in io.Reader, out io.Writer) *Console {
+	return &Console{in: bufio.NewScanner(in), out: out}
+}
+
+// OnPause prints where evaluation paused and then reads and runs commands
+// until one of them resumes evaluation.  Reaching the end of the input
+// continues evaluation.
+func (c *Console) OnPause(d *Debugger, p *Pause) Action {
+	fmt.Fprintf(c.out, "Paused (%s) at %s\n", p.Reason, p.Expr.Repr())
+	for {
+		fmt.Fprint(c.out, "(epldb) ")
+		if !c.in.Scan() {
+			fmt.Fprintln(c.out)
+			return Continue
+		}
+		fields := strings.Fields(c.in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		cmd, args := fields[0], fields[1:]
+		switch cmd {
+		case "s", "step":
+			return StepInto
+		case "n", "next":
+			return StepOver
+		case "o", "out":
+			return StepOut
+		case "c", "continue":
+			return Continue
+		case "q", "quit":
+			return Quit
+		case "b", "break":
+			if len(args) == 0 {
+				fmt.Fprintf(c.out, "Breakpoints: %s\n", strings.Join(d.ProcBreakpoints(), ", "))
+			}
+			for _, name := range args {
+				d.BreakOnProc(name)
+			}
+		case "d", "delete":
+			for _, name := range args {
+				d.ClearOnProc(name)
+			}
+		case "l", "list":
+			p.Expr.Printable().Fprint(c.out)
+		case "bt", "where":
+			fmt.Fprintln(c.out, p.Stack.String())
+		case "env":
+			for i, scope := range Scopes(p.Env) {
+				fmt.Fprintf(c.out, "Scope %d:\n", i)
+				for _, b := range scope {
+					fmt.Fprintf(c.out, "  %s = %s\n", b.Name, FormatValue(b.Value))
+				}
+			}
+		case "store":
+			for i, ref := range References(p.Env) {
+				fmt.Fprintf(c.out, "  #%d = %s\n", i, FormatValue(ref.Value))
+			}
+		case "p", "print":
+			for _, name := range args {
+				if value, found := p.Env.Get(name); found {
+					fmt.Fprintf(c.out, "%s = %s\n", name, FormatValue(value))
+				} else {
+					fmt.Fprintf(c.out, "%s is not bound\n", name)
+				}
+			}
+		case "h", "help":
+			fmt.Fprintln(c.out, consoleHelp)
+		default:
+			fmt.Fprintf(c.out, "Unknown command: %s (try 'help')\n", cmd)
+		}
+	}
+}
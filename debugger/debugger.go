@@ -0,0 +1,181 @@
+// Package debugger implements an interactive step debugger for the EPL
+// evaluators.  It attaches to an evaluator as its chapter3.EvalHook and pauses
+// evaluation at breakpoints or while stepping, handing control to an OnPause
+// callback that inspects the paused state and decides how to proceed.
+package debugger
+
+import (
+	"errors"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+)
+
+// ErrQuit is returned by the evaluator when the debugger is asked to quit.
+var ErrQuit = errors.New("debugger: evaluation aborted")
+
+// Debuggable is the part of an evaluator the debugger needs.  All evaluators
+// embedding chapter3.BaseEval satisfy it.
+type Debuggable interface {
+	SetHook(chapter3.EvalHook)
+	CallDepth() int
+	StackTrace() *chapter3.StackTrace
+}
+
+// Action tells the debugger how to resume after a pause.
+type Action int
+
+const (
+	// Continue runs until the next breakpoint.
+	Continue Action = iota
+
+	// StepInto pauses at the very next expression to be evaluated.
+	StepInto
+
+	// StepOver pauses at the next expression once the current one (and
+	// everything it calls) has been evaluated.
+	StepOver
+
+	// StepOut pauses at the next expression once the current procedure has
+	// returned to its caller.
+	StepOut
+
+	// Quit aborts evaluation with ErrQuit.
+	Quit
+)
+
+// Pause describes the state the evaluator was paused in.
+type Pause struct {
+	// Why evaluation paused, eg "step" or "breakpoint".
+	Reason string
+
+	// The expression about to be evaluated and the environment it will be
+	// evaluated in.
+	Expr Expr
+	Env  *epl.Env[any]
+
+	// The EPL call stack, innermost frame first.
+	Stack *chapter3.StackTrace
+}
+
+type Expr = chapter3.Expr
+
+// Debugger drives an evaluator one expression at a time.
+type Debugger struct {
+	// OnPause is called every time evaluation pauses.  The returned Action
+	// decides how evaluation resumes.  If nil evaluation is never paused.
+	OnPause func(d *Debugger, p *Pause) Action
+
+	eval            Debuggable
+	exprBreakpoints map[Expr]bool
+	procBreakpoints map[string]bool
+
+	action     Action
+	level      int // Number of expressions currently being evaluated
+	pauseLevel int // Value of level at the last pause
+	pauseDepth int // Call depth at the last pause
+	lastDepth  int // Call depth seen at the last Enter/Exit
+}
+
+// New creates a debugger and attaches it to the given evaluator.  The
+// debugger starts off stepping so the first expression evaluated pauses.
+func New(eval Debuggable, onPause func(d *Debugger, p *Pause) Action) *Debugger {
+	d := &Debugger{
+		OnPause:         onPause,
+		eval:            eval,
+		exprBreakpoints: map[Expr]bool{},
+		procBreakpoints: map[string]bool{},
+		action:          StepInto,
+	}
+	eval.SetHook(d)
+	return d
+}
+
+// Detach removes the debugger from its evaluator.
+func (d *Debugger) Detach() {
+	d.eval.SetHook(nil)
+}
+
+// SetAction changes how the debugger behaves until the next pause, eg
+// SetAction(Continue) before evaluating to only stop at breakpoints.
+func (d *Debugger) SetAction(action Action) {
+	d.action = action
+}
+
+// BreakAt sets a breakpoint on a particular AST node.  Nodes are matched by
+// identity, not by ExprEq.
+func (d *Debugger) BreakAt(expr Expr) {
+	d.exprBreakpoints[expr] = true
+}
+
+// ClearAt removes the breakpoint on the given node.
+func (d *Debugger) ClearAt(expr Expr) {
+	delete(d.exprBreakpoints, expr)
+}
+
+// BreakOnProc sets a breakpoint that pauses at the start of the body of every
+// call to a procedure with the given name (see ProcExpr.Name).
+func (d *Debugger) BreakOnProc(name string) {
+	d.procBreakpoints[name] = true
+}
+
+// ClearOnProc removes the breakpoint on the named procedure.
+func (d *Debugger) ClearOnProc(name string) {
+	delete(d.procBreakpoints, name)
+}
+
+// ProcBreakpoints returns the names of procedures with breakpoints.
+func (d *Debugger) ProcBreakpoints() []string {
+	return epl.SortedKeys(d.procBreakpoints)
+}
+
+// Enter implements chapter3.EvalHook.
+func (d *Debugger) Enter(expr Expr, env *epl.Env[any]) error {
+	level := d.level
+	d.level++
+	depth := d.eval.CallDepth()
+	enteredProc := depth > d.lastDepth
+	d.lastDepth = depth
+
+	reason := ""
+	if d.exprBreakpoints[expr] {
+		reason = "breakpoint"
+	} else if enteredProc && d.procBreakpoints[d.eval.StackTrace().Frames[0].ProcName] {
+		reason = "breakpoint in " + d.eval.StackTrace().Frames[0].ProcName
+	} else {
+		switch d.action {
+		case StepInto:
+			reason = "step"
+		case StepOver:
+			if level <= d.pauseLevel {
+				reason = "step"
+			}
+		case StepOut:
+			if depth < d.pauseDepth {
+				reason = "step"
+			}
+		}
+	}
+	if reason == "" || d.OnPause == nil {
+		return nil
+	}
+
+	d.pauseLevel = level
+	d.pauseDepth = depth
+	d.action = d.OnPause(d, &Pause{
+		Reason: reason,
+		Expr:   expr,
+		Env:    env,
+		Stack:  d.eval.StackTrace(),
+	})
+	if d.action == Quit {
+		return ErrQuit
+	}
+	return nil
+}
+
+// Exit implements chapter3.EvalHook.
+func (d *Debugger) Exit(expr Expr, env *epl.Env[any], val any, err error) {
+	d.level--
+	d.lastDepth = d.eval.CallDepth()
+}
@@ -0,0 +1,227 @@
+package debugger
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+	"github.com/panyam/eplgo/chapter4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	Call   = chapter3.Call
+	IsZero = chapter3.IsZero
+	If     = chapter3.If
+	Let    = chapter3.Let
+	LetRec = chapter3.LetRec
+	Lit    = chapter3.Lit
+	Op     = chapter3.Op
+	Proc   = chapter3.Proc
+	Var    = chapter3.Var
+)
+
+func newEval() *chapter3.LetRecLangEval {
+	e := chapter3.NewLetRecLangEval()
+	chapter3.SetOpFuncs(e)
+	return e
+}
+
+// letrec double(x) = if isz(x) then 0 else -((double -(x,1)), -2) in (double 2)
+func makeDouble() (*chapter3.LetRecExpr, *chapter3.CallExpr) {
+	recursive := Call("double", Op("-", "x", 1))
+	return LetRec(chapter3.ProcMap("double", Proc([]string{"x"},
+		If(IsZero("x"), 0, Op("-", recursive, -2)))), Call("double", 2)), recursive
+}
+
+// Records the pauses and replays a fixed list of actions
+type script struct {
+	actions []Action
+	pauses  []*Pause
+}
+
+func (s *script) OnPause(d *Debugger, p *Pause) Action {
+	s.pauses = append(s.pauses, p)
+	if len(s.pauses) > len(s.actions) {
+		return Continue
+	}
+	return s.actions[len(s.pauses)-1]
+}
+
+func TestStepInto(t *testing.T) {
+	e := newEval()
+	s := &script{actions: []Action{StepInto, StepInto, StepInto}}
+	New(e, s.OnPause)
+
+	expr := Let(chapter3.ExprDict("x", Lit(5)), Op("-", "x", 3))
+	val, err := e.Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	assert.Equal(t, chapter3.IntVal(2), val)
+
+	// let, 5, -(x, 3), x (and then continue)
+	require.Len(t, s.pauses, 4)
+	assert.Same(t, expr, s.pauses[0].Expr)
+	assert.Same(t, expr.Mappings["x"], s.pauses[1].Expr)
+	assert.Same(t, expr.Body, s.pauses[2].Expr)
+	assert.True(t, chapter3.ExprEq(Var("x"), s.pauses[3].Expr))
+
+	// x is visible when evaluating the body
+	x, found := s.pauses[3].Env.Get("x")
+	assert.True(t, found)
+	assert.Equal(t, "5", FormatValue(x))
+}
+
+func TestBreakOnProc(t *testing.T) {
+	e := newEval()
+	s := &script{}
+	d := New(e, s.OnPause)
+	d.SetAction(Continue)
+	d.BreakOnProc("double")
+
+	expr, _ := makeDouble()
+	val, err := e.Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	assert.Equal(t, chapter3.IntVal(4), val)
+
+	// One pause per call: (double 2), (double 1), (double 0)
+	require.Len(t, s.pauses, 3)
+	for i, p := range s.pauses {
+		assert.Equal(t, "breakpoint in double", p.Reason)
+		assert.Len(t, p.Stack.Frames, i+1)
+		x, _ := p.Env.Get("x")
+		assert.Equal(t, chapter3.IntVal(2-i), x)
+	}
+}
+
+func TestBreakAtNode(t *testing.T) {
+	e := newEval()
+	s := &script{}
+	d := New(e, s.OnPause)
+	d.SetAction(Continue)
+
+	expr, recursive := makeDouble()
+	d.BreakAt(recursive)
+	_, err := e.Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	require.Len(t, s.pauses, 2)
+	assert.Same(t, recursive, s.pauses[0].Expr)
+}
+
+func TestStepOver(t *testing.T) {
+	expr, recursive := makeDouble()
+
+	// Stepping over the recursive call skips everything inside it
+	e := newEval()
+	var pauses []*Pause
+	d := New(e, func(d *Debugger, p *Pause) Action {
+		pauses = append(pauses, p)
+		d.ClearAt(recursive)
+		return StepOver
+	})
+	d.SetAction(Continue)
+	d.BreakAt(recursive)
+	_, err := e.Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	require.Len(t, pauses, 2)
+	assert.Same(t, recursive, pauses[0].Expr)
+	assert.True(t, chapter3.ExprEq(Lit(-2), pauses[1].Expr))
+	assert.Len(t, pauses[1].Stack.Frames, 1)
+}
+
+func TestStepOut(t *testing.T) {
+	expr, recursive := makeDouble()
+
+	// Step into the recursive call and then straight back out of it
+	e := newEval()
+	var pauses []*Pause
+	d := New(e, func(d *Debugger, p *Pause) Action {
+		pauses = append(pauses, p)
+		d.ClearAt(recursive)
+		if len(p.Stack.Frames) > 1 {
+			return StepOut
+		}
+		return StepInto
+	})
+	d.SetAction(Continue)
+	d.BreakAt(recursive)
+	_, err := e.Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+
+	// The first pause inside the recursive call is followed by a pause
+	// back in the caller
+	for i, p := range pauses {
+		if len(p.Stack.Frames) > 1 {
+			require.Greater(t, len(pauses), i+1)
+			assert.True(t, chapter3.ExprEq(Lit(-2), pauses[i+1].Expr))
+			assert.Len(t, pauses[i+1].Stack.Frames, 1)
+			return
+		}
+	}
+	t.Fatal("Never paused inside the recursive call")
+}
+
+func TestQuit(t *testing.T) {
+	e := newEval()
+	New(e, func(d *Debugger, p *Pause) Action { return Quit })
+	expr, _ := makeDouble()
+	_, err := e.Eval(expr, epl.NewEnv[any](nil))
+	assert.True(t, errors.Is(err, ErrQuit))
+}
+
+func TestStoreInspection(t *testing.T) {
+	e := chapter4.NewExpRefLangEval()
+	chapter3.SetOpFuncs(e)
+	var pause *Pause
+	body := chapter4.DeRef("x")
+	d := New(e, func(d *Debugger, p *Pause) Action {
+		pause = p
+		return Continue
+	})
+	d.SetAction(Continue)
+	d.BreakAt(body)
+
+	// let x = newref(newref(3)) in deref(x)
+	_, err := e.Eval(Let(chapter3.ExprDict("x", chapter4.NewRef(chapter4.NewRef(3))), body), epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	require.NotNil(t, pause)
+	refs := References(pause.Env)
+	require.Len(t, refs, 2)
+	assert.Equal(t, "ref(3)", FormatValue(refs[0].Value))
+	assert.Equal(t, "3", FormatValue(refs[1].Value))
+}
+
+func TestConsole(t *testing.T) {
+	in := strings.NewReader(strings.Join([]string{
+		"help",
+		"b double",
+		"c",
+		"p x",
+		"bt",
+		"env",
+		"bogus",
+		"d double",
+		"c",
+	}, "\n"))
+	var out bytes.Buffer
+	e := newEval()
+	New(e, NewConsole(in, &out).OnPause)
+
+	expr, _ := makeDouble()
+	val, err := e.Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	assert.Equal(t, chapter3.IntVal(4), val)
+
+	output := out.String()
+	assert.Contains(t, output, "Paused (step) at <LetRec")
+	assert.Contains(t, output, "Paused (breakpoint in double) at <If(")
+	assert.Contains(t, output, "x = 2")
+	assert.Contains(t, output, "at double (called at <Call (<Var(double)>) in Val(2:int)")
+	assert.Contains(t, output, "double = <proc double(x)>")
+	assert.Contains(t, output, "Unknown command: bogus")
+	// Only paused twice - once on entry and once in the first call
+	assert.Equal(t, 2, strings.Count(output, "Paused"))
+}
@@ -0,0 +1,94 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+)
+
+// Binding is a single name/value pair in an environment.
+type Binding struct {
+	Name  string
+	Value any
+}
+
+// Scopes returns the bindings in an environment chain, innermost scope first.
+func Scopes(env *epl.Env[any]) (out [][]Binding) {
+	for ; env != nil; env = env.Outer() {
+		var scope []Binding
+		for _, name := range env.Names() {
+			value, _ := env.Get(name)
+			scope = append(scope, Binding{name, value})
+		}
+		out = append(out, scope)
+	}
+	return
+}
+
+// References returns the distinct reference cells (the "store") reachable
+// from the bindings in an environment chain, in the order they are found.
+func References(env *epl.Env[any]) (out []*epl.Ref[any]) {
+	seen := map[*epl.Ref[any]]bool{}
+	var visit func(v any)
+	visit = func(v any) {
+		switch v := v.(type) {
+		case chapter3.RefVal:
+			if !seen[v.Ref] {
+				seen[v.Ref] = true
+				out = append(out, v.Ref)
+				visit(v.Value)
+			}
+		case chapter3.TupleVal:
+			for _, child := range v {
+				visit(child)
+			}
+		case chapter3.RecordVal:
+			for _, k := range epl.SortedKeys(v) {
+				visit(v[k])
+			}
+		}
+	}
+	for _, scope := range Scopes(env) {
+		for _, b := range scope {
+			visit(b.Value)
+		}
+	}
+	return
+}
+
+// FormatValue returns a short human readable form of a runtime value.
+func FormatValue(v any) string {
+	return formatValue(v, 0)
+}
+
+func formatValue(v any, depth int) string {
+	if depth > 8 {
+		return "..."
+	}
+	switch v := v.(type) {
+	case nil:
+		return "<nil>"
+	case chapter3.RefVal:
+		return fmt.Sprintf("ref(%s)", formatValue(v.Value, depth+1))
+	case chapter3.TupleVal:
+		parts := make([]string, len(v))
+		for i, child := range v {
+			parts[i] = formatValue(child, depth+1)
+		}
+		return "(" + strings.Join(parts, ", ") + ")"
+	case chapter3.RecordVal:
+		parts := make([]string, 0, len(v))
+		for _, k := range epl.SortedKeys(v) {
+			parts = append(parts, k+" = "+formatValue(v[k], depth+1))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	case chapter3.Value:
+		return v.String()
+	case interface{ Repr() string }:
+		return v.Repr()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -44,6 +44,12 @@ func (e *Env[T]) Set(key string, value T) {
 	e.store[key] = &Ref[T]{Value: value}
 }
 
+// SetRef binds a name to an existing reference so that the binding shares
+// the reference's cell.
+func (e *Env[T]) SetRef(key string, ref *Ref[T]) {
+	e.store[key] = ref
+}
+
 // Set multiple key/values at once.
 func (e *Env[T]) SetMany(kvpairs map[string]T) {
 	for k, v := range kvpairs {
@@ -63,6 +69,17 @@ func (e *Env[T]) Extend(kvpairs map[string]T) *Env[T] {
 	return out
 }
 
+// Outer returns the environment this one is nested in (nil at the top level).
+func (e *Env[T]) Outer() *Env[T] {
+	return e.outer
+}
+
+// Names returns the (sorted) names bound directly in this environment,
+// excluding any outer environments.
+func (e *Env[T]) Names() []string {
+	return SortedKeys(e.store)
+}
+
 // String representation for debugging
 func (e *Env[T]) String() string {
 	keys := make([]string, 0, len(e.store))
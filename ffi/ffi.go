@@ -0,0 +1,123 @@
+// Package ffi exposes ordinary Go functions to EPL programs as native
+// procedures.  Arguments and results are converted between EPL values and Go
+// types with the marshal package, so a function can take and return numbers,
+// bools, strings, slices (lists), arrays (tuples), maps and structs (records)
+// or chapter3.Values.
+//
+// A function may return nothing, a value, an error or a value and an error.
+// Functions returning nothing (or only an error) evaluate to 0 like an empty
+// block.  Variadic functions take any number of arguments after their fixed
+// ones.
+//
+//	add := ffi.MustFunc("add", func(a, b int) int { return a + b })
+//	env.Set("add", add)   // (add 1 2) is 3 and (add 1) is a procedure
+//
+// An argument that cannot be converted results in a TypeMismatchError and a
+// result that cannot be converted in a ConversionError.  Errors returned (or
+// panics raised) by the function surface as a chapter3.NativeError unless
+// they are already a chapter3.RuntimeError.
+package ffi
+
+import (
+	"fmt"
+	"reflect"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+	"github.com/panyam/eplgo/marshal"
+)
+
+var errorType = reflect.TypeFor[error]()
+
+// Func wraps a Go function as a native procedure.  It fails if fn is not a
+// function or uses argument or result types that cannot be converted.
+func Func(name string, fn any) (*chapter3.NativeProc, error) {
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func {
+		return nil, fmt.Errorf("ffi: %s: expected a function, got %T", name, fn)
+	}
+	ft := fv.Type()
+	for i := range ft.NumIn() {
+		t := ft.In(i)
+		if ft.IsVariadic() && i == ft.NumIn()-1 {
+			t = t.Elem()
+		}
+		if !marshal.Supports(t) {
+			return nil, fmt.Errorf("ffi: %s: unsupported argument type %s", name, t)
+		}
+	}
+	numOut := ft.NumOut()
+	returnsError := numOut > 0 && ft.Out(numOut-1) == errorType
+	if returnsError {
+		numOut--
+	}
+	if numOut > 1 {
+		return nil, fmt.Errorf("ffi: %s: too many results", name)
+	} else if numOut == 1 && !marshal.Supports(ft.Out(0)) {
+		return nil, fmt.Errorf("ffi: %s: unsupported result type %s", name, ft.Out(0))
+	}
+
+	arity := ft.NumIn()
+	if ft.IsVariadic() {
+		arity--
+	}
+	call := func(args []chapter3.Value) (result chapter3.Value, err error) {
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			t := ft.In(min(i, ft.NumIn()-1))
+			if ft.IsVariadic() && i >= arity {
+				t = t.Elem()
+			}
+			var err error
+			if in[i], err = marshal.FromValue(arg, t); err != nil {
+				return nil, chapter3.TypeMismatchError{
+					Context:  fmt.Sprintf("argument %d of native procedure '%s'", i+1, name),
+					Expected: t.String(),
+					Found:    arg,
+				}
+			}
+		}
+
+		defer func() {
+			if r := recover(); r != nil {
+				result, err = nil, fmt.Errorf("panic: %v", r)
+			}
+		}()
+		out := fv.Call(in)
+		if returnsError {
+			if errv := out[len(out)-1]; !errv.IsNil() {
+				return nil, errv.Interface().(error)
+			}
+		}
+		if numOut == 0 {
+			return chapter3.IntVal(0), nil
+		}
+		val, err := marshal.ToValue(out[0])
+		if err != nil {
+			return nil, chapter3.ConversionError{Context: fmt.Sprintf("result of native procedure '%s'", name), Value: out[0].Interface()}
+		}
+		return val, nil
+	}
+	return &chapter3.NativeProc{Name: name, Arity: arity, Variadic: ft.IsVariadic(), Fn: call}, nil
+}
+
+// MustFunc is like Func but panics if fn cannot be wrapped.
+func MustFunc(name string, fn any) *chapter3.NativeProc {
+	out, err := Func(name, fn)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// Bind wraps each function in funcs and binds it to its name in env.
+func Bind(env *epl.Env[any], funcs map[string]any) error {
+	for _, name := range epl.SortedKeys(funcs) {
+		proc, err := Func(name, funcs[name])
+		if err != nil {
+			return err
+		}
+		env.Set(name, proc)
+	}
+	return nil
+}
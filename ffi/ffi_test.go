@@ -0,0 +1,143 @@
+package ffi
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	. "github.com/panyam/eplgo/chapter3"
+	"github.com/panyam/eplgo/chapter5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errNegative = errors.New("negative")
+
+func hostEnv(t *testing.T) *epl.Env[any] {
+	env := epl.NewEnv[any](nil)
+	err := Bind(env, map[string]any{
+		"add":   func(a, b int) int { return a + b },
+		"hypot": func(a, b float64) float64 { return a*a + b*b },
+		"upper": strings.ToUpper,
+		"sum": func(xs []int) (out int) {
+			for _, x := range xs {
+				out += x
+			}
+			return
+		},
+		"words": strings.Fields,
+		"join":  func(sep string, parts ...string) string { return strings.Join(parts, sep) },
+		"sqrt": func(n int) (int, error) {
+			if n < 0 {
+				return 0, errNegative
+			}
+			r := 0
+			for (r+1)*(r+1) <= n {
+				r++
+			}
+			return r, nil
+		},
+		"adder": func(n int) *NativeProc {
+			return MustFunc("adder", func(m int) int { return n + m })
+		},
+		"big":   func(a *big.Int) *big.Int { return new(big.Int).Mul(a, a) },
+		"half":  func(a *big.Rat) *big.Rat { return new(big.Rat).Quo(a, big.NewRat(2, 1)) },
+		"byte":  func(b uint8) uint8 { return b },
+		"boom":  func() int { panic("boom") },
+		"ident": func(v Value) Value { return v },
+	})
+	require.NoError(t, err)
+	return env
+}
+
+func eval(t *testing.T, e Evaluator, expr Expr) (any, error) {
+	return e.Eval(expr, hostEnv(t))
+}
+
+func TestCallNative(t *testing.T) {
+	cases := []struct {
+		name     string
+		expected any
+		expr     Expr
+	}{
+		{"add", 3, Call("add", 1, 2)},
+		{"curried", 3, Call(Call("add", 1), 2)},
+		{"curried_let", 15, Let(ExprDict("inc", Call("add", 1)), Call("inc", 14))},
+		{"float_args", FloatVal(25), Call("hypot", 3, 4)},
+		{"string", "ABC", Call("upper", Lit("abc"))},
+		{"list_arg", 6, Call("sum", List(1, 2, 3))},
+		{"tuple_arg", 6, Call("sum", Tuple(Lit(1), Lit(2), Lit(3)))},
+		{"list_result", NewList(StringVal("a"), StringVal("b")), Call("words", Lit(" a  b "))},
+		{"variadic", "a-b-c", Call("join", Lit("-"), Lit("a"), Lit("b"), Lit("c"))},
+		{"variadic_none", "", Call("join", Lit("-"))},
+		{"error_result", 4, Call("sqrt", 17)},
+		{"returns_native", 7, Call("adder", 3, 4)},
+		{"big", BigIntVal{Int: new(big.Int).Lsh(big.NewInt(1), 128)}, Call("big", Lit(new(big.Int).Lsh(big.NewInt(1), 64)))},
+		{"rat", RatVal{Rat: big.NewRat(1, 6)}, Call("half", Lit(big.NewRat(1, 3)))},
+		{"value", NewList(IntVal(1)), Call("ident", List(1))},
+		// A native procedure passed to and applied by an EPL procedure
+		{"higher_order", 10, Let(ExprDict("twice", Proc([]string{"f", "x"}, Call("f", Call("f", "x")))),
+			Call("twice", Call("add", 3), 4))},
+	}
+	for _, tc := range cases {
+		val, err := eval(t, SetOpFuncs(NewLetRecLangEval()), tc.expr)
+		require.NoError(t, err, "Test %s", tc.name)
+		AssertValue(t, tc.name, tc.expected, val)
+	}
+}
+
+func TestNativeErrors(t *testing.T) {
+	e := SetOpFuncs(NewLetRecLangEval())
+
+	var mismatch TypeMismatchError
+	_, err := eval(t, e, Call("add", 1, Lit("two")))
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, "argument 2 of native procedure 'add'", mismatch.Context)
+	assert.Equal(t, "int", mismatch.Expected)
+	_, err = eval(t, e, Call("byte", 256))
+	require.ErrorAs(t, err, &mismatch)
+	_, err = eval(t, e, Call("sum", List(1, Lit(true))))
+	require.ErrorAs(t, err, &mismatch)
+
+	var native NativeError
+	_, err = eval(t, e, Call("sqrt", -1))
+	require.ErrorAs(t, err, &native)
+	assert.Equal(t, "sqrt", native.Proc)
+	assert.ErrorIs(t, err, errNegative)
+	_, err = eval(t, e, Call("boom"))
+	require.ErrorAs(t, err, &native)
+
+	var arity ArityError
+	_, err = eval(t, e, Call("add"))
+	require.ErrorAs(t, err, &arity)
+	_, err = eval(t, e, Call("add", 1, 2, 3))
+	require.ErrorAs(t, err, &arity)
+
+	// The native procedure is on the EPL call stack
+	var traced *TracedError
+	_, err = eval(t, e, Call("sqrt", -1))
+	require.ErrorAs(t, err, &traced)
+	assert.Equal(t, "sqrt", traced.Trace.Frames[0].ProcName)
+}
+
+func TestCatchNativeError(t *testing.T) {
+	e := chapter5.NewTryLangEval()
+	e.CatchRuntimeErrors = true
+	val, err := eval(t, SetOpFuncs(e), chapter5.Try(Call("sqrt", -4), "err", -1))
+	require.NoError(t, err)
+	AssertValue(t, "catch", -1, val)
+}
+
+func TestFuncRejectsUnsupportedTypes(t *testing.T) {
+	_, err := Func("notfunc", 3)
+	assert.Error(t, err)
+	_, err = Func("chan", func(c chan int) {})
+	assert.Error(t, err)
+	_, err = Func("results", func() (int, int) { return 0, 0 })
+	assert.Error(t, err)
+	_, err = Func("map", func() map[int]string { return nil })
+	assert.Error(t, err)
+	assert.Panics(t, func() { MustFunc("nil", nil) })
+}
@@ -0,0 +1,212 @@
+// Package interpreter is the API for embedding EPL in an application.  An
+// Interpreter bundles an evaluator for one of the languages, the operator
+// libraries installed on it and a global environment:
+//
+//	interp, err := interpreter.New(interpreter.LetRec)
+//	interp.Set("greeting", "hello")
+//	val, err := interp.Run(`concat(greeting, ", world")`)
+//
+// Operators registered by the libraries are also bound as global
+// procedures, so they can be called like any procedure - (car l) as well as
+// car(l) - and passed to other procedures.
+package interpreter
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+	"github.com/panyam/eplgo/chapter4"
+	"github.com/panyam/eplgo/chapter5"
+	"github.com/panyam/eplgo/ffi"
+	"github.com/panyam/eplgo/marshal"
+	"github.com/panyam/eplgo/parser"
+	"github.com/panyam/eplgo/prelude"
+)
+
+type (
+	Expr      = chapter3.Expr
+	Value     = chapter3.Value
+	Evaluator = chapter3.Evaluator
+)
+
+// Level is a language from the book, each one extending the previous.
+type Level int
+
+const (
+	Let    Level = iota // chapter 3 - let, if and operators
+	Proc                // chapter 3 - procedures
+	LetRec              // chapter 3 - recursive procedures
+	ExpRef              // chapter 4 - explicit references
+	ImpRef              // chapter 4 - implicit references and assignment
+	Lazy                // chapter 4 - lazy evaluation
+	Try                 // chapter 5 - exceptions
+)
+
+var levelNames = []string{"let", "proc", "letrec", "expref", "impref", "lazy", "try"}
+
+func (l Level) String() string {
+	if l < 0 || int(l) >= len(levelNames) {
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns the Level with the given name, eg "letrec".
+func ParseLevel(name string) (Level, error) {
+	for i, n := range levelNames {
+		if strings.EqualFold(n, name) {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown language level '%s', expected one of %s", name, strings.Join(levelNames, ", "))
+}
+
+// NewEvaluator returns a new evaluator for a language level with no
+// operators registered.
+func NewEvaluator(level Level) (Evaluator, error) {
+	switch level {
+	case Let:
+		return chapter3.NewLetLangEval(), nil
+	case Proc:
+		return chapter3.NewProcLangEval(), nil
+	case LetRec:
+		return chapter3.NewLetRecLangEval(), nil
+	case ExpRef:
+		return chapter4.NewExpRefLangEval(), nil
+	case ImpRef:
+		return chapter4.NewImpRefLangEval(), nil
+	case Lazy:
+		return chapter4.NewLazyLangEval(), nil
+	case Try:
+		// Runtime errors, eg car of the empty list, can be caught by 'try'
+		out := chapter5.NewTryLangEval()
+		out.CatchRuntimeErrors = true
+		return out, nil
+	}
+	return nil, fmt.Errorf("invalid language level %d, expected one of %s", int(level), strings.Join(levelNames, ", "))
+}
+
+// Library installs operators on an evaluator, eg prelude.Install or
+// chapter3.SetStringOpFuncs.
+type Library = func(Evaluator) Evaluator
+
+// Interpreter runs EPL programs at a given language level.
+type Interpreter struct {
+	Level     Level
+	Evaluator Evaluator
+
+	// Each program is run in a new scope over the globals
+	Globals *epl.Env[any]
+}
+
+// New returns an interpreter for a language level with the given operator
+// libraries installed, or the whole prelude if none are given.
+func New(level Level, libs ...Library) (*Interpreter, error) {
+	if len(libs) == 0 {
+		libs = []Library{prelude.Install}
+	}
+	evaluator, err := NewEvaluator(level)
+	if err != nil {
+		return nil, err
+	}
+	out := &Interpreter{
+		Level:     level,
+		Evaluator: evaluator,
+		Globals:   epl.NewEnv[any](nil),
+	}
+	ops := &opRecorder{Evaluator: out.Evaluator, ops: map[string]chapter3.OpFunc{}}
+	for _, lib := range libs {
+		lib(ops)
+	}
+	// Procedures only exist from ProcLang on
+	if level >= Proc {
+		for name, fn := range ops.ops {
+			out.Globals.Set(name, opProc(name, fn))
+		}
+	}
+	return out, nil
+}
+
+// Set binds a global.  Values are bound as is, Go functions are bound as
+// native procedures (see the ffi package) and other Go data is converted with
+// the marshal package.  A function ffi cannot bind is an error rather than
+// being marshalled.
+func (i *Interpreter) Set(name string, value any) error {
+	switch v := value.(type) {
+	case Value:
+		i.Globals.Set(name, v)
+		return nil
+	case nil:
+		return fmt.Errorf("cannot bind '%s' to nil", name)
+	}
+	if reflect.ValueOf(value).Kind() == reflect.Func {
+		proc, err := ffi.Func(name, value)
+		if err != nil {
+			return fmt.Errorf("cannot bind '%s': %w", name, err)
+		}
+		i.Globals.Set(name, proc)
+		return nil
+	}
+	if err := marshal.Set(i.Globals, name, value); err != nil {
+		return fmt.Errorf("cannot bind '%s': %w", name, err)
+	}
+	return nil
+}
+
+// Parse parses a program without running it.
+func (i *Interpreter) Parse(src string) (Expr, error) {
+	return parser.Parse(src)
+}
+
+// Run parses and evaluates a program.
+func (i *Interpreter) Run(src string) (Value, error) {
+	expr, err := i.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return i.Eval(expr)
+}
+
+// Eval evaluates an expression in a fresh scope over the globals.
+func (i *Interpreter) Eval(expr Expr) (Value, error) {
+	val, err := i.Evaluator.Eval(expr, i.Globals.Push())
+	if err != nil {
+		return nil, err
+	}
+	return chapter3.AsValue("result", val)
+}
+
+// opRecorder passes operators through to an evaluator while keeping a copy
+// to bind as procedures.
+type opRecorder struct {
+	Evaluator
+	ops map[string]chapter3.OpFunc
+}
+
+func (r *opRecorder) SetOpFunc(name string, fn chapter3.OpFunc) {
+	r.Evaluator.SetOpFunc(name, fn)
+	r.ops[name] = fn
+}
+
+// opProc wraps an operator as a variadic native procedure.  The arguments
+// have already been evaluated so they are passed on as literals.
+func opProc(name string, fn chapter3.OpFunc) *chapter3.NativeProc {
+	return &chapter3.NativeProc{
+		Name:     name,
+		Variadic: true,
+		Fn: func(args []Value) (Value, error) {
+			exprs := make([]Expr, len(args))
+			for i, arg := range args {
+				exprs[i] = chapter3.Lit(arg)
+			}
+			val, err := fn(epl.NewEnv[any](nil), exprs)
+			if err != nil {
+				return nil, err
+			}
+			return chapter3.AsValue("result of '"+name+"'", val)
+		},
+	}
+}
@@ -0,0 +1,155 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/panyam/eplgo/chapter3"
+	"github.com/panyam/eplgo/chapter5"
+	"github.com/panyam/eplgo/parser"
+	"github.com/panyam/eplgo/prelude"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newInterp(t *testing.T, level Level, libs ...Library) *Interpreter {
+	t.Helper()
+	interp, err := New(level, libs...)
+	require.NoError(t, err)
+	return interp
+}
+
+func run(t *testing.T, interp *Interpreter, src string) Value {
+	t.Helper()
+	val, err := interp.Run(src)
+	require.NoError(t, err, "running %s", src)
+	return val
+}
+
+func TestRunAtEachLevel(t *testing.T) {
+	cases := []struct {
+		level    Level
+		src      string
+		expected any
+	}{
+		{Let, "let x = 5 y = 3 in if <(x, y) then x else y", 3},
+		{Proc, "let f = proc (x, y) -(x, y) in ((f 10) 4)", 6},
+		{LetRec, "letrec even(x) = if isz(x) then 1 else (odd -(x,1)) odd(x) = if isz(x) then 0 else (even -(x,1)) in (odd 13)", 1},
+		{ExpRef, "let r = newref(1) in begin setref(r, +(deref(r), 41)); deref(r) end", 42},
+		{ImpRef, "let x = 1 in begin set x = *(x, 10); x end", 10},
+		{Lazy, "letrec loop(x) = (loop x) in let f = proc (z) 11 in (f lazy (loop 0))", 11},
+		{Try, `try -(1, raise "oops") catch (e) concat("caught ", e)`, "caught oops"},
+	}
+	for _, tc := range cases {
+		val := run(t, newInterp(t, tc.level), tc.src)
+		chapter3.AssertValue(t, tc.level.String(), tc.expected, val)
+	}
+}
+
+func TestCatchRuntimeErrors(t *testing.T) {
+	interp := newInterp(t, Try)
+	val := run(t, interp, "try car(emptylist()) catch (e) -1")
+	chapter3.AssertValue(t, "car", -1, val)
+	val = run(t, interp, "try car(emptylist()) catch (e) concat(\"caught \", tostring(e))")
+	chapter3.AssertValue(t, "message", "caught car of the empty list", val)
+	val = run(t, interp, "try unpack a b = (1, 2, 3) in a catch (e) 0")
+	chapter3.AssertValue(t, "unpack", 0, val)
+	val = run(t, interp, "try (1, 2).2 catch (e) 0")
+	chapter3.AssertValue(t, "index", 0, val)
+}
+
+func TestOperatorsAsProcedures(t *testing.T) {
+	interp := newInterp(t, LetRec)
+	// The exceptions case from tests/chapter5/cases.py calls list operators
+	// with the procedure call syntax
+	val := run(t, interp, `
+		letrec index(lst, str) =
+			if (null? lst) then -1
+			else if (stringequals (car lst) str) then 0
+			else +(1, (index (cdr lst) str))
+		in (index list("a", "b", "c") "c")`)
+	chapter3.AssertValue(t, "index", 2, val)
+
+	// and passed to other procedures
+	val = run(t, interp, "let apply = proc (f, x, y) (f x y) in (apply max 3 7)")
+	chapter3.AssertValue(t, "apply", 7, val)
+
+	// Procedures without parameters are called with ()
+	val = run(t, interp, "let f = proc () 5 in +((f)(), 1)")
+	chapter3.AssertValue(t, "no arguments", 6, val)
+
+	// but can be shadowed
+	val = run(t, interp, "let car = 5 in car")
+	chapter3.AssertValue(t, "shadow", 5, val)
+}
+
+func TestLibraries(t *testing.T) {
+	interp := newInterp(t, LetRec, prelude.Arithmetic)
+	chapter3.AssertValue(t, "arithmetic", 7, run(t, interp, "max(3, 7)"))
+	_, err := interp.Run(`concat("a", "b")`)
+	var unknown chapter3.UnknownOperatorError
+	assert.ErrorAs(t, err, &unknown)
+}
+
+func TestGlobals(t *testing.T) {
+	interp := newInterp(t, LetRec)
+	require.NoError(t, interp.Set("greeting", "hello"))
+	require.NoError(t, interp.Set("answer", 42))
+	require.NoError(t, interp.Set("shout", strings.ToUpper))
+	require.NoError(t, interp.Set("items", chapter3.NewList(chapter3.IntVal(1), chapter3.IntVal(2))))
+	require.NoError(t, interp.Set("point", struct{ X, Y int }{3, 4}))
+	assert.Error(t, interp.Set("bad", make(chan int)))
+	// A function ffi cannot bind reports why rather than trying to marshal it
+	assert.ErrorContains(t, interp.Set("badfunc", func(c chan int) {}), "unsupported argument type chan int")
+	assert.Error(t, interp.Set("nil", nil))
+
+	chapter3.AssertValue(t, "string", "HELLO, WORLD", run(t, interp, `(shout concat(greeting, ", world"))`))
+	chapter3.AssertValue(t, "int", 43, run(t, interp, "+(answer, 1)"))
+	chapter3.AssertValue(t, "list", 2, run(t, interp, "car(cdr(items))"))
+	assert.Equal(t, "{X = 3, Y = 4}", run(t, interp, "point").String())
+
+	// Bindings made by one program are not visible to the next
+	run(t, interp, "let x = 1 in x")
+	_, err := interp.Run("x")
+	var unbound chapter3.UnboundVariableError
+	assert.ErrorAs(t, err, &unbound)
+}
+
+func TestEvalExpr(t *testing.T) {
+	interp := newInterp(t, Proc)
+	val, err := interp.Eval(chapter3.Op("-", 10, 3))
+	require.NoError(t, err)
+	chapter3.AssertValue(t, "eval", 7, val)
+}
+
+func TestErrors(t *testing.T) {
+	var syntax parser.SyntaxError
+	_, err := newInterp(t, LetRec).Run("let x = in x")
+	assert.ErrorAs(t, err, &syntax)
+
+	// A construct from a later chapter
+	var unsupported chapter3.UnsupportedExprError
+	_, err = newInterp(t, LetRec).Run("newref(1)")
+	assert.ErrorAs(t, err, &unsupported)
+	_, err = newInterp(t, Let).Run("proc (x) x")
+	assert.ErrorAs(t, err, &unsupported)
+
+	var raised chapter5.RaisedError
+	_, err = newInterp(t, Try).Run("raise 3")
+	assert.ErrorAs(t, err, &raised)
+}
+
+func TestParseLevel(t *testing.T) {
+	for level := Let; level <= Try; level++ {
+		parsed, err := ParseLevel(level.String())
+		require.NoError(t, err)
+		assert.Equal(t, level, parsed)
+	}
+	_, err := ParseLevel("cps")
+	assert.Error(t, err)
+
+	_, err = NewEvaluator(Try + 1)
+	assert.Error(t, err)
+	_, err = New(Level(-1))
+	assert.Error(t, err)
+}
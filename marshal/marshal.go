@@ -0,0 +1,495 @@
+// Package marshal converts between Go data and EPL values:
+//
+//	Go                                 EPL
+//	int, int8 ... int64, uint ...      IntVal (BigIntVal if it does not fit)
+//	*big.Int, *big.Rat                 IntVal, BigIntVal, RatVal
+//	float32, float64                   FloatVal
+//	bool                               BoolVal
+//	string                             StringVal
+//	slices                             ListVal
+//	arrays                             TupleVal
+//	maps with string keys, structs     RecordVal
+//	pointers, interfaces               the value pointed to
+//	chapter3.Value                     itself
+//
+// Struct fields are named by an `epl:"name"` tag if present, and skipped if
+// the tag is "-" or they are unexported.  Data that refers back to itself
+// cannot be converted.  Going back to Go, a list or tuple
+// converts to either a slice or an array, an integer to a float and a record
+// to a struct (ignoring fields it does not have) or map.  Decoding into an
+// `any` gives ints, *big.Int, *big.Rat, float64, bool, string, []any and
+// map[string]any, or the Value itself for procedures and references.
+package marshal
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+)
+
+type Value = chapter3.Value
+
+var (
+	valueType  = reflect.TypeFor[Value]()
+	anyType    = reflect.TypeFor[any]()
+	bigIntType = reflect.TypeFor[*big.Int]()
+	bigRatType = reflect.TypeFor[*big.Rat]()
+)
+
+// Error is returned for data that cannot be converted.
+type Error struct {
+	// Where in the data the conversion failed, eg ".Points[2].X"
+	Path string
+
+	// The Go or EPL value that could not be converted
+	Value any
+
+	// The type being converted to - nil when converting to EPL
+	Type reflect.Type
+}
+
+func (e *Error) Error() string {
+	var out string
+	if e.Type == nil {
+		out = fmt.Sprintf("cannot convert %T to an EPL value", e.Value)
+	} else {
+		out = fmt.Sprintf("cannot convert %s to %s", chapter3.ReprOf(e.Value), e.Type)
+	}
+	if e.Path != "" {
+		out += " at " + e.Path
+	}
+	return out
+}
+
+// CycleError is returned converting Go data that refers back to itself, which
+// EPL values cannot represent.
+type CycleError struct {
+	// Where the data refers back to a value containing it
+	Path string
+}
+
+func (e *CycleError) Error() string {
+	out := "cannot convert cyclic data to an EPL value"
+	if e.Path != "" {
+		out += " at " + e.Path
+	}
+	return out
+}
+
+// ToEPL converts Go data to an EPL value.
+func ToEPL(data any) (Value, error) {
+	return ToValue(reflect.ValueOf(data))
+}
+
+// FromEPL converts an EPL value into the Go variable out points to.
+func FromEPL(value Value, out any) error {
+	ptr := reflect.ValueOf(out)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() {
+		return fmt.Errorf("marshal: FromEPL needs a non nil pointer, got %T", out)
+	}
+	result, err := FromValue(value, ptr.Type().Elem())
+	if err != nil {
+		return err
+	}
+	ptr.Elem().Set(result)
+	return nil
+}
+
+// Set converts Go data to an EPL value and binds it in an environment.
+func Set(env *epl.Env[any], name string, data any) error {
+	val, err := ToEPL(data)
+	if err != nil {
+		return err
+	}
+	env.Set(name, val)
+	return nil
+}
+
+// ToValue converts a reflected Go value to an EPL value.
+func ToValue(rv reflect.Value) (Value, error) {
+	return toValue(rv, "", map[visit]bool{})
+}
+
+// FromValue converts an EPL value to a Go value of type t.
+func FromValue(v Value, t reflect.Type) (reflect.Value, error) {
+	return fromValue(v, t, "")
+}
+
+// visit is a pointer, map or slice being converted.  Slices also need their
+// length as a slice and its prefix share a pointer.
+type visit struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+// toValue converts rv, with seen holding the pointers, maps and slices that
+// contain it so that cycles are reported rather than followed forever.
+func toValue(rv reflect.Value, path string, seen map[visit]bool) (Value, error) {
+	if !rv.IsValid() {
+		return nil, &Error{Path: path, Value: nil}
+	}
+	switch rv.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if rv.IsNil() {
+			return nil, &Error{Path: path, Value: rv.Interface()}
+		}
+	}
+	if rv.CanInterface() {
+		// Copy big numbers so the value does not change with the Go data
+		switch x := rv.Interface().(type) {
+		case *big.Int:
+			return chapter3.NormalizeInt(new(big.Int).Set(x)), nil
+		case *big.Rat:
+			return chapter3.NormalizeRat(new(big.Rat).Set(x)), nil
+		}
+		if val, ok := chapter3.ToValue(rv.Interface()); ok {
+			return val, nil
+		}
+	}
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		key := visit{ptr: rv.Pointer(), typ: rv.Type()}
+		if rv.Kind() == reflect.Slice {
+			key.len = rv.Len()
+		}
+		if seen[key] {
+			return nil, &CycleError{Path: path}
+		}
+		seen[key] = true
+		defer delete(seen, key)
+	}
+	switch rv.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		return toValue(rv.Elem(), path, seen)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return chapter3.NormalizeInt(big.NewInt(rv.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return chapter3.NormalizeInt(new(big.Int).SetUint64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return chapter3.FloatVal(rv.Float()), nil
+	case reflect.Bool:
+		return chapter3.BoolVal(rv.Bool()), nil
+	case reflect.String:
+		return chapter3.StringVal(rv.String()), nil
+	case reflect.Slice, reflect.Array:
+		items := make([]Value, rv.Len())
+		for i := range items {
+			var err error
+			if items[i], err = toValue(rv.Index(i), fmt.Sprintf("%s[%d]", path, i), seen); err != nil {
+				return nil, err
+			}
+		}
+		if rv.Kind() == reflect.Array {
+			return chapter3.TupleVal(items), nil
+		}
+		return chapter3.NewList(items...), nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		out := chapter3.RecordVal{}
+		for iter := rv.MapRange(); iter.Next(); {
+			key := iter.Key().String()
+			val, err := toValue(iter.Value(), path+"."+key, seen)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = val
+		}
+		return out, nil
+	case reflect.Struct:
+		out := chapter3.RecordVal{}
+		for _, f := range fields(rv.Type()) {
+			val, err := toValue(rv.FieldByIndex(f.index), path+"."+f.goName, seen)
+			if err != nil {
+				return nil, err
+			}
+			out[f.name] = val
+		}
+		return out, nil
+	}
+	return nil, &Error{Path: path, Value: rv.Interface()}
+}
+
+func fromValue(v Value, t reflect.Type, path string) (reflect.Value, error) {
+	fail := func() (reflect.Value, error) {
+		return reflect.Value{}, &Error{Path: path, Value: v, Type: t}
+	}
+	if v == nil {
+		return fail()
+	}
+	if t == anyType {
+		native, err := toNative(v, path)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(&native).Elem(), nil
+	}
+	if vt := reflect.TypeOf(v); vt.AssignableTo(t) {
+		out := reflect.New(t).Elem()
+		out.Set(reflect.ValueOf(v))
+		return out, nil
+	}
+
+	out := reflect.New(t).Elem()
+	switch {
+	case t == bigIntType:
+		i, ok := toBigInt(v)
+		if !ok {
+			return fail()
+		}
+		return reflect.ValueOf(new(big.Int).Set(i)), nil
+	case t == bigRatType:
+		switch v := v.(type) {
+		case chapter3.RatVal:
+			return reflect.ValueOf(new(big.Rat).Set(v.Rat)), nil
+		case chapter3.IntVal, chapter3.BigIntVal:
+			i, _ := toBigInt(v)
+			return reflect.ValueOf(new(big.Rat).SetInt(i)), nil
+		}
+		return fail()
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		elem, err := fromValue(v, t.Elem(), path)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out = reflect.New(t.Elem())
+		out.Elem().Set(elem)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := toBigInt(v)
+		if !ok || !i.IsInt64() || out.OverflowInt(i.Int64()) {
+			return fail()
+		}
+		out.SetInt(i.Int64())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := toBigInt(v)
+		if !ok || !i.IsUint64() || out.OverflowUint(i.Uint64()) {
+			return fail()
+		}
+		out.SetUint(i.Uint64())
+	case reflect.Float32, reflect.Float64:
+		f, ok := toFloat(v)
+		if !ok {
+			return fail()
+		}
+		out.SetFloat(f)
+	case reflect.Bool:
+		b, ok := v.(chapter3.BoolVal)
+		if !ok {
+			return fail()
+		}
+		out.SetBool(bool(b))
+	case reflect.String:
+		s, ok := v.(chapter3.StringVal)
+		if !ok {
+			return fail()
+		}
+		out.SetString(string(s))
+	case reflect.Slice, reflect.Array:
+		items, ok := sequence(v)
+		if !ok {
+			return fail()
+		}
+		if t.Kind() == reflect.Slice {
+			out = reflect.MakeSlice(t, len(items), len(items))
+		} else if t.Len() != len(items) {
+			return fail()
+		}
+		for i, item := range items {
+			elem, err := fromValue(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.Index(i).Set(elem)
+		}
+	case reflect.Map:
+		rec, ok := v.(chapter3.RecordVal)
+		if !ok || t.Key().Kind() != reflect.String {
+			return fail()
+		}
+		out = reflect.MakeMapWithSize(t, len(rec))
+		for _, k := range epl.SortedKeys(rec) {
+			elem, err := fromValue(rec[k], t.Elem(), path+"."+k)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), elem)
+		}
+	case reflect.Struct:
+		rec, ok := v.(chapter3.RecordVal)
+		if !ok {
+			return fail()
+		}
+		for _, f := range fields(t) {
+			fv, ok := rec[f.name]
+			if !ok {
+				continue
+			}
+			elem, err := fromValue(fv, f.typ, path+"."+f.goName)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			out.FieldByIndex(f.index).Set(elem)
+		}
+	default:
+		return fail()
+	}
+	return out, nil
+}
+
+// toNative converts an EPL value to the natural Go representation for
+// decoding into an any.
+func toNative(v Value, path string) (any, error) {
+	switch v := v.(type) {
+	case chapter3.IntVal:
+		return int(v), nil
+	case chapter3.BigIntVal:
+		return new(big.Int).Set(v.Int), nil
+	case chapter3.RatVal:
+		return new(big.Rat).Set(v.Rat), nil
+	case chapter3.FloatVal:
+		return float64(v), nil
+	case chapter3.BoolVal:
+		return bool(v), nil
+	case chapter3.StringVal:
+		return string(v), nil
+	case *chapter3.ListVal, chapter3.TupleVal:
+		items, _ := sequence(v)
+		out := make([]any, len(items))
+		for i, item := range items {
+			var err error
+			if out[i], err = toNative(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case chapter3.RecordVal:
+		out := make(map[string]any, len(v))
+		for k, fv := range v {
+			var err error
+			if out[k], err = toNative(fv, path+"."+k); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+func sequence(v Value) ([]Value, bool) {
+	switch v := v.(type) {
+	case *chapter3.ListVal:
+		return v.Items(), true
+	case chapter3.TupleVal:
+		return v, true
+	}
+	return nil, false
+}
+
+type field struct {
+	name   string // name in EPL
+	goName string
+	index  []int
+	typ    reflect.Type
+}
+
+// fields returns the struct fields that are marshaled.
+func fields(t reflect.Type) (out []field) {
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous || viaPointer(t, f.Index) {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("epl"); ok {
+			tag, _, _ = strings.Cut(tag, ",")
+			if tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+		}
+		out = append(out, field{name: name, goName: f.Name, index: f.Index, typ: f.Type})
+	}
+	return
+}
+
+// viaPointer is true for fields promoted through an embedded pointer, which
+// may be nil.
+func viaPointer(t reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		t = t.Field(i).Type
+		if t.Kind() == reflect.Pointer {
+			return true
+		}
+	}
+	return false
+}
+
+// Supports returns true if values of type t can be converted to and from EPL
+// values (though particular values may still fail, eg a nil pointer or an
+// integer that is too large).
+func Supports(t reflect.Type) bool {
+	return supports(t, map[reflect.Type]bool{})
+}
+
+func supports(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return true
+	}
+	seen[t] = true
+	if t.Implements(valueType) || t == bigIntType || t == bigRatType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Bool, reflect.String, reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return supports(t.Elem(), seen)
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && supports(t.Elem(), seen)
+	case reflect.Struct:
+		for _, f := range fields(t) {
+			if !supports(f.typ, seen) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func toBigInt(v Value) (*big.Int, bool) {
+	switch v := v.(type) {
+	case chapter3.IntVal:
+		return big.NewInt(int64(v)), true
+	case chapter3.BigIntVal:
+		return v.Int, true
+	}
+	return nil, false
+}
+
+func toFloat(v Value) (float64, bool) {
+	switch v := v.(type) {
+	case chapter3.IntVal:
+		return float64(v), true
+	case chapter3.BigIntVal:
+		f, _ := new(big.Float).SetInt(v.Int).Float64()
+		return f, true
+	case chapter3.RatVal:
+		f, _ := v.Float64()
+		return f, true
+	case chapter3.FloatVal:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,208 @@
+package marshal_test
+
+import (
+	"math/big"
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+	"github.com/panyam/eplgo/interpreter"
+	"github.com/panyam/eplgo/marshal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Point struct {
+	X, Y int
+}
+
+type Shape struct {
+	Name   string  `epl:"name"`
+	Points []Point `epl:"points"`
+	Closed bool    `epl:"closed"`
+	Cache  string  `epl:"-"`
+	hidden int
+}
+
+func toEPL(t *testing.T, data any) chapter3.Value {
+	t.Helper()
+	val, err := marshal.ToEPL(data)
+	require.NoError(t, err, "converting %#v", data)
+	return val
+}
+
+func TestToEPL(t *testing.T) {
+	cases := []struct {
+		data     any
+		expected string
+	}{
+		{3, "3"},
+		{uint64(1 << 63), "9223372036854775808"},
+		{2.5, "2.5"},
+		{true, "true"},
+		{"hi", "hi"},
+		{big.NewRat(1, 3), "1/3"},
+		{[]int{1, 2, 3}, "[1, 2, 3]"},
+		{[2]string{"a", "b"}, "(a, b)"},
+		{map[string]int{"b": 2, "a": 1}, "{a = 1, b = 2}"},
+		{&Point{1, 2}, "{X = 1, Y = 2}"},
+		{[]any{1, "x", []bool{true}}, "[1, x, [true]]"},
+		{chapter3.IntVal(7), "7"},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.expected, toEPL(t, tc.data).String(), "converting %#v", tc.data)
+	}
+}
+
+func TestStructTags(t *testing.T) {
+	shape := Shape{Name: "line", Points: []Point{{0, 0}, {3, 4}}, Cache: "skipped", hidden: 1}
+	val := toEPL(t, shape)
+	rec, ok := val.(chapter3.RecordVal)
+	require.True(t, ok, "expected a record, found %T", val)
+	assert.Equal(t, []string{"closed", "name", "points"}, epl.SortedKeys(rec))
+
+	var out Shape
+	require.NoError(t, marshal.FromEPL(val, &out))
+	shape.Cache, shape.hidden = "", 0
+	assert.Equal(t, shape, out)
+}
+
+func TestFromEPL(t *testing.T) {
+	var i int8
+	require.NoError(t, marshal.FromEPL(chapter3.IntVal(-5), &i))
+	assert.Equal(t, int8(-5), i)
+
+	var f float64
+	require.NoError(t, marshal.FromEPL(chapter3.IntVal(2), &f))
+	assert.Equal(t, 2.0, f)
+
+	var b *big.Int
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	require.NoError(t, marshal.FromEPL(chapter3.BigIntVal{Int: huge}, &b))
+	assert.Equal(t, 0, huge.Cmp(b))
+
+	// Lists and tuples convert to either slices or arrays
+	var arr [3]int
+	require.NoError(t, marshal.FromEPL(chapter3.NewList(chapter3.IntVal(1), chapter3.IntVal(2), chapter3.IntVal(3)), &arr))
+	assert.Equal(t, [3]int{1, 2, 3}, arr)
+	var strs []string
+	require.NoError(t, marshal.FromEPL(chapter3.TupleVal{chapter3.StringVal("a"), chapter3.StringVal("b")}, &strs))
+	assert.Equal(t, []string{"a", "b"}, strs)
+
+	var p *Point
+	require.NoError(t, marshal.FromEPL(chapter3.RecordVal{"X": chapter3.IntVal(1), "Z": chapter3.IntVal(9)}, &p))
+	assert.Equal(t, &Point{X: 1}, p)
+
+	var m map[string]bool
+	require.NoError(t, marshal.FromEPL(chapter3.RecordVal{"on": chapter3.BoolVal(true)}, &m))
+	assert.Equal(t, map[string]bool{"on": true}, m)
+
+	// Values that are not data are kept as is
+	var v chapter3.Value
+	require.NoError(t, marshal.FromEPL(chapter3.StringVal("s"), &v))
+	assert.Equal(t, chapter3.StringVal("s"), v)
+}
+
+func TestFromEPLToAny(t *testing.T) {
+	ref := chapter3.RefVal{Ref: &epl.Ref[any]{}}
+	rec := chapter3.RecordVal{
+		"items": chapter3.NewList(chapter3.IntVal(1), chapter3.FloatVal(1.5)),
+		"pair":  chapter3.TupleVal{chapter3.BoolVal(true), chapter3.StringVal("x")},
+		"ref":   ref,
+	}
+	var out any
+	require.NoError(t, marshal.FromEPL(rec, &out))
+	assert.Equal(t, map[string]any{
+		"items": []any{1, 1.5},
+		"pair":  []any{true, "x"},
+		"ref":   ref,
+	}, out)
+}
+
+func TestErrors(t *testing.T) {
+	var merr *marshal.Error
+	_, err := marshal.ToEPL(nil)
+	assert.ErrorAs(t, err, &merr)
+	_, err = marshal.ToEPL(map[string]any{"f": make(chan int)})
+	if assert.ErrorAs(t, err, &merr) {
+		assert.Equal(t, ".f", merr.Path)
+	}
+
+	val := toEPL(t, Shape{Points: []Point{{1, 2}, {3, 4}}})
+	val.(chapter3.RecordVal)["points"].(*chapter3.ListVal).Items()[1].(chapter3.RecordVal)["X"] = chapter3.StringVal("three")
+	var shape Shape
+	err = marshal.FromEPL(val, &shape)
+	if assert.ErrorAs(t, err, &merr) {
+		assert.Equal(t, ".Points[1].X", merr.Path)
+		assert.Equal(t, `cannot convert String("three") to int at .Points[1].X`, err.Error())
+	}
+
+	var small int8
+	assert.Error(t, marshal.FromEPL(chapter3.IntVal(300), &small))
+	var arr [2]int
+	assert.Error(t, marshal.FromEPL(chapter3.TupleVal{chapter3.IntVal(1)}, &arr))
+	assert.Error(t, marshal.FromEPL(chapter3.IntVal(1), small))
+}
+
+type Node struct {
+	Value int
+	Next  *Node
+}
+
+func TestCycles(t *testing.T) {
+	var cycle *marshal.CycleError
+	loop := &Node{Value: 1}
+	loop.Next = &Node{Value: 2, Next: loop}
+	_, err := marshal.ToEPL(loop)
+	if assert.ErrorAs(t, err, &cycle) {
+		assert.Equal(t, ".Next.Next", cycle.Path)
+	}
+
+	m := map[string]any{}
+	m["self"] = m
+	_, err = marshal.ToEPL(m)
+	assert.ErrorAs(t, err, &cycle)
+
+	s := []any{1, nil}
+	s[1] = s
+	_, err = marshal.ToEPL(s)
+	assert.ErrorAs(t, err, &cycle)
+
+	// Data shared without a cycle is converted each time it is seen
+	shared := &Point{1, 2}
+	assert.Equal(t, "[{X = 1, Y = 2}, {X = 1, Y = 2}]", toEPL(t, []*Point{shared, shared}).String())
+	assert.Equal(t, "[[], []]", toEPL(t, [][]int{{}, {}}).String())
+}
+
+func TestBigNumbersCopied(t *testing.T) {
+	i := new(big.Int).Lsh(big.NewInt(1), 100)
+	r := big.NewRat(1, 3)
+	ival, rval := toEPL(t, i), toEPL(t, r)
+	i.SetInt64(0)
+	r.SetInt64(0)
+	assert.Equal(t, "1267650600228229401496703205376", ival.String())
+	assert.Equal(t, "1/3", rval.String())
+}
+
+func TestRoundTrip(t *testing.T) {
+	interp, err := interpreter.New(interpreter.LetRec)
+	require.NoError(t, err)
+	require.NoError(t, interp.Set("shape", Shape{Name: "tri", Points: []Point{{0, 0}, {1, 0}, {0, 1}}}))
+	require.NoError(t, interp.Set("xs", []int{1, 2, 3}))
+
+	val, err := interp.Run("cons(10, cdr(xs))")
+	require.NoError(t, err)
+	var xs []int
+	require.NoError(t, marshal.FromEPL(val, &xs))
+	assert.Equal(t, []int{10, 2, 3}, xs)
+
+	val, err = interp.Eval(chapter3.Tuple(chapter3.Lit(1), chapter3.Var("shape")))
+	require.NoError(t, err)
+	var out [2]any
+	require.NoError(t, marshal.FromEPL(val, &out))
+	assert.Equal(t, 1, out[0])
+	var shape Shape
+	require.NoError(t, marshal.FromEPL(val.(chapter3.TupleVal)[1], &shape))
+	assert.Equal(t, "tri", shape.Name)
+	assert.Equal(t, []Point{{0, 0}, {1, 0}, {0, 1}}, shape.Points)
+}
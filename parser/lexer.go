@@ -0,0 +1,202 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type TokenKind int
+
+const (
+	EOF TokenKind = iota
+	Number
+	String
+	Ident   // names and keywords - x, odd, null?, infinite-loop
+	Symbol  // operator names - -, +, <=, >>
+	Punct   // ( ) [ ] , ; :
+	Invalid // a character that cannot start any token
+)
+
+func (k TokenKind) String() string {
+	return [...]string{"end of input", "number", "string", "identifier", "symbol", "punctuation", "invalid"}[k]
+}
+
+// Pos is a position in the source - both 1 based.
+type Pos struct {
+	Line int
+	Col  int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("line %d, col %d", p.Line, p.Col)
+}
+
+type Token struct {
+	Kind TokenKind
+	Text string
+	Pos  Pos
+
+	// Whether whitespace or a comment came before the token
+	SpaceBefore bool
+}
+
+func (t Token) String() string {
+	if t.Kind == EOF {
+		return "end of input"
+	}
+	return fmt.Sprintf("'%s'", t.Text)
+}
+
+const symbolChars = "+-*/<>=!%^&|$?~@"
+const punctChars = "()[]{},;:."
+
+// lexer splits source into tokens.  Comments run from "//" to the end of the
+// line.
+type lexer struct {
+	src string
+	off int
+	pos Pos
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src, pos: Pos{1, 1}}
+}
+
+func (l *lexer) peekRune(ahead int) rune {
+	off := l.off
+	for ; ahead > 0 && off < len(l.src); ahead-- {
+		_, size := utf8.DecodeRuneInString(l.src[off:])
+		off += size
+	}
+	if off >= len(l.src) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[off:])
+	return r
+}
+
+func (l *lexer) advance() rune {
+	r, size := utf8.DecodeRuneInString(l.src[l.off:])
+	l.off += size
+	if r == '\n' {
+		l.pos.Line++
+		l.pos.Col = 1
+	} else {
+		l.pos.Col++
+	}
+	return r
+}
+
+func (l *lexer) skipSpaceAndComments() {
+	for l.off < len(l.src) {
+		r := l.peekRune(0)
+		if unicode.IsSpace(r) {
+			l.advance()
+		} else if r == '/' && l.peekRune(1) == '/' {
+			for l.off < len(l.src) && l.peekRune(0) != '\n' {
+				l.advance()
+			}
+		} else {
+			return
+		}
+	}
+}
+
+func (l *lexer) skipDigits() {
+	for unicode.IsDigit(l.peekRune(0)) {
+		l.advance()
+	}
+}
+
+// skipExponent skips the exponent of a number, eg the e3 of 1.5e3 or the
+// E-4 of 2E-4, if there is one.
+func (l *lexer) skipExponent() {
+	if e := l.peekRune(0); e != 'e' && e != 'E' {
+		return
+	}
+	digits := 1
+	if sign := l.peekRune(1); sign == '+' || sign == '-' {
+		digits = 2
+	}
+	if !unicode.IsDigit(l.peekRune(digits)) {
+		return
+	}
+	for range digits {
+		l.advance()
+	}
+	l.skipDigits()
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentPart(r rune) bool {
+	return r == '_' || r == '?' || r == '!' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// next returns the next token in the source.
+func (l *lexer) next() Token {
+	start := l.off
+	l.skipSpaceAndComments()
+	tok := Token{Pos: l.pos, SpaceBefore: l.off > start}
+	start = l.off
+	r := l.peekRune(0)
+	switch {
+	case l.off >= len(l.src):
+		tok.Kind = EOF
+	case unicode.IsDigit(r) || (r == '-' && unicode.IsDigit(l.peekRune(1))):
+		// There are no infix operators so a '-' before a digit is a sign
+		tok.Kind = Number
+		l.advance()
+		l.skipDigits()
+		switch {
+		case l.peekRune(0) == '.' && unicode.IsDigit(l.peekRune(1)):
+			l.advance()
+			l.skipDigits()
+			l.skipExponent()
+		case l.peekRune(0) == '/' && unicode.IsDigit(l.peekRune(1)):
+			// An exact rational, eg 1/3
+			l.advance()
+			l.skipDigits()
+		default:
+			l.skipExponent()
+		}
+	case r == '"':
+		tok.Kind = String
+		l.advance()
+		for {
+			c := l.peekRune(0)
+			if l.off >= len(l.src) || c == '\n' {
+				tok.Kind = Invalid
+				break
+			}
+			l.advance()
+			if c == '\\' {
+				l.advance()
+			} else if c == '"' {
+				break
+			}
+		}
+	case isIdentStart(r):
+		tok.Kind = Ident
+		for isIdentPart(l.peekRune(0)) || (l.peekRune(0) == '-' && isIdentStart(l.peekRune(1))) {
+			l.advance()
+		}
+	case strings.ContainsRune(symbolChars, r):
+		tok.Kind = Symbol
+		for l.off < len(l.src) && strings.ContainsRune(symbolChars, l.peekRune(0)) {
+			l.advance()
+		}
+	case strings.ContainsRune(punctChars, r):
+		tok.Kind = Punct
+		l.advance()
+	default:
+		tok.Kind = Invalid
+		l.advance()
+	}
+	tok.Text = l.src[start:l.off]
+	return tok
+}
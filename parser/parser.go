@@ -0,0 +1,1323 @@
+// Package parser turns EPL source into the AST of the chapter3, chapter4,
+// chapter5, chapter7, chapter8 and chapter9 languages.  The grammar is that
+// of the book:
+//
+//	Expr ::= Number | String | true | false | Identifier
+//	       | Identifier( Expr, ... )             operator application, eg isnull(l)
+//	       | Symbol ( Expr, ... )                operator application, eg -(x, 1)
+//	       | ( Expr )
+//	       | ( Expr Expr ... )                   procedure call
+//	       | Expr()                              procedure call without arguments, eg (f)()
+//	       | ( ) | ( Expr , ) | ( Expr, Expr, ... )  tuples
+//	       | Expr.Number                         the item of a tuple, from 0
+//	       | { Identifier = Expr, ... }           a record
+//	       | { Expr with Identifier = Expr, ... } a copy of a record with new field values
+//	       | Expr.Identifier                     a field of a record
+//	       | isz Expr
+//	       | if Expr then Expr else Expr
+//	       | let Identifier = Expr ... in Expr
+//	       | unpack Identifier ... = Expr in Expr
+//	       | proc ( Param, ... ) [-> Annot] Expr
+//	       | letrec Identifier ( Param, ... ) [-> Annot] = Expr ... in Expr
+//	       | newref ( Expr ) | deref ( Expr ) | setref ( Expr, Expr ) | ref Identifier
+//	       | begin Expr ; ... end
+//	       | set Identifier = Expr | set Expr.Identifier = Expr
+//	       | lazy Expr | thunk Expr
+//	       | try Expr catch ( Identifier ) Expr | raise Expr
+//	       | tag Identifier Expr
+//	       | cases Expr of Identifier ( Identifier ) => Expr ... [else => Expr] end
+//	       | Datatype ... Expr                    a program declaring datatypes
+//	       | match Expr with [|] Pattern => Expr | ... end
+//	       | Module ... Expr                      a program using modules
+//	       | from Identifier take Identifier
+//	       | Class ... Expr                       a program using classes
+//	       | new Identifier ( Expr, ... )
+//	       | send Expr Identifier ( Expr, ... )
+//	       | super Identifier ( Expr, ... )
+//	       | self
+//
+//	Datatype ::= define-datatype Identifier [( Identifier, ... )]
+//	                 = Identifier ( Type, ... ) | ...
+//	Pattern  ::= _ | Identifier | Identifier ( Pattern, ... ) | ( Pattern, ... )
+//	Module ::= module Identifier interface [ Identifier : Type, ... ]
+//	                             body [ Identifier = Expr, ... ]
+//	                             where the Types are int, bool or procedures of them
+//	Param  ::= Identifier [: Annot]
+//	Annot  ::= Type | ?                           ? is a type to be inferred
+//	Type   ::= int | bool | string | list Type | refto Type | lazy Type
+//	         | ( Type * ... -> Type ) | ( Type, ... ) | ( Type )
+//	         | [ Identifier Type | ... ]          a closed union of tagged values
+//	         | { Identifier : Type, ... }          a closed record
+//	         | Identifier Type ...                a datatype applied to its parameters
+//	Class  ::= class Identifier extends Identifier
+//	                 field Identifier ...
+//	                 method Identifier ( Identifier, ... ) Expr ...
+//
+// Every construct is parsed regardless of language level - an evaluator that
+// does not support one returns an UnsupportedExprError.  Datatypes and
+// their constructors must be declared before they are used - constructors
+// are applied like procedures, so 'Leaf(1)' is a call and not an operator,
+// and a constructor without fields is a pattern rather than a variable.
+//
+// Numbers are integers of any size, decimals like -2.5 or 1.5e3 and exact
+// rationals like 1/3 (with no spaces around the '/').
+package parser
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/panyam/eplgo/chapter3"
+	"github.com/panyam/eplgo/chapter4"
+	"github.com/panyam/eplgo/chapter5"
+	"github.com/panyam/eplgo/chapter7"
+	"github.com/panyam/eplgo/chapter8"
+	"github.com/panyam/eplgo/chapter9"
+)
+
+type Expr = chapter3.Expr
+
+// SyntaxError is returned for source that cannot be parsed.
+type SyntaxError struct {
+	Pos Pos
+	Msg string
+}
+
+func (e SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at %s: %s", e.Pos, e.Msg)
+}
+
+// Keywords cannot be used as variable names.
+var Keywords = []string{
+	"begin", "body", "cases", "catch", "class", "define-datatype", "deref",
+	"else", "end", "extends", "false", "field", "from", "if", "in",
+	"interface", "isz", "lazy", "let", "letrec", "match", "method", "module",
+	"new", "newref", "of", "proc", "raise", "ref", "self", "send", "set",
+	"setref", "super", "tag", "take", "then", "thunk", "true", "try", "unpack", "with",
+}
+
+// Parse parses a complete EPL program.
+func Parse(src string) (Expr, error) {
+	p := &Parser{lexer: newLexer(src), datatypes: map[string]int{}, constructors: map[string]bool{}}
+	p.tok = p.lexer.next()
+	expr, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.Kind != EOF {
+		return nil, p.errorf("expected end of input, found %s", p.tok)
+	}
+	return expr, nil
+}
+
+// Parser is a recursive descent parser over a stream of tokens.
+type Parser struct {
+	lexer *lexer
+	tok   Token // the current token
+
+	// The datatypes declared so far with the number of their parameters,
+	// and their constructors
+	datatypes    map[string]int
+	constructors map[string]bool
+
+	// The parameters of the datatype being declared
+	typeParams []string
+}
+
+func (p *Parser) errorf(format string, args ...any) error {
+	return SyntaxError{Pos: p.tok.Pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *Parser) advance() Token {
+	tok := p.tok
+	p.tok = p.lexer.next()
+	return tok
+}
+
+func (p *Parser) is(kind TokenKind, text string) bool {
+	return p.tok.Kind == kind && p.tok.Text == text
+}
+
+func (p *Parser) isKeyword(kw string) bool {
+	return p.is(Ident, kw)
+}
+
+// expect consumes the current token if it has the given text.
+func (p *Parser) expect(kind TokenKind, text string) error {
+	if !p.is(kind, text) {
+		return p.errorf("expected '%s', found %s", text, p.tok)
+	}
+	p.advance()
+	return nil
+}
+
+func (p *Parser) expectName() (string, error) {
+	if p.tok.Kind != Ident || slices.Contains(Keywords, p.tok.Text) {
+		return "", p.errorf("expected a name, found %s", p.tok)
+	}
+	return p.advance().Text, nil
+}
+
+// ParseExpr parses a single expression.
+func (p *Parser) ParseExpr() (Expr, error) {
+	e, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for !p.tok.SpaceBefore {
+		switch {
+		case p.is(Punct, "."):
+			p.advance()
+			if e, err = p.parseSelector(e); err != nil {
+				return nil, err
+			}
+		case p.is(Punct, "("):
+			// (f)() calls a procedure with no arguments - (f) is just f
+			p.advance()
+			if err := p.expect(Punct, ")"); err != nil {
+				return nil, err
+			}
+			e = chapter3.Call(e)
+		default:
+			return e, nil
+		}
+	}
+	return e, nil
+}
+
+// parseSelector parses what follows the '.' in 'e.0' or 'e.x'.  As 't.0.1'
+// is lexed with a number 0.1 each part of a number is an index.
+func (p *Parser) parseSelector(e Expr) (Expr, error) {
+	if p.tok.Kind == Ident && !p.tok.SpaceBefore && !slices.Contains(Keywords, p.tok.Text) {
+		return chapter3.Field(e, p.advance().Text), nil
+	}
+	if p.tok.Kind != Number || p.tok.SpaceBefore || strings.HasPrefix(p.tok.Text, "-") {
+		return nil, p.errorf("expected a tuple index or field name, found %s", p.tok)
+	}
+	tok := p.advance()
+	for _, part := range strings.Split(tok.Text, ".") {
+		index, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, SyntaxError{Pos: tok.Pos, Msg: "invalid tuple index " + tok.Text}
+		}
+		e = chapter3.TupleRef(e, index)
+	}
+	return e, nil
+}
+
+// parsePrimary parses an expression without any selectors.
+func (p *Parser) parsePrimary() (Expr, error) {
+	switch p.tok.Kind {
+	case Number:
+		return p.parseNumber()
+	case String:
+		tok := p.advance()
+		s, err := strconv.Unquote(tok.Text)
+		if err != nil {
+			return nil, SyntaxError{Pos: tok.Pos, Msg: "invalid string " + tok.Text}
+		}
+		return chapter3.Lit(s), nil
+	case Symbol:
+		op := p.advance().Text
+		return p.parseOpArgs(op)
+	case Punct:
+		if p.is(Punct, "(") {
+			return p.parseParens()
+		}
+		if p.is(Punct, "{") {
+			return p.parseRecord()
+		}
+	case Ident:
+		if slices.Contains(Keywords, p.tok.Text) {
+			return p.parseKeyword()
+		}
+		name := p.advance().Text
+		// isnull(l) is an operator but (f (x)) a call
+		if p.is(Punct, "(") && !p.tok.SpaceBefore {
+			if p.constructors[name] {
+				args, err := p.parseArgs()
+				if err != nil {
+					return nil, err
+				}
+				return &chapter3.CallExpr{Operator: chapter3.Var(name), Args: args}, nil
+			}
+			return p.parseOpArgs(name)
+		}
+		return chapter3.Var(name), nil
+	case Invalid:
+		return nil, p.errorf("unexpected character %s", p.tok)
+	}
+	return nil, p.errorf("expected an expression, found %s", p.tok)
+}
+
+func (p *Parser) parseNumber() (Expr, error) {
+	tok := p.advance()
+	if strings.Contains(tok.Text, "/") {
+		r, ok := new(big.Rat).SetString(tok.Text)
+		if !ok {
+			return nil, SyntaxError{Pos: tok.Pos, Msg: "invalid number " + tok.Text}
+		}
+		return chapter3.Lit(r), nil
+	}
+	if strings.ContainsAny(tok.Text, ".eE") {
+		f, err := strconv.ParseFloat(tok.Text, 64)
+		if err != nil {
+			return nil, SyntaxError{Pos: tok.Pos, Msg: "invalid number " + tok.Text}
+		}
+		return chapter3.Lit(f), nil
+	}
+	if i, err := strconv.Atoi(tok.Text); err == nil {
+		return chapter3.Lit(i), nil
+	}
+	// Too big for an int
+	i, _ := new(big.Int).SetString(tok.Text, 10)
+	return chapter3.Lit(i), nil
+}
+
+// parseList parses zero or more items separated by commas up to a closing
+// parenthesis or bracket, which is consumed.
+func parseList[T any](p *Parser, close string, item func() (T, error)) ([]T, error) {
+	var out []T
+	for !p.is(Punct, close) {
+		if len(out) > 0 {
+			if err := p.expect(Punct, ","); err != nil {
+				return nil, err
+			}
+		}
+		val, err := item()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, val)
+	}
+	p.advance()
+	return out, nil
+}
+
+// parseArgs parses a parenthesized, comma separated list of expressions.
+func (p *Parser) parseArgs() ([]Expr, error) {
+	if err := p.expect(Punct, "("); err != nil {
+		return nil, err
+	}
+	return parseList(p, ")", p.ParseExpr)
+}
+
+func (p *Parser) parseParams() ([]string, error) {
+	if err := p.expect(Punct, "("); err != nil {
+		return nil, err
+	}
+	return parseList(p, ")", p.expectName)
+}
+
+func (p *Parser) parseOpArgs(op string) (Expr, error) {
+	args, err := p.parseArgs()
+	if err != nil {
+		return nil, err
+	}
+	return &chapter3.OpExpr{Op: op, Args: args}, nil
+}
+
+// parseParens parses a parenthesized expression, a procedure call or a
+// tuple.
+func (p *Parser) parseParens() (Expr, error) {
+	p.advance()
+	if p.is(Punct, ")") {
+		p.advance()
+		return chapter3.Tuple(), nil
+	}
+	first, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.is(Punct, ",") {
+		p.advance()
+		rest, err := parseList(p, ")", p.ParseExpr)
+		if err != nil {
+			return nil, err
+		}
+		return chapter3.Tuple(append([]Expr{first}, rest...)...), nil
+	}
+	var args []any
+	for !p.is(Punct, ")") {
+		arg, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.advance()
+	if args == nil {
+		return first, nil
+	}
+	return chapter3.Call(first, args...), nil
+}
+
+// parseRecord parses a record literal or the update '{r with x = e}'.
+func (p *Parser) parseRecord() (Expr, error) {
+	p.advance()
+	fields := map[string]Expr{}
+	if p.is(Punct, "}") {
+		p.advance()
+		return chapter3.Record(fields), nil
+	}
+	first := p.tok
+	e, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.isKeyword("with") {
+		p.advance()
+		if err := p.parseFields(fields); err != nil {
+			return nil, err
+		}
+		return chapter3.Update(e, fields), nil
+	}
+	name, ok := e.(*chapter3.VarExpr)
+	if !ok || first.Kind != Ident {
+		return nil, SyntaxError{Pos: first.Pos, Msg: fmt.Sprintf("expected a field name, found %s", first)}
+	}
+	if err := p.parseFieldValue(fields, first.Pos, name.Name); err != nil {
+		return nil, err
+	}
+	if err := p.parseFields(fields); err != nil {
+		return nil, err
+	}
+	return chapter3.Record(fields), nil
+}
+
+// parseFields parses comma separated 'name = e' fields, after any already in
+// fields, up to the closing brace, which is consumed.
+func (p *Parser) parseFields(fields map[string]Expr) error {
+	for len(fields) == 0 || !p.is(Punct, "}") {
+		if len(fields) > 0 {
+			if err := p.expect(Punct, ","); err != nil {
+				return err
+			}
+		}
+		pos := p.tok.Pos
+		name, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		if err := p.parseFieldValue(fields, pos, name); err != nil {
+			return err
+		}
+	}
+	p.advance()
+	return nil
+}
+
+// parseFieldValue parses the '= e' of the field name.
+func (p *Parser) parseFieldValue(fields map[string]Expr, pos Pos, name string) error {
+	if _, exists := fields[name]; exists {
+		return SyntaxError{Pos: pos, Msg: fmt.Sprintf("'%s' is in the record more than once", name)}
+	}
+	if err := p.expect(Symbol, "="); err != nil {
+		return err
+	}
+	var err error
+	fields[name], err = p.ParseExpr()
+	return err
+}
+
+// parseKeyword parses the constructs introduced by keywords.
+func (p *Parser) parseKeyword() (Expr, error) {
+	switch kw := p.advance().Text; kw {
+	case "true", "false":
+		return chapter3.Lit(kw == "true"), nil
+	case "isz":
+		e, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return chapter3.IsZero(e), nil
+	case "if":
+		return p.parseIf()
+	case "let":
+		return p.parseLet()
+	case "unpack":
+		return p.parseUnpack()
+	case "proc":
+		return p.parseProc()
+	case "letrec":
+		return p.parseLetRec()
+	case "newref", "deref", "setref":
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		arity := map[string]int{"newref": 1, "deref": 1, "setref": 2}[kw]
+		if len(args) != arity {
+			return nil, p.errorf("%s takes %d arguments, found %d", kw, arity, len(args))
+		}
+		switch kw {
+		case "newref":
+			return chapter4.NewRef(args[0]), nil
+		case "deref":
+			return chapter4.DeRef(args[0]), nil
+		}
+		return chapter4.SetRef(args[0], args[1]), nil
+	case "ref":
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		return chapter4.RefVar(name), nil
+	case "begin":
+		return p.parseBegin()
+	case "set":
+		return p.parseSet()
+	case "lazy", "thunk", "raise":
+		e, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+		switch kw {
+		case "lazy":
+			return chapter4.Lazy(e), nil
+		case "thunk":
+			return chapter4.ForceThunk(e), nil
+		}
+		return chapter5.Raise(e), nil
+	case "try":
+		return p.parseTry()
+	case "tag":
+		tag, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		e, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return chapter7.Tag(tag, e), nil
+	case "cases":
+		return p.parseCases()
+	case "define-datatype":
+		return p.parseDataProgram()
+	case "match":
+		return p.parseMatch()
+	case "module":
+		return p.parseProgram()
+	case "from":
+		module, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(Ident, "take"); err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		return chapter8.QualifiedVar(module, name), nil
+	case "class":
+		return p.parseClassProgram()
+	case "new":
+		class, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		return &chapter9.NewObjectExpr{Class: class, Args: args}, nil
+	case "send":
+		obj, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+		method, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		return &chapter9.MethodCallExpr{Object: obj, Method: method, Args: args}, nil
+	case "super":
+		method, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		return &chapter9.SuperCallExpr{Method: method, Args: args}, nil
+	case "self":
+		return chapter9.Self(), nil
+	default:
+		return nil, p.errorf("unexpected '%s'", kw)
+	}
+}
+
+func (p *Parser) parseIf() (Expr, error) {
+	cond, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(Ident, "then"); err != nil {
+		return nil, err
+	}
+	then, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(Ident, "else"); err != nil {
+		return nil, err
+	}
+	els, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return chapter3.If(cond, then, els), nil
+}
+
+func (p *Parser) parseLet() (Expr, error) {
+	mappings := map[string]Expr{}
+	for !p.isKeyword("in") || len(mappings) == 0 {
+		pos := p.tok.Pos
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := mappings[name]; exists {
+			return nil, SyntaxError{Pos: pos, Msg: fmt.Sprintf("'%s' is bound more than once", name)}
+		}
+		if err := p.expect(Symbol, "="); err != nil {
+			return nil, err
+		}
+		if mappings[name], err = p.ParseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	p.advance()
+	body, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return chapter3.Let(mappings, body), nil
+}
+
+// parseSet parses the assignment 'set x = e' or 'set r.x = e' which sets a
+// field of a record.
+func (p *Parser) parseSet() (Expr, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	var target Expr = chapter3.Var(name)
+	for p.is(Punct, ".") && !p.tok.SpaceBefore {
+		pos := p.advance().Pos
+		if target, err = p.parseSelector(target); err != nil {
+			return nil, err
+		}
+		if _, ok := target.(*chapter3.FieldExpr); !ok {
+			return nil, SyntaxError{Pos: pos, Msg: "cannot set the item of a tuple"}
+		}
+	}
+	if err := p.expect(Symbol, "="); err != nil {
+		return nil, err
+	}
+	e, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if field, ok := target.(*chapter3.FieldExpr); ok {
+		return chapter4.SetField(field.Record, field.Field, e), nil
+	}
+	return chapter4.Assign(name, e), nil
+}
+
+func (p *Parser) parseUnpack() (Expr, error) {
+	var names []string
+	for !p.is(Symbol, "=") || len(names) == 0 {
+		pos := p.tok.Pos
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(names, name) {
+			return nil, SyntaxError{Pos: pos, Msg: fmt.Sprintf("'%s' is bound more than once", name)}
+		}
+		names = append(names, name)
+	}
+	p.advance()
+	e, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(Ident, "in"); err != nil {
+		return nil, err
+	}
+	body, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return chapter3.Unpack(names, e, body), nil
+}
+
+func (p *Parser) parseProc() (Expr, error) {
+	proc, err := p.parseSignature()
+	if err != nil {
+		return nil, err
+	}
+	if proc.Body, err = p.ParseExpr(); err != nil {
+		return nil, err
+	}
+	return proc, nil
+}
+
+// parseSignature parses the parameters of a procedure, each optionally
+// annotated with its type, and an optional '-> Type' result annotation.
+// The returned procedure has no body.
+func (p *Parser) parseSignature() (*chapter3.ProcExpr, error) {
+	if err := p.expect(Punct, "("); err != nil {
+		return nil, err
+	}
+	proc := &chapter3.ProcExpr{}
+	annotated := false
+	_, err := parseList(p, ")", func() (string, error) {
+		name, err := p.expectName()
+		if err != nil {
+			return "", err
+		}
+		var t chapter3.TypeAnnotation
+		if p.is(Punct, ":") {
+			p.advance()
+			if t, err = p.parseAnnotation(); err != nil {
+				return "", err
+			}
+			annotated = t != nil
+		}
+		proc.Varnames = append(proc.Varnames, name)
+		proc.ArgTypes = append(proc.ArgTypes, t)
+		return name, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !annotated {
+		proc.ArgTypes = nil
+	}
+	if p.is(Symbol, "->") {
+		p.advance()
+		if proc.ResultType, err = p.parseAnnotation(); err != nil {
+			return nil, err
+		}
+	}
+	return proc, nil
+}
+
+// parseAnnotation parses a type annotation, returning nil for '?' - a type
+// left to be inferred.
+func (p *Parser) parseAnnotation() (chapter3.TypeAnnotation, error) {
+	if p.is(Symbol, "?") {
+		p.advance()
+		return nil, nil
+	}
+	return p.parseType()
+}
+
+func (p *Parser) parseLetRec() (Expr, error) {
+	procs := map[string]*chapter3.ProcExpr{}
+	for !p.isKeyword("in") || len(procs) == 0 {
+		pos := p.tok.Pos
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := procs[name]; exists {
+			return nil, SyntaxError{Pos: pos, Msg: fmt.Sprintf("'%s' is bound more than once", name)}
+		}
+		proc, err := p.parseSignature()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(Symbol, "="); err != nil {
+			return nil, err
+		}
+		if proc.Body, err = p.ParseExpr(); err != nil {
+			return nil, err
+		}
+		procs[name] = proc
+	}
+	p.advance()
+	body, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return chapter3.LetRec(procs, body), nil
+}
+
+func (p *Parser) parseBegin() (Expr, error) {
+	var exprs []any
+	for {
+		e, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+		if p.isKeyword("end") {
+			p.advance()
+			return chapter4.Begin(exprs...), nil
+		}
+		if err := p.expect(Punct, ";"); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *Parser) parseTry() (Expr, error) {
+	body, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(Ident, "catch"); err != nil {
+		return nil, err
+	}
+	if err := p.expect(Punct, "("); err != nil {
+		return nil, err
+	}
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(Punct, ")"); err != nil {
+		return nil, err
+	}
+	handler, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return chapter5.Try(body, name, handler), nil
+}
+
+// parseCases parses the rest of 'cases e of Tag (x) => body ... [else => body] end'.
+func (p *Parser) parseCases() (Expr, error) {
+	expr, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(Ident, "of"); err != nil {
+		return nil, err
+	}
+	var arms []*chapter7.CaseArm
+	var els Expr
+	for !p.isKeyword("end") {
+		if p.isKeyword("else") {
+			p.advance()
+			if err := p.expect(Symbol, "=>"); err != nil {
+				return nil, err
+			}
+			if els, err = p.ParseExpr(); err != nil {
+				return nil, err
+			}
+			if !p.isKeyword("end") {
+				return nil, p.errorf("expected 'end' after the else arm, found %s", p.tok)
+			}
+			break
+		}
+		tag, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(Punct, "("); err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(Punct, ")"); err != nil {
+			return nil, err
+		}
+		if err := p.expect(Symbol, "=>"); err != nil {
+			return nil, err
+		}
+		body, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+		arms = append(arms, chapter7.Arm(tag, name, body))
+	}
+	p.advance()
+	return chapter7.Cases(expr, arms, els), nil
+}
+
+// parseDataProgram parses datatype declarations followed by the expression
+// using them.  The first 'define-datatype' keyword has already been consumed.
+func (p *Parser) parseDataProgram() (Expr, error) {
+	var types []*chapter7.DatatypeDecl
+	for {
+		d, err := p.parseDatatype()
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, d)
+		if !p.isKeyword("define-datatype") {
+			break
+		}
+		p.advance()
+	}
+	body, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return chapter7.DataProgram(types, body), nil
+}
+
+func (p *Parser) parseDatatype() (*chapter7.DatatypeDecl, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	var params []string
+	if p.is(Punct, "(") {
+		if params, err = p.parseParams(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect(Symbol, "="); err != nil {
+		return nil, err
+	}
+	// The datatype can refer to itself
+	p.datatypes[name], p.typeParams = len(params), params
+	defer func() { p.typeParams = nil }()
+	d := chapter7.Datatype(name, params)
+	for len(d.Variants) == 0 || p.is(Symbol, "|") {
+		if len(d.Variants) > 0 {
+			p.advance()
+		}
+		pos := p.tok.Pos
+		ctor, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if p.constructors[ctor] {
+			return nil, SyntaxError{Pos: pos, Msg: fmt.Sprintf("constructor '%s' is declared more than once", ctor)}
+		}
+		if err := p.expect(Punct, "("); err != nil {
+			return nil, err
+		}
+		fields, err := parseList(p, ")", p.parseType)
+		if err != nil {
+			return nil, err
+		}
+		p.constructors[ctor] = true
+		d.Variants = append(d.Variants, chapter7.Variant(ctor, fields...))
+	}
+	return d, nil
+}
+
+// parseMatch parses the rest of 'match e with Pattern => body | ... end'.
+func (p *Parser) parseMatch() (Expr, error) {
+	expr, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(Ident, "with"); err != nil {
+		return nil, err
+	}
+	// The first arm may also start with a '|'
+	if p.is(Symbol, "|") {
+		p.advance()
+	}
+	var arms []*chapter7.MatchArm
+	for {
+		pos := p.tok.Pos
+		pattern, err := p.parsePattern()
+		if err != nil {
+			return nil, err
+		}
+		vars := chapter7.PatternVars(pattern)
+		for i, name := range vars {
+			if slices.Contains(vars[:i], name) {
+				return nil, SyntaxError{Pos: pos, Msg: fmt.Sprintf("'%s' is bound more than once", name)}
+			}
+		}
+		if err := p.expect(Symbol, "=>"); err != nil {
+			return nil, err
+		}
+		body, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+		arms = append(arms, chapter7.MatchCase(pattern, body))
+		if p.isKeyword("end") {
+			p.advance()
+			return chapter7.Match(expr, arms...), nil
+		}
+		if err := p.expect(Symbol, "|"); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parsePattern parses the pattern of an arm of a match.
+func (p *Parser) parsePattern() (chapter7.Pattern, error) {
+	if p.is(Ident, "_") {
+		p.advance()
+		return chapter7.Wildcard(), nil
+	}
+	if p.is(Punct, "(") {
+		p.advance()
+		children, err := parseList(p, ")", p.parsePattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(children) == 1 {
+			return children[0], nil
+		}
+		return chapter7.PatTuple(children...), nil
+	}
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if p.is(Punct, "(") {
+		p.advance()
+		args, err := parseList(p, ")", p.parsePattern)
+		if err != nil {
+			return nil, err
+		}
+		return chapter7.PatCons(name, args...), nil
+	}
+	if p.constructors[name] {
+		return chapter7.PatCons(name), nil
+	}
+	return chapter7.PatVar(name), nil
+}
+
+// parseProgram parses module definitions followed by the expression using
+// them.  The first 'module' keyword has already been consumed.
+func (p *Parser) parseProgram() (Expr, error) {
+	var modules []*chapter8.ModuleDefn
+	for {
+		m, err := p.parseModule()
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, m)
+		if !p.isKeyword("module") {
+			break
+		}
+		p.advance()
+	}
+	body, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return chapter8.Program(modules, body), nil
+}
+
+func (p *Parser) parseModule() (*chapter8.ModuleDefn, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(Ident, "interface"); err != nil {
+		return nil, err
+	}
+	if err := p.expect(Punct, "["); err != nil {
+		return nil, err
+	}
+	decls, err := parseList(p, "]", func() (*chapter8.VarDecl, error) {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(Punct, ":"); err != nil {
+			return nil, err
+		}
+		t, err := p.parseInterfaceType()
+		if err != nil {
+			return nil, err
+		}
+		return chapter8.Decl(name, t), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(Ident, "body"); err != nil {
+		return nil, err
+	}
+	if err := p.expect(Punct, "["); err != nil {
+		return nil, err
+	}
+	defns, err := parseList(p, "]", func() (*chapter8.VarDefn, error) {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(Symbol, "="); err != nil {
+			return nil, err
+		}
+		e, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return chapter8.Defn(name, e), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return chapter8.Module(name, decls, defns), nil
+}
+
+// parseInterfaceType parses the type of a name in a module interface - an
+// int, a bool or a procedure of those.
+func (p *Parser) parseInterfaceType() (chapter8.Type, error) {
+	pos := p.tok.Pos
+	t, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	out, ok := interfaceType(t)
+	if !ok {
+		return nil, SyntaxError{Pos: pos, Msg: fmt.Sprintf("%s cannot be declared in a module interface", t)}
+	}
+	return out, nil
+}
+
+func interfaceType(t chapter7.Type) (chapter8.Type, bool) {
+	switch t := t.(type) {
+	case chapter7.IntType:
+		return chapter8.IntType{}, true
+	case chapter7.BoolType:
+		return chapter8.BoolType{}, true
+	case *chapter7.FuncType:
+		result, ok := interfaceType(t.Result)
+		args := make([]chapter8.Type, len(t.Args))
+		for i, arg := range t.Args {
+			var argOk bool
+			args[i], argOk = interfaceType(arg)
+			ok = ok && argOk
+		}
+		return chapter8.ProcOf(result, args...), ok
+	}
+	return nil, false
+}
+
+// parseClassProgram parses class declarations followed by the expression
+// using them.  The first 'class' keyword has already been consumed.
+func (p *Parser) parseClassProgram() (Expr, error) {
+	var classes []*chapter9.ClassDecl
+	for {
+		c, err := p.parseClass()
+		if err != nil {
+			return nil, err
+		}
+		classes = append(classes, c)
+		if !p.isKeyword("class") {
+			break
+		}
+		p.advance()
+	}
+	body, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return chapter9.Program(classes, body), nil
+}
+
+func (p *Parser) parseClass() (*chapter9.ClassDecl, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(Ident, "extends"); err != nil {
+		return nil, err
+	}
+	super, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	class := chapter9.Class(name, super, nil)
+	for p.isKeyword("field") {
+		p.advance()
+		field, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		class.Fields = append(class.Fields, field)
+	}
+	for p.isKeyword("method") {
+		p.advance()
+		method, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		params, err := p.parseParams()
+		if err != nil {
+			return nil, err
+		}
+		body, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+		class.Methods = append(class.Methods, chapter9.Method(method, params, body))
+	}
+	return class, nil
+}
+
+// parseType parses a type annotation or the type of a name in a module
+// interface.
+func (p *Parser) parseType() (chapter7.Type, error) {
+	switch {
+	case p.isKeyword("int"):
+		p.advance()
+		return chapter7.Int, nil
+	case p.isKeyword("bool"):
+		p.advance()
+		return chapter7.Bool, nil
+	case p.is(Ident, "string"):
+		p.advance()
+		return chapter7.String, nil
+	case p.is(Ident, "list"), p.is(Ident, "refto"), p.is(Ident, "lazy"):
+		name := p.tok.Text
+		p.advance()
+		elem, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		return chapter7.Tagged(name, elem), nil
+	case p.is(Punct, "["):
+		return p.parseUnionType()
+	case p.is(Punct, "{"):
+		return p.parseRecordType()
+	case p.tok.Kind == Ident && slices.Contains(p.typeParams, p.tok.Text):
+		return chapter7.Data(p.advance().Text), nil
+	case p.isDatatype():
+		return p.parseDataType()
+	case p.is(Punct, "("):
+		p.advance()
+	default:
+		return nil, p.errorf("expected a type, found %s", p.tok)
+	}
+	// A procedure, a tuple or a parenthesized type
+	var first chapter7.Type
+	if !p.is(Symbol, "->") {
+		var err error
+		if first, err = p.parseType(); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case p.is(Punct, ")"):
+		p.advance()
+		return first, nil
+	case p.is(Punct, ","):
+		p.advance()
+		rest, err := parseList(p, ")", p.parseType)
+		if err != nil {
+			return nil, err
+		}
+		return chapter7.TupleOf(append([]chapter7.Type{first}, rest...)...), nil
+	}
+	var args []chapter7.Type
+	if first != nil {
+		args = append(args, first)
+	}
+	for !p.is(Symbol, "->") {
+		if err := p.expect(Symbol, "*"); err != nil {
+			return nil, err
+		}
+		arg, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.advance()
+	result, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(Punct, ")"); err != nil {
+		return nil, err
+	}
+	return chapter7.Func(args, result), nil
+}
+
+func (p *Parser) isDatatype() bool {
+	_, ok := p.datatypes[p.tok.Text]
+	return p.tok.Kind == Ident && ok
+}
+
+// parseDataType parses a declared datatype followed by the types for each of
+// its parameters, eg 'option int'.
+func (p *Parser) parseDataType() (chapter7.Type, error) {
+	name := p.advance().Text
+	out := chapter7.Data(name)
+	for range p.datatypes[name] {
+		arg, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		out.Args = append(out.Args, arg)
+	}
+	return out, nil
+}
+
+// parseUnionType parses the closed union type '[Tag Type | ...]'.
+func (p *Parser) parseUnionType() (chapter7.Type, error) {
+	p.advance()
+	options := map[string]chapter7.Type{}
+	for !p.is(Punct, "]") {
+		if len(options) > 0 {
+			if err := p.expect(Symbol, "|"); err != nil {
+				return nil, err
+			}
+		}
+		pos := p.tok.Pos
+		tag, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := options[tag]; exists {
+			return nil, SyntaxError{Pos: pos, Msg: fmt.Sprintf("'%s' is in the union more than once", tag)}
+		}
+		if options[tag], err = p.parseType(); err != nil {
+			return nil, err
+		}
+	}
+	p.advance()
+	return chapter7.Union(options, nil), nil
+}
+
+// parseRecordType parses the closed record type '{name : Type, ...}'.
+func (p *Parser) parseRecordType() (chapter7.Type, error) {
+	p.advance()
+	fields := map[string]chapter7.Type{}
+	for !p.is(Punct, "}") {
+		if len(fields) > 0 {
+			if err := p.expect(Punct, ","); err != nil {
+				return nil, err
+			}
+		}
+		pos := p.tok.Pos
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := fields[name]; exists {
+			return nil, SyntaxError{Pos: pos, Msg: fmt.Sprintf("'%s' is in the record more than once", name)}
+		}
+		if err := p.expect(Punct, ":"); err != nil {
+			return nil, err
+		}
+		if fields[name], err = p.parseType(); err != nil {
+			return nil, err
+		}
+	}
+	p.advance()
+	return chapter7.RecordOf(fields, nil), nil
+}
@@ -0,0 +1,523 @@
+package parser_test
+
+import (
+	"math/big"
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	. "github.com/panyam/eplgo/chapter3"
+	"github.com/panyam/eplgo/chapter4"
+	"github.com/panyam/eplgo/chapter5"
+	"github.com/panyam/eplgo/chapter7"
+	"github.com/panyam/eplgo/chapter8"
+	"github.com/panyam/eplgo/chapter9"
+	"github.com/panyam/eplgo/parser"
+	"github.com/panyam/eplgo/prelude"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runTest(t *testing.T, input string, expected Expr) {
+	t.Helper()
+	expr, err := parser.Parse(input)
+	require.NoError(t, err, "parsing %q", input)
+	assert.True(t, ExprEq(expected, expr), "parsing %q\nexpected: %s\nfound:    %s", input, expected.Repr(), expr.Repr())
+}
+
+// Ported from tests/parser/test_parser.py
+
+func TestParseNum(t *testing.T) {
+	runTest(t, "3", Lit(3))
+	runTest(t, "-3", Lit(-3))
+	runTest(t, "2.5", Lit(2.5))
+	runTest(t, "123456789012345678901234567890", Lit(bigInt("123456789012345678901234567890")))
+	runTest(t, "1.5e3", Lit(1500.0))
+	runTest(t, "2E-2", Lit(0.02))
+	runTest(t, "-1e+2", Lit(-100.0))
+	runTest(t, "(e 2e)", Call("e", Lit(2), Var("e")))
+	runTest(t, "1/3", Lit(big.NewRat(1, 3)))
+	runTest(t, "-2/4", Lit(big.NewRat(-1, 2)))
+	runTest(t, "4/2", Lit(2))
+	runTest(t, "/(1, 3)", Op("/", 1, 3))
+
+	// Rationals are exact
+	expr, err := parser.Parse("-(+(1/3, 1/6), 1/2)")
+	require.NoError(t, err)
+	val, err := SetOpFuncs(NewLetRecLangEval()).Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	AssertValue(t, "exact", 0, val)
+}
+
+func TestParseVarname(t *testing.T) {
+	runTest(t, "x", Var("x"))
+	runTest(t, "null?", Var("null?"))
+	runTest(t, "infinite-loop", Var("infinite-loop"))
+}
+
+func TestParseParen(t *testing.T) {
+	runTest(t, "( ( ( 666 )) )", Lit(666))
+}
+
+func TestParseOperators(t *testing.T) {
+	runTest(t, "/(x,y)", Op("/", "x", "y"))
+	runTest(t, ">>(x,y)", Op(">>", "x", "y"))
+	runTest(t, "? ( 0 )", Op("?", 0))
+	runTest(t, "- ( 33, 44)", Op("-", 33, 44))
+	runTest(t, "-(x,-1)", Op("-", "x", -1))
+	runTest(t, "$ (3, 4)", Op("$", 3, 4))
+	runTest(t, "emptylist()", Op("emptylist"))
+	runTest(t, "cons(1, emptylist())", Op("cons", 1, Op("emptylist")))
+}
+
+func TestParseIsZero(t *testing.T) {
+	runTest(t, "isz ( ( ( 33 ) ) )", IsZero(33))
+	runTest(t, "(isz x)", IsZero("x"))
+}
+
+func TestParseLiterals(t *testing.T) {
+	runTest(t, `"hello\n"`, Lit("hello\n"))
+	runTest(t, "true", Lit(true))
+	runTest(t, "if false then 1 else 2", If(Lit(false), 1, 2))
+}
+
+func TestParseCalls(t *testing.T) {
+	runTest(t, "(f x)", Call("f", "x"))
+	// An operator needs its parenthesis right after the name - anything else
+	// is a call
+	runTest(t, "(f (x))", Call("f", "x"))
+	runTest(t, "(stringequals (car lst) str)", Call("stringequals", Call("car", "lst"), "str"))
+	runTest(t, "(proc (x) (x 3) 4)", Call(Proc([]string{"x"}, Call("x", 3)), 4))
+	// (f) is f itself - a procedure without parameters is called with ()
+	runTest(t, "(f)", Var("f"))
+	runTest(t, "(f)()", Call("f"))
+	runTest(t, "(proc () 5)()", Call(Proc(nil, Lit(5))))
+	runTest(t, "((make 1))().0", TupleRef(Call(Call("make", 1)), 0))
+}
+
+func TestParseLet(t *testing.T) {
+	runTest(t, "let x = 1 y = 2 in -(x, y)", Let(ExprDict("x", Lit(1), "y", Lit(2)), Op("-", "x", "y")))
+}
+
+func TestParseTuples(t *testing.T) {
+	runTest(t, "(1, true)", Tuple(Lit(1), Lit(true)))
+	runTest(t, "(x,)", Tuple(Var("x")))
+	runTest(t, "()", Tuple())
+	runTest(t, "(f (1, 2))", Call("f", Tuple(Lit(1), Lit(2))))
+	runTest(t, "unpack x y = (1, 2) in -(x, y)", Unpack([]string{"x", "y"}, Tuple(Lit(1), Lit(2)), Op("-", "x", "y")))
+	runTest(t, "t.1", TupleRef("t", 1))
+	runTest(t, "t.0.1", TupleRef(TupleRef("t", 0), 1))
+	runTest(t, "(f x).0.1.2", TupleRef(TupleRef(TupleRef(Call("f", "x"), 0), 1), 2))
+	runTest(t, "let p = (1, 2) in +(p.0, p.1)", Let(ExprDict("p", Tuple(Lit(1), Lit(2))), Op("+", TupleRef("p", 0), TupleRef("p", 1))))
+
+	expr, err := parser.Parse(`
+        let swap = proc (p) unpack a b = p in (b, a)
+        in equal?((swap (1, (2,))), ((2,), 1))`)
+	require.NoError(t, err)
+	val, err := prelude.Install(NewLetRecLangEval()).Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	AssertValue(t, "swap", true, val)
+	val, err = NewLetRecLangEval().Eval(Tuple(Lit(1), Tuple(Lit(2))), epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	assert.Equal(t, "(1, (2,))", val.(Value).String())
+}
+
+func TestParseRecords(t *testing.T) {
+	point := Record(ExprDict("x", Lit(1), "y", Lit(true)))
+	runTest(t, "{x = 1, y = true}", point)
+	runTest(t, "{}", Record(ExprDict()))
+	runTest(t, "r.x", Field("r", "x"))
+	runTest(t, "{x = 1, y = true}.y", Field(point, "y"))
+	runTest(t, "r.p.0.x", Field(TupleRef(Field("r", "p"), 0), "x"))
+	runTest(t, "{r with x = 2}", Update("r", ExprDict("x", Lit(2))))
+	runTest(t, "{(f r) with x = r.x, y = 3}", Update(Call("f", "r"), ExprDict("x", Field("r", "x"), "y", Lit(3))))
+	runTest(t, "set r.x = 2", chapter4.SetField("r", "x", 2))
+	runTest(t, "set r.a.b = 2", chapter4.SetField(Field("r", "a"), "b", 2))
+	runTest(t, "proc (r : {x : int, y : bool}) r.x", chapter7.TypedProc([]string{"r"},
+		[]chapter7.Type{chapter7.RecordOf(map[string]chapter7.Type{"x": chapter7.Int, "y": chapter7.Bool}, nil)}, nil, Field("r", "x")))
+
+	// proc (r) r.x works on any record with an x
+	expr, err := parser.Parse(`
+        let getx = proc (r) r.x
+        in ((getx {x = 1}), (getx {x = true, y = 2}))`)
+	require.NoError(t, err)
+	found, err := chapter7.NewPolyInferencer().Check(expr)
+	require.NoError(t, err)
+	assert.Equal(t, "(int, bool)", found.String())
+
+	expr, err = parser.Parse(`
+        let p = {x = 1, y = 2}
+        in let q = {p with y = 5}
+           in begin set p.x = 10; list(p, q) end`)
+	require.NoError(t, err)
+	val, err := SetListOpFuncs(SetOpFuncs(chapter4.NewImpRefLangEval())).Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	assert.Equal(t, "[{x = 10, y = 2}, {x = 1, y = 5}]", val.(Value).String())
+}
+
+func TestParseLetRecDouble(t *testing.T) {
+	expected := LetRec(ProcMap("double", Proc([]string{"x"},
+		If(IsZero("x"), 0, Op("-", Call("double", Op("-", "x", 1)), -2)))),
+		Call("double", 6))
+	runTest(t, `
+        letrec
+            double(x) = (if (isz x) then 0 else -((double -(x,1)), -2))
+        in (double 6)
+    `, expected)
+}
+
+func TestParseLetRecOddEven(t *testing.T) {
+	expected := LetRec(ProcMap(
+		"even", Proc([]string{"x"}, If(IsZero("x"), 1, Call("odd", Op("-", "x", 1)))),
+		"odd", Proc([]string{"x"}, If(IsZero("x"), 0, Call("even", Op("-", "x", 1))))),
+		Call("odd", 13))
+	runTest(t, `
+        letrec
+            even(x) = if (isz x) then 1 else (odd -(x,1))
+            odd(x) = if (isz x) then 0 else (even -(x,1))
+        in (odd 13)
+    `, expected)
+}
+
+func TestParseState(t *testing.T) {
+	runTest(t, "let r = newref(1) in begin setref(r, 2); deref(r) end",
+		Let(ExprDict("r", chapter4.NewRef(1)), chapter4.Begin(chapter4.SetRef("r", 2), chapter4.DeRef("r"))))
+	runTest(t, "begin set x = 3; x end", chapter4.Begin(chapter4.Assign("x", 3), "x"))
+	runTest(t, "ref x", chapter4.RefVar("x"))
+	runTest(t, "thunk lazy -(1, 2)", chapter4.ForceThunk(chapter4.Lazy(Op("-", 1, 2))))
+}
+
+func TestParseExceptions(t *testing.T) {
+	// From tests/chapter5/cases.py
+	expected := Proc([]string{"str"}, LetRec(ProcMap("inner", Proc([]string{"lst"},
+		If(Call("isnull", "lst"),
+			chapter5.Raise(Lit("ListIndexFailed")),
+			If(Call("stringequals", Call("car", "lst"), "str"),
+				0,
+				Op("-", Call("inner", Call("cdr", "lst")), -1))))),
+		Lit(5)))
+	runTest(t, `
+        proc(str)
+            letrec inner (lst) =
+                if (isnull lst)
+                then raise ("ListIndexFailed")
+                else
+                    ( if (stringequals (car lst) str)
+                    then 0
+                    else - ((inner (cdr lst)), -1) )
+            in 5
+    `, expected)
+
+	runTest(t, "try raise 1 catch (e) e", chapter5.Try(chapter5.Raise(1), "e", "e"))
+}
+
+func TestParseModules(t *testing.T) {
+	intType := chapter8.IntType{}
+	expected := chapter8.Program([]*chapter8.ModuleDefn{
+		chapter8.Module("m1",
+			[]*chapter8.VarDecl{
+				chapter8.Decl("a", intType),
+				chapter8.Decl("f", chapter8.ProcOf(chapter8.BoolType{}, intType, chapter8.ProcOf(intType))),
+			},
+			[]*chapter8.VarDefn{
+				chapter8.Defn("a", 33),
+				chapter8.Defn("f", Proc([]string{"x", "g"}, IsZero("x"))),
+			}),
+		chapter8.Module("m2", nil, nil),
+	}, Op("-", chapter8.QualifiedVar("m1", "a"), 1))
+	runTest(t, `
+        module m1
+            interface [a : int, f : (int * (-> int) -> bool)]
+            body [a = 33, f = proc (x, g) isz x]
+        module m2 interface [] body []
+        -(from m1 take a, 1)
+    `, expected)
+}
+
+func TestParseTypeAnnotations(t *testing.T) {
+	intType := chapter7.Int
+	runTest(t, "proc (x : int) -> int -(x, 11)",
+		chapter7.TypedProc([]string{"x"}, []chapter7.Type{intType}, intType, Op("-", "x", 11)))
+	runTest(t, "proc (x : int, f) -(x, 11)",
+		chapter7.TypedProc([]string{"x", "f"}, []chapter7.Type{intType, nil}, nil, Op("-", "x", 11)))
+	runTest(t, "proc (p : (int, list bool), l : list (list string), f : (-> (int))) p",
+		chapter7.TypedProc([]string{"p", "l", "f"}, []chapter7.Type{
+			chapter7.TupleOf(intType, chapter7.ListOf(chapter7.Bool)),
+			chapter7.ListOf(chapter7.ListOf(chapter7.String)),
+			chapter7.Func(nil, intType),
+		}, nil, "p"))
+	runTest(t, "letrec double (x : int) -> int = if isz(x) then 0 else -((double -(x, 1)), -2) in double",
+		LetRec(ProcMap("double", chapter7.TypedProc([]string{"x"}, []chapter7.Type{intType}, intType,
+			If(IsZero("x"), 0, Op("-", Call("double", Op("-", "x", 1)), -2)))), Var("double")))
+	runTest(t, "proc (r : refto int, l : lazy (refto bool)) r",
+		chapter7.TypedProc([]string{"r", "l"}, []chapter7.Type{
+			chapter7.RefTo(intType), chapter7.LazyOf(chapter7.RefTo(chapter7.Bool)),
+		}, nil, "r"))
+	// Annotations are checked by Eq
+	expr, err := parser.Parse("proc (x : bool) x")
+	require.NoError(t, err)
+	assert.False(t, ExprEq(expr, chapter7.TypedProc([]string{"x"}, []chapter7.Type{intType}, nil, "x")))
+}
+
+func TestCheckParsed(t *testing.T) {
+	expr, err := parser.Parse(`
+        letrec
+            even (x : int) -> bool = if isz(x) then true else (odd -(x, 1))
+            odd (x : int) -> bool = if isz(x) then false else (even -(x, 1))
+        in (odd 13)
+    `)
+	require.NoError(t, err)
+	found, err := chapter7.NewTypeChecker().Check(expr)
+	require.NoError(t, err)
+	assert.Equal(t, "bool", found.String())
+
+	expr, err = parser.Parse("proc (f : (int -> bool)) (f true)")
+	require.NoError(t, err)
+	_, err = chapter7.NewTypeChecker().Check(expr)
+	assert.EqualError(t, err, "expected type int, found bool")
+
+	expr, err = parser.Parse(`
+        let counter = newref(0)
+        in let incr = proc (r : refto int) begin setref(r, -(deref(r), -1)); deref(r) end
+           in begin (incr counter); (incr counter) end
+    `)
+	require.NoError(t, err)
+	found, err = chapter7.NewTypeChecker().Check(expr)
+	require.NoError(t, err)
+	assert.Equal(t, "int", found.String())
+}
+
+func TestInferParsed(t *testing.T) {
+	// The inferred case from tests/chapter7/cases.py with its if branches
+	// made to agree
+	expr, err := parser.Parse(`
+        letrec
+            even (x : int) -> ? = if isz(x) then true else (odd -(x, 1))
+            odd (x : ?) -> bool = if isz(x) then false else (even -(x, 1))
+        in (odd 13)
+    `)
+	require.NoError(t, err)
+	found, err := chapter7.NewInferencer().Check(expr)
+	require.NoError(t, err)
+	assert.Equal(t, "bool", found.String())
+
+	expr, err = parser.Parse("let id = proc (x) x in (id id)")
+	require.NoError(t, err)
+	found, err = chapter7.NewPolyInferencer().Check(expr)
+	require.NoError(t, err)
+	assert.Equal(t, "forall a. (a -> a)", found.String())
+
+	// '?' is the same as leaving out the annotation
+	runTest(t, "proc (x : ?) -> ? x", Proc([]string{"x"}, Var("x")))
+	expr, err = parser.Parse("letrec f (x : int) -> ? = x in f")
+	require.NoError(t, err)
+	_, err = chapter7.NewTypeChecker().Check(expr)
+	assert.ErrorIs(t, err, chapter7.MissingAnnotationError{Context: "result of proc f"})
+
+	// The exception type is inferred from the raise
+	expr, err = parser.Parse(`try -(deref(newref(1)), raise "underflow") catch (e) strlen(e)`)
+	require.NoError(t, err)
+	found, err = chapter7.NewInferencer().Check(expr)
+	require.NoError(t, err)
+	assert.Equal(t, "int", found.String())
+}
+
+func TestParseVariants(t *testing.T) {
+	shape := chapter7.Union(map[string]chapter7.Type{"Circle": chapter7.Int, "Square": chapter7.Int}, nil)
+	area := chapter7.TypedProc([]string{"s"}, []chapter7.Type{shape}, nil,
+		chapter7.Cases("s", []*chapter7.CaseArm{
+			chapter7.Arm("Circle", "r", Op("*", "r", "r")),
+			chapter7.Arm("Square", "x", Op("*", "x", 4)),
+		}, nil))
+	src := `proc (s : [Circle int | Square int])
+                cases s of
+                    Circle (r) => *(r, r)
+                    Square (x) => *(x, 4)
+                end`
+	runTest(t, src, area)
+	runTest(t, "cases tag A 1 of A (x) => x else => 0 end",
+		chapter7.Cases(chapter7.Tag("A", 1), []*chapter7.CaseArm{chapter7.Arm("A", "x", "x")}, 0))
+
+	expr, err := parser.Parse("(" + src + " tag Circle 3)")
+	require.NoError(t, err)
+	found, err := chapter7.NewTypeChecker().Check(expr)
+	require.NoError(t, err)
+	assert.Equal(t, "int", found.String())
+	val, err := SetOpFuncs(chapter7.NewVariantLangEval()).Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	AssertValue(t, "area", 9, val)
+}
+
+func TestParseDatatypes(t *testing.T) {
+	tree := chapter7.Data("tree")
+	types := []*chapter7.DatatypeDecl{
+		chapter7.Datatype("tree", nil, chapter7.Variant("Leaf", chapter7.Int), chapter7.Variant("Node", tree, tree)),
+		chapter7.Datatype("option", []string{"a"}, chapter7.Variant("None"), chapter7.Variant("Some", chapter7.Data("a"))),
+	}
+	sum := chapter7.TypedProc([]string{"t", "d"}, []chapter7.Type{tree, chapter7.Data("option", chapter7.Int)}, nil,
+		chapter7.Match("t",
+			chapter7.MatchCase(chapter7.PatCons("Leaf", chapter7.PatVar("n")), "n"),
+			chapter7.MatchCase(chapter7.PatCons("Node", chapter7.PatCons("Leaf", chapter7.Wildcard()), chapter7.PatVar("r")), "d"),
+			chapter7.MatchCase(chapter7.PatTuple(chapter7.PatCons("None"), chapter7.Wildcard()), 0)))
+	src := `define-datatype tree = Leaf (int) | Node (tree, tree)
+            define-datatype option (a) = None () | Some (a)
+            proc (t : tree, d : option int)
+                match t with
+                | Leaf (n) => n
+                | Node (Leaf (_), r) => d
+                | (None, _) => 0
+                end`
+	runTest(t, src, chapter7.DataProgram(types, sum))
+
+	// Constructors are called like procedures
+	runTest(t, "define-datatype tree = Leaf (int) | Node (tree, tree) Node(Leaf(1), (Leaf 2))",
+		chapter7.DataProgram(types[:1], Call("Node", Call("Leaf", 1), Call("Leaf", 2))))
+
+	expr, err := parser.Parse(`
+        define-datatype tree = Leaf (int) | Node (tree, tree)
+        letrec sum (t : tree) -> int = match t with
+                   Leaf (n) => n
+                 | Node (l, r) => +((sum l), (sum r))
+                 end
+        in (sum Node(Leaf(1), Node(Leaf(2), Leaf(3))))`)
+	require.NoError(t, err)
+	found, err := chapter7.NewTypeChecker().Check(expr)
+	require.NoError(t, err)
+	assert.Equal(t, "int", found.String())
+	val, err := SetOpFuncs(chapter7.NewDataLangEval()).Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	AssertValue(t, "sum", 6, val)
+
+	expr, err = parser.Parse(`
+        define-datatype option (a) = None () | Some (a)
+        proc (o) match o with Some (x) => x end`)
+	require.NoError(t, err)
+	_, err = chapter7.NewInferencer().Check(expr)
+	assert.ErrorIs(t, err, chapter7.NonExhaustiveMatchError{Missing: "None"})
+}
+
+func TestParseClasses(t *testing.T) {
+	expected := chapter9.Program([]*chapter9.ClassDecl{
+		chapter9.Class("c1", "object", []string{"x"},
+			chapter9.Method("initialize", []string{"v"}, chapter4.Assign("x", "v")),
+			chapter9.Method("get", nil, "x")),
+		chapter9.Class("c2", "c1", nil,
+			chapter9.Method("get", nil, Op("-", chapter9.Super("get"), chapter9.Send(chapter9.Self(), "bump", 1)))),
+	}, chapter9.Send(chapter9.New("c2", 5), "get"))
+	runTest(t, `
+        class c1 extends object
+            field x
+            method initialize (v) set x = v
+            method get () x
+        class c2 extends c1
+            method get () -(super get(), send self bump(1))
+        send new c2(5) get()
+    `, expected)
+}
+
+func TestRunClasses(t *testing.T) {
+	// The point/colorpoint example from EOPL chapter 9
+	expr, err := parser.Parse(`
+        class point extends object
+            field x
+            field y
+            method initialize (initx, inity)
+                begin set x = initx; set y = inity end
+            method move (dx, dy)
+                begin set x = +(x, dx); set y = +(y, dy) end
+            method get_location () list(x, y)
+        class colorpoint extends point
+            field color
+            method set_color (c) set color = c
+            method get_color () color
+        let p = new point(3, 4)
+            cp = new colorpoint(10, 20)
+        in begin
+            send p move(3, 4);
+            send cp set_color(87);
+            send cp move(10, 20);
+            list(send p get_location(), send cp get_location(), send cp get_color())
+        end
+    `)
+	require.NoError(t, err)
+	e := SetListOpFuncs(SetOpFuncs(chapter9.NewClassLangEval()))
+	val, err := e.Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	assert.Equal(t, "[[6, 8], [20, 40], 87]", val.(Value).String())
+}
+
+func TestComments(t *testing.T) {
+	runTest(t, "// a comment\n-(1, // another\n 2)", Op("-", 1, 2))
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	cases := []struct {
+		input string
+		pos   parser.Pos
+	}{
+		{"", parser.Pos{1, 1}},
+		{"let x = 1", parser.Pos{1, 10}},
+		{"let in 3", parser.Pos{1, 5}},
+		{"let x = 1 x = 2 in x", parser.Pos{1, 11}},
+		{"-(1 2)", parser.Pos{1, 5}},
+		{"if 1 then 2", parser.Pos{1, 12}},
+		{"proc (x 3", parser.Pos{1, 9}},
+		{"(f x", parser.Pos{1, 5}},
+		{"1 2", parser.Pos{1, 3}},
+		{"\n  #", parser.Pos{2, 3}},
+		{`"unterminated`, parser.Pos{1, 1}},
+		{"let if = 1 in if", parser.Pos{1, 5}},
+		{"newref(1, 2)", parser.Pos{1, 13}},
+		{"begin 1; 2", parser.Pos{1, 11}},
+		{"module m interface [x : int] body [x = 1]", parser.Pos{1, 42}},
+		{"module m interface [x : num] body [] 1", parser.Pos{1, 25}},
+		{"module m interface [f : (int int)] body [] 1", parser.Pos{1, 30}},
+		{"module m interface [f : (string -> int)] body [] 1", parser.Pos{1, 25}},
+		{"from m x", parser.Pos{1, 8}},
+		{"class c object 1", parser.Pos{1, 9}},
+		{"class c extends object method m 1", parser.Pos{1, 33}},
+		{"send o m", parser.Pos{1, 9}},
+		{"proc (x : ) x", parser.Pos{1, 11}},
+		{"proc (x) -> 3", parser.Pos{1, 13}},
+		{"proc (x : (int, bool -> int)) x", parser.Pos{1, 22}},
+		{"cases s Circle (r) => r end", parser.Pos{1, 9}},
+		{"cases s of Circle r => r end", parser.Pos{1, 19}},
+		{"cases s of else => 0 A (x) => x end", parser.Pos{1, 22}},
+		{"proc (s : [A int | A bool]) s", parser.Pos{1, 20}},
+		{"define-datatype t = A (int) | A (bool) 1", parser.Pos{1, 31}},
+		{"define-datatype t = A (num) 1", parser.Pos{1, 24}},
+		{"define-datatype t (a) = A (a) proc (x : a) x", parser.Pos{1, 41}},
+		{"define-datatype t = A (int) proc (x : t int) x", parser.Pos{1, 41}},
+		{"match x with A (y, y) => y end", parser.Pos{1, 14}},
+		{"match x with y => y", parser.Pos{1, 20}},
+		{"match x y => y end", parser.Pos{1, 9}},
+		{"unpack x x = p in x", parser.Pos{1, 10}},
+		{"unpack = p in 1", parser.Pos{1, 8}},
+		{"unpack x = p x", parser.Pos{1, 14}},
+		{"(1, 2", parser.Pos{1, 6}},
+		{"t.if", parser.Pos{1, 3}},
+		{"t. 1", parser.Pos{1, 4}},
+		{"t.-1", parser.Pos{1, 3}},
+		{"(f)(1)", parser.Pos{1, 5}},
+		{"1/0", parser.Pos{1, 1}},
+		{"1e999", parser.Pos{1, 1}},
+		{"{x = 1, x = 2}", parser.Pos{1, 9}},
+		{"{x = 1 y = 2}", parser.Pos{1, 8}},
+		{"{1 = 2}", parser.Pos{1, 2}},
+		{"{x}", parser.Pos{1, 3}},
+		{"{r with}", parser.Pos{1, 8}},
+		{"set t.0 = 1", parser.Pos{1, 6}},
+		{"proc (r : {x : int, x : bool}) r", parser.Pos{1, 21}},
+	}
+	for _, tc := range cases {
+		_, err := parser.Parse(tc.input)
+		var syntax parser.SyntaxError
+		if assert.ErrorAs(t, err, &syntax, "parsing %q", tc.input) {
+			assert.Equal(t, tc.pos, syntax.Pos, "parsing %q: %s", tc.input, err)
+		}
+	}
+}
+
+func bigInt(s string) *big.Int {
+	out, _ := new(big.Int).SetString(s, 10)
+	return out
+}
@@ -0,0 +1,157 @@
+// Package prelude is the standard library of built-in operators for EPL.
+// Install registers all of them on any evaluator:
+//
+//	Arithmetic (on the numeric tower, see chapter3.Number)
+//	  +(n ...)       sum, 0 if no arguments
+//	  *(n ...)       product, 1 if no arguments
+//	  -(a, b)        difference
+//	  /(a, b)        true division - integers divide to a float
+//	  mod(a, b)      remainder with the sign of b
+//	  min(n1, ...)   smallest of one or more numbers
+//	  max(n1, ...)   largest of one or more numbers
+//
+//	Comparisons
+//	  <, >, <=, >=   numeric ordering of two numbers
+//	  =(a, b)        numeric equality, so =(1, 1.0) is true
+//	  equal?(a, b)   structural equality of any two values
+//	  zero?(n)       true if n is zero (also available as isz)
+//
+//	Logic
+//	  not(b)         negation of a boolean
+//
+//	Strings (see chapter3.SetStringOpFuncs)
+//	  concat, strlen, substring, stringequals, stringcompare, tostring, toint
+//
+//	Lists (see chapter3.SetListOpFuncs)
+//	  emptylist, cons, car, cdr, null?, isnull, list
+//
+// Operators report failures with the chapter3 RuntimeError types - an
+// ArityError for the wrong number of arguments, a TypeMismatchError for
+// arguments of the wrong type and a DivisionByZeroError from / and mod.
+package prelude
+
+import (
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+)
+
+type (
+	Evaluator = chapter3.Evaluator
+	Expr      = chapter3.Expr
+	Number    = chapter3.Number
+	OpFunc    = chapter3.OpFunc
+)
+
+// Install registers the whole prelude on an evaluator and returns it.
+func Install(e Evaluator) Evaluator {
+	Arithmetic(e)
+	Comparisons(e)
+	Logic(e)
+	chapter3.SetStringOpFuncs(e)
+	chapter3.SetListOpFuncs(e)
+	return e
+}
+
+// Arithmetic registers +, -, *, /, mod, min and max.
+func Arithmetic(e Evaluator) Evaluator {
+	chapter3.SetOpFuncs(e)
+	e.SetOpFunc("mod", chapter3.BinaryOp(e, "mod", chapter3.Mod))
+	e.SetOpFunc("min", extremum(e, "min", -1))
+	e.SetOpFunc("max", extremum(e, "max", 1))
+	return e
+}
+
+// Comparisons registers <, >, <=, >=, =, equal?, zero? and isz.
+func Comparisons(e Evaluator) Evaluator {
+	e.SetOpFunc("<", compare(e, "<", func(c int) bool { return c < 0 }))
+	e.SetOpFunc(">", compare(e, ">", func(c int) bool { return c > 0 }))
+	e.SetOpFunc("<=", compare(e, "<=", func(c int) bool { return c <= 0 }))
+	e.SetOpFunc(">=", compare(e, ">=", func(c int) bool { return c >= 0 }))
+	e.SetOpFunc("=", compare(e, "=", func(c int) bool { return c == 0 }))
+	e.SetOpFunc("equal?", func(env *epl.Env[any], args []Expr) (any, error) {
+		if err := chapter3.CheckArity("equal?", args, 2); err != nil {
+			return nil, err
+		}
+		var vals [2]chapter3.Value
+		for i, arg := range args {
+			val, err := e.Eval(arg, env)
+			if err != nil {
+				return nil, err
+			}
+			if vals[i], err = chapter3.AsValue("'equal?' operator", val); err != nil {
+				return nil, err
+			}
+		}
+		return chapter3.BoolVal(vals[0].Eq(vals[1])), nil
+	})
+	e.SetOpFunc("zero?", isZero(e, "zero?"))
+	e.SetOpFunc("isz", isZero(e, "isz"))
+	return e
+}
+
+// Logic registers not.
+func Logic(e Evaluator) Evaluator {
+	e.SetOpFunc("not", func(env *epl.Env[any], args []Expr) (any, error) {
+		if err := chapter3.CheckArity("not", args, 1); err != nil {
+			return nil, err
+		}
+		b, err := chapter3.EvalBool(e, "not", args[0], env)
+		if err != nil {
+			return nil, err
+		}
+		return chapter3.BoolVal(!b), nil
+	})
+	return e
+}
+
+// compare returns an OpFunc comparing two numbers with chapter3.Compare.
+func compare(e Evaluator, op string, test func(int) bool) OpFunc {
+	return func(env *epl.Env[any], args []Expr) (any, error) {
+		if err := chapter3.CheckArity(op, args, 2); err != nil {
+			return nil, err
+		}
+		a, err := chapter3.EvalNumber(e, op, args[0], env)
+		if err != nil {
+			return nil, err
+		}
+		b, err := chapter3.EvalNumber(e, op, args[1], env)
+		if err != nil {
+			return nil, err
+		}
+		return chapter3.BoolVal(test(chapter3.Compare(a, b))), nil
+	}
+}
+
+// extremum returns an OpFunc picking the number for which Compare against
+// every other returns sign (or 0).
+func extremum(e Evaluator, op string, sign int) OpFunc {
+	return func(env *epl.Env[any], args []Expr) (any, error) {
+		if len(args) == 0 {
+			return nil, chapter3.ArityError{Context: "'" + op + "' operator", Expected: 1, Found: 0}
+		}
+		var out Number
+		for _, arg := range args {
+			n, err := chapter3.EvalNumber(e, op, arg, env)
+			if err != nil {
+				return nil, err
+			}
+			if out == nil || chapter3.Compare(n, out) == sign {
+				out = n
+			}
+		}
+		return out, nil
+	}
+}
+
+func isZero(e Evaluator, op string) OpFunc {
+	return func(env *epl.Env[any], args []Expr) (any, error) {
+		if err := chapter3.CheckArity(op, args, 1); err != nil {
+			return nil, err
+		}
+		n, err := chapter3.EvalNumber(e, op, args[0], env)
+		if err != nil {
+			return nil, err
+		}
+		return chapter3.BoolVal(n.IsZero()), nil
+	}
+}
@@ -0,0 +1,113 @@
+package prelude_test
+
+import (
+	"math/big"
+	"testing"
+
+	epl "github.com/panyam/eplgo"
+	. "github.com/panyam/eplgo/chapter3"
+	"github.com/panyam/eplgo/chapter4"
+	"github.com/panyam/eplgo/chapter5"
+	"github.com/panyam/eplgo/prelude"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func evalError(t *testing.T, e Evaluator, expr Expr) error {
+	_, err := e.Eval(expr, epl.NewEnv[any](nil))
+	require.Error(t, err, "Expected an error evaluating %s", expr.Repr())
+	return err
+}
+
+func TestArithmetic(t *testing.T) {
+	cases := []TestCase{
+		{Name: "add", Expected: 6, Expr: Op("+", 1, 2, 3)},
+		{Name: "add_empty", Expected: 0, Expr: Op("+")},
+		{Name: "mul_empty", Expected: 1, Expr: Op("*")},
+		{Name: "sub", Expected: -1, Expr: Op("-", 1, 2)},
+		{Name: "div", Expected: FloatVal(2.5), Expr: Op("/", 5, 2)},
+		{Name: "div_rat", Expected: RatVal{Rat: big.NewRat(1, 3)}, Expr: Op("/", big.NewRat(2, 3), 2)},
+		{Name: "mod", Expected: 1, Expr: Op("mod", 7, 3)},
+		{Name: "mod_negative", Expected: 2, Expr: Op("mod", -7, 3)},
+		{Name: "mod_negative_divisor", Expected: -2, Expr: Op("mod", 7, -3)},
+		{Name: "mod_float", Expected: FloatVal(1.5), Expr: Op("mod", 7.5, 2)},
+		{Name: "min", Expected: -3, Expr: Op("min", 4, -3, 7)},
+		{Name: "max", Expected: 7, Expr: Op("max", 4, -3, 7)},
+		{Name: "max_mixed", Expected: FloatVal(7.5), Expr: Op("max", 4, 7.5, 7)},
+		{Name: "min_single", Expected: 4, Expr: Op("min", 4)},
+	}
+	for _, tc := range cases {
+		RunTest(t, prelude.Install(NewLetRecLangEval()), &tc, nil)
+	}
+}
+
+func TestComparisons(t *testing.T) {
+	cases := []TestCase{
+		{Name: "lt", Expected: true, Expr: Op("<", 1, 2)},
+		{Name: "lt_false", Expected: false, Expr: Op("<", 2, 2)},
+		{Name: "gt", Expected: true, Expr: Op(">", 3, 2)},
+		{Name: "le", Expected: true, Expr: Op("<=", 2, 2)},
+		{Name: "ge", Expected: false, Expr: Op(">=", 1, 2)},
+		{Name: "eq", Expected: true, Expr: Op("=", 2, 2)},
+		{Name: "eq_mixed", Expected: true, Expr: Op("=", 1, 1.0)},
+		{Name: "equal_mixed", Expected: false, Expr: Op("equal?", 1, 1.0)},
+		{Name: "equal_strings", Expected: true, Expr: Op("equal?", Lit("ab"), Op("concat", Lit("a"), Lit("b")))},
+		{Name: "equal_lists", Expected: true, Expr: Op("equal?", List(1, 2), Op("cons", 1, List(2)))},
+		{Name: "equal_lists_false", Expected: false, Expr: Op("equal?", List(1, 2), List(1))},
+		{Name: "equal_tuples", Expected: true, Expr: Op("equal?", Tuple(Lit(1), List(2)), Tuple(Lit(1), Op("cons", 2, List())))},
+		{Name: "equal_tuples_sizes", Expected: false, Expr: Op("equal?", Tuple(Lit(1), Lit(2)), Tuple(Lit(1)))},
+		{Name: "zero", Expected: true, Expr: Op("zero?", Op("-", 3, 3))},
+		{Name: "isz", Expected: false, Expr: Op("isz", 1)},
+		{Name: "not", Expected: false, Expr: Op("not", Op("<", 1, 2))},
+	}
+	for _, tc := range cases {
+		RunTest(t, prelude.Install(NewLetRecLangEval()), &tc, nil)
+	}
+}
+
+func TestPreludeInLetRec(t *testing.T) {
+	// letrec gcd(a, b) = if zero?(b) then a else (gcd b mod(a, b)) in (gcd 48 18)
+	expr := LetRec(ProcMap("gcd", Proc([]string{"a", "b"},
+		If(Op("zero?", "b"), "a", Call("gcd", "b", Op("mod", "a", "b"))))),
+		Call("gcd", 48, 18))
+	tc := TestCase{Name: "gcd", Expected: 6, Expr: expr}
+	RunTest(t, prelude.Install(NewLetRecLangEval()), &tc, nil)
+}
+
+func TestPreludeOnLaterEvaluators(t *testing.T) {
+	// Forced thunks are ordinary operands
+	expr := Let(ExprDict("x", chapter4.Lazy(Op("*", 6, 7))), Op("max", chapter4.ForceThunk("x"), 10))
+	tc := TestCase{Name: "lazy", Expected: 42, Expr: expr}
+	RunTest(t, prelude.Install(chapter4.NewLazyLangEval()), &tc, nil)
+
+	// Prelude errors can be caught like any other runtime error
+	try := chapter5.NewTryLangEval()
+	try.CatchRuntimeErrors = true
+	e := prelude.Install(try)
+	tc = TestCase{Name: "catch", Expected: -1, Expr: chapter5.Try(Op("mod", 1, 0), "err", -1)}
+	RunTest(t, e, &tc, nil)
+}
+
+func TestPreludeErrors(t *testing.T) {
+	e := prelude.Install(NewLetRecLangEval())
+
+	var arity ArityError
+	require.ErrorAs(t, evalError(t, e, Op("min")), &arity)
+	assert.Equal(t, 1, arity.Expected)
+	require.ErrorAs(t, evalError(t, e, Op("<", 1)), &arity)
+	assert.Equal(t, 2, arity.Expected)
+	assert.Equal(t, 1, arity.Found)
+
+	var mismatch TypeMismatchError
+	require.ErrorAs(t, evalError(t, e, Op("<", 1, Lit("a"))), &mismatch)
+	assert.Equal(t, "a number", mismatch.Expected)
+	require.ErrorAs(t, evalError(t, e, Op("not", 1)), &mismatch)
+	assert.Equal(t, "a boolean", mismatch.Expected)
+
+	var div DivisionByZeroError
+	assert.ErrorAs(t, evalError(t, e, Op("mod", 5, 0)), &div)
+	assert.ErrorAs(t, evalError(t, e, Op("/", 5, 0)), &div)
+
+	var rt RuntimeError
+	assert.ErrorAs(t, evalError(t, e, Op("max", 1, Lit(true))), &rt)
+}
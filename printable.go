@@ -2,6 +2,7 @@ package epl
 
 import (
 	"fmt"
+	"io"
 	"iter"
 	"log"
 )
@@ -35,6 +36,21 @@ func (p *Printable) print(depth int) {
 	}
 }
 
+// Fprint writes the printable tree to w, one leaf per line.
+func (p *Printable) Fprint(w io.Writer) {
+	p.fprint(w, 0)
+}
+
+func (p *Printable) fprint(w io.Writer, depth int) {
+	if p.Leaf != "" {
+		fmt.Fprintf(w, "%s%s\n", Indent(depth+p.IndentLevel), p.Leaf)
+	} else {
+		for printable := range p.Iter {
+			printable.fprint(w, depth+p.IndentLevel)
+		}
+	}
+}
+
 func PrintableIter(i iter.Seq[*Printable]) (out *Printable) {
 	out = &Printable{Iter: i}
 	return out
@@ -0,0 +1,215 @@
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+	"strings"
+)
+
+// WriteProfile writes the profile as a gzipped pprof protobuf (see
+// https://github.com/google/pprof/blob/main/proto/profile.proto) so it can be
+// explored with `go tool pprof`.  Each procedure is a function, samples are
+// keyed by the EPL call stack and carry the number of steps and the wall time
+// spent, and are labelled with the kind of node ("node") that was evaluated.
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(p.encodeProfile()); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Field numbers from profile.proto
+const (
+	profileSampleType    = 1
+	profileSample        = 2
+	profileLocation      = 4
+	profileFunction      = 5
+	profileStringTable   = 6
+	profileTimeNanos     = 9
+	profileDurationNanos = 10
+	profilePeriodType    = 11
+	profilePeriod        = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+	sampleLabel      = 3
+
+	labelKey = 1
+	labelStr = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+)
+
+func (p *Profiler) encodeProfile() []byte {
+	strs := &stringTable{index: map[string]int{}}
+	strs.add("")
+
+	var out protoBuffer
+	valueType := func(field int, typ, unit string) {
+		var vt protoBuffer
+		vt.int64(valueTypeType, int64(strs.add(typ)))
+		vt.int64(valueTypeUnit, int64(strs.add(unit)))
+		out.message(field, &vt)
+	}
+	valueType(profileSampleType, "steps", "count")
+	valueType(profileSampleType, "wall", "nanoseconds")
+
+	// One function (and location) per procedure
+	funcIDs := map[string]uint64{}
+	var funcNames []string
+	funcID := func(name string) uint64 {
+		if id, ok := funcIDs[name]; ok {
+			return id
+		}
+		id := uint64(len(funcIDs) + 1)
+		funcIDs[name] = id
+		funcNames = append(funcNames, name)
+		return id
+	}
+
+	// Samples in a deterministic order
+	keys := make([]sampleKey, 0, len(p.samples))
+	for key := range p.samples {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].stack != keys[j].stack {
+			return keys[i].stack < keys[j].stack
+		}
+		return keys[i].kind < keys[j].kind
+	})
+	for _, key := range keys {
+		s := p.samples[key]
+		frames := strings.Split(key.stack, ";")
+		var locs []uint64
+		for i := len(frames) - 1; i >= 0; i-- { // Leaf first
+			locs = append(locs, funcID(frames[i]))
+		}
+		var sm protoBuffer
+		sm.packedUint64(sampleLocationID, locs)
+		sm.packedInt64(sampleValue, []int64{int64(s.steps), s.nanos})
+		var label protoBuffer
+		label.int64(labelKey, int64(strs.add("node")))
+		label.int64(labelStr, int64(strs.add(key.kind)))
+		sm.message(sampleLabel, &label)
+		out.message(profileSample, &sm)
+	}
+
+	for i, name := range funcNames {
+		id := uint64(i + 1)
+		var line protoBuffer
+		line.uint64(lineFunctionID, id)
+		var loc protoBuffer
+		loc.uint64(locationID, id)
+		loc.message(locationLine, &line)
+		out.message(profileLocation, &loc)
+
+		var fn protoBuffer
+		fn.uint64(functionID, id)
+		fn.int64(functionName, int64(strs.add(name)))
+		fn.int64(functionSystemName, int64(strs.add(name)))
+		out.message(profileFunction, &fn)
+	}
+
+	// Period type must be interned before the string table is written
+	var period protoBuffer
+	period.int64(valueTypeType, int64(strs.add("steps")))
+	period.int64(valueTypeUnit, int64(strs.add("count")))
+
+	for _, s := range strs.strings {
+		out.string(profileStringTable, s)
+	}
+	out.int64(profileTimeNanos, p.start.UnixNano())
+	out.int64(profileDurationNanos, int64(p.Duration()))
+	out.message(profilePeriodType, &period)
+	out.int64(profilePeriod, 1)
+	return out.data
+}
+
+type stringTable struct {
+	strings []string
+	index   map[string]int
+}
+
+func (t *stringTable) add(s string) int {
+	if i, ok := t.index[s]; ok {
+		return i
+	}
+	t.index[s] = len(t.strings)
+	t.strings = append(t.strings, s)
+	return len(t.strings) - 1
+}
+
+// protoBuffer is a minimal protobuf encoder covering the wire types the
+// profile format needs.
+type protoBuffer struct {
+	data []byte
+}
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+func (b *protoBuffer) key(field int, wire int) {
+	b.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (b *protoBuffer) uint64(field int, x uint64) {
+	b.key(field, wireVarint)
+	b.varint(x)
+}
+
+func (b *protoBuffer) int64(field int, x int64) {
+	b.uint64(field, uint64(x))
+}
+
+func (b *protoBuffer) bytes(field int, data []byte) {
+	b.key(field, wireBytes)
+	b.varint(uint64(len(data)))
+	b.data = append(b.data, data...)
+}
+
+func (b *protoBuffer) string(field int, s string) {
+	b.bytes(field, []byte(s))
+}
+
+func (b *protoBuffer) message(field int, m *protoBuffer) {
+	b.bytes(field, m.data)
+}
+
+func (b *protoBuffer) packedUint64(field int, xs []uint64) {
+	var packed protoBuffer
+	for _, x := range xs {
+		packed.varint(x)
+	}
+	b.bytes(field, packed.data)
+}
+
+func (b *protoBuffer) packedInt64(field int, xs []int64) {
+	var packed protoBuffer
+	for _, x := range xs {
+		packed.varint(uint64(x))
+	}
+	b.bytes(field, packed.data)
+}
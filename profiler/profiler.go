@@ -0,0 +1,255 @@
+// Package profiler collects evaluation step counts and wall time for EPL
+// programs, broken down by procedure and by AST node kind.  Profiles can be
+// printed as a table or written in the pprof format for use with
+// `go tool pprof`.
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+)
+
+// Names for code outside any call and for procedures without names.  These
+// avoid angle brackets as pprof strips them from function names.
+const (
+	TopLevel  = "[toplevel]"
+	Anonymous = "[anonymous]"
+)
+
+// Profilable is the part of an evaluator the profiler needs.  All evaluators
+// embedding chapter3.BaseEval satisfy it.
+type Profilable interface {
+	AddObserver(chapter3.Observer)
+	RemoveObserver(chapter3.Observer)
+	StackTrace() *chapter3.StackTrace
+}
+
+// Stats are the totals for a procedure or node kind.
+type Stats struct {
+	Name      string
+	Calls     int // Number of applications (procedures only)
+	SelfSteps int // Expressions evaluated directly in this procedure / of this kind
+	Steps     int // Including everything called from the procedure
+	SelfTime  time.Duration
+	Time      time.Duration
+}
+
+type sampleKey struct {
+	stack string // Procedure names joined with ";", outermost first
+	kind  string // Node kind
+}
+
+type sample struct {
+	steps int
+	nanos int64
+}
+
+type exprEntry struct {
+	start     time.Time
+	childTime time.Duration
+	stack     string
+	kind      string
+}
+
+// Profiler is a chapter3.Observer that accumulates a profile.
+type Profiler struct {
+	chapter3.NopObserver
+
+	// Clock returns the current time.  Defaults to time.Now.
+	Clock func() time.Time
+
+	eval    Profilable
+	start   time.Time
+	stop    time.Time
+	calls   map[string]int
+	samples map[sampleKey]*sample
+	stacks  []string // Keys of the procedure stack, outermost first
+	exprs   []exprEntry
+}
+
+// New creates a profiler and starts profiling the given evaluator.
+func New(eval Profilable) *Profiler {
+	p := &Profiler{
+		Clock:   time.Now,
+		eval:    eval,
+		calls:   map[string]int{},
+		samples: map[sampleKey]*sample{},
+		stacks:  []string{TopLevel},
+	}
+	eval.AddObserver(p)
+	return p
+}
+
+// Stop detaches the profiler from its evaluator.
+func (p *Profiler) Stop() {
+	p.stop = p.Clock()
+	p.eval.RemoveObserver(p)
+}
+
+// Duration returns the time from the first expression evaluated until Stop,
+// or until now if the profiler has not been stopped.
+func (p *Profiler) Duration() time.Duration {
+	if p.start.IsZero() {
+		return 0
+	}
+	if p.stop.IsZero() {
+		return p.Clock().Sub(p.start)
+	}
+	return p.stop.Sub(p.start)
+}
+
+// ProcName returns the name a procedure is profiled under - its ProcExpr.Name
+// if it has one, otherwise the name of the variable it was called through, or
+// Anonymous.
+func (p *Profiler) ProcName(proc *chapter3.BoundProc) string {
+	if proc.ProcExpr.Name != "" {
+		return proc.ProcExpr.Name
+	}
+	if frames := p.eval.StackTrace().Frames; len(frames) > 0 && frames[0].CallSite != nil {
+		if v, ok := frames[0].CallSite.Operator.(*chapter3.VarExpr); ok {
+			return v.Name
+		}
+	}
+	return Anonymous
+}
+
+// NodeKind returns the name a node is profiled under, eg "CallExpr".
+func NodeKind(expr chapter3.Expr) string {
+	t := reflect.TypeOf(expr)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+func (p *Profiler) EnterExpr(expr chapter3.Expr, env *epl.Env[any]) {
+	now := p.Clock()
+	if p.start.IsZero() {
+		p.start = now
+	}
+	p.exprs = append(p.exprs, exprEntry{
+		start: now,
+		stack: p.stacks[len(p.stacks)-1],
+		kind:  NodeKind(expr),
+	})
+}
+
+func (p *Profiler) ExitExpr(expr chapter3.Expr, env *epl.Env[any], val any, err error) {
+	if len(p.exprs) == 0 {
+		return
+	}
+	entry := p.exprs[len(p.exprs)-1]
+	p.exprs = p.exprs[:len(p.exprs)-1]
+	elapsed := p.Clock().Sub(entry.start)
+	if len(p.exprs) > 0 {
+		p.exprs[len(p.exprs)-1].childTime += elapsed
+	}
+
+	key := sampleKey{entry.stack, entry.kind}
+	s := p.samples[key]
+	if s == nil {
+		s = &sample{}
+		p.samples[key] = s
+	}
+	s.steps++
+	s.nanos += int64(elapsed - entry.childTime)
+}
+
+func (p *Profiler) EnterProc(proc *chapter3.BoundProc, args []any) {
+	name := p.ProcName(proc)
+	p.calls[name]++
+	p.stacks = append(p.stacks, p.stacks[len(p.stacks)-1]+";"+name)
+}
+
+func (p *Profiler) ExitProc(proc *chapter3.BoundProc, val any, err error) {
+	if len(p.stacks) > 1 {
+		p.stacks = p.stacks[:len(p.stacks)-1]
+	}
+}
+
+// TotalSteps returns the number of expressions evaluated so far.
+func (p *Profiler) TotalSteps() (out int) {
+	for _, s := range p.samples {
+		out += s.steps
+	}
+	return
+}
+
+// Procs returns per procedure stats sorted by decreasing inclusive steps.
+func (p *Profiler) Procs() []*Stats {
+	stats := map[string]*Stats{}
+	get := func(name string) *Stats {
+		if stats[name] == nil {
+			stats[name] = &Stats{Name: name, Calls: p.calls[name]}
+		}
+		return stats[name]
+	}
+	for key, s := range p.samples {
+		frames := strings.Split(key.stack, ";")
+		leaf := get(frames[len(frames)-1])
+		leaf.SelfSteps += s.steps
+		leaf.SelfTime += time.Duration(s.nanos)
+		// Only count each procedure once per stack so recursion does not
+		// inflate inclusive totals
+		seen := map[string]bool{}
+		for _, name := range frames {
+			if !seen[name] {
+				seen[name] = true
+				st := get(name)
+				st.Steps += s.steps
+				st.Time += time.Duration(s.nanos)
+			}
+		}
+	}
+	return sortedStats(stats)
+}
+
+// Nodes returns per node kind stats sorted by decreasing steps.
+func (p *Profiler) Nodes() []*Stats {
+	stats := map[string]*Stats{}
+	for key, s := range p.samples {
+		st := stats[key.kind]
+		if st == nil {
+			st = &Stats{Name: key.kind}
+			stats[key.kind] = st
+		}
+		st.SelfSteps += s.steps
+		st.Steps += s.steps
+		st.SelfTime += time.Duration(s.nanos)
+		st.Time += time.Duration(s.nanos)
+	}
+	return sortedStats(stats)
+}
+
+func sortedStats(stats map[string]*Stats) (out []*Stats) {
+	for _, s := range stats {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Steps != out[j].Steps {
+			return out[i].Steps > out[j].Steps
+		}
+		return out[i].Name < out[j].Name
+	})
+	return
+}
+
+// WriteReport writes a human readable summary of the profile.
+func (p *Profiler) WriteReport(w io.Writer) {
+	fmt.Fprintf(w, "Total steps: %d\n\n", p.TotalSteps())
+	fmt.Fprintf(w, "%-24s %8s %10s %10s %12s %12s\n", "Procedure", "Calls", "Self", "Total", "SelfTime", "TotalTime")
+	for _, s := range p.Procs() {
+		fmt.Fprintf(w, "%-24s %8d %10d %10d %12s %12s\n", s.Name, s.Calls, s.SelfSteps, s.Steps, s.SelfTime, s.Time)
+	}
+	fmt.Fprintf(w, "\n%-24s %10s %12s\n", "Node", "Steps", "Time")
+	for _, s := range p.Nodes() {
+		fmt.Fprintf(w, "%-24s %10d %12s\n", s.Name, s.Steps, s.Time)
+	}
+}
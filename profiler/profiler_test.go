@@ -0,0 +1,144 @@
+package profiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+	"time"
+
+	epl "github.com/panyam/eplgo"
+	"github.com/panyam/eplgo/chapter3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	Call   = chapter3.Call
+	If     = chapter3.If
+	IsZero = chapter3.IsZero
+	Let    = chapter3.Let
+	LetRec = chapter3.LetRec
+	Op     = chapter3.Op
+	Proc   = chapter3.Proc
+)
+
+// A clock that advances by a millisecond every time it is read
+func fakeClock() func() time.Time {
+	now := time.Unix(0, 0)
+	return func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+}
+
+// let twice = proc(f, x) (f (f x)) in
+// letrec double(x) = if isz(x) then 0 else -((double -(x,1)), -2)
+// in (twice double 1)
+func profileDouble(t *testing.T) *Profiler {
+	e := chapter3.NewLetRecLangEval()
+	chapter3.SetOpFuncs(e)
+	p := New(e)
+	p.Clock = fakeClock()
+
+	expr := Let(chapter3.ExprDict("twice", Proc([]string{"f", "x"}, Call("f", Call("f", "x")))),
+		LetRec(chapter3.ProcMap("double", Proc([]string{"x"},
+			If(IsZero("x"), 0, Op("-", Call("double", Op("-", "x", 1)), -2)))),
+			Call("twice", "double", 1)))
+	val, err := e.Eval(expr, epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	assert.Equal(t, chapter3.IntVal(4), val)
+	p.Stop()
+	return p
+}
+
+func findStats(stats []*Stats, name string) *Stats {
+	for _, s := range stats {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func TestProcStats(t *testing.T) {
+	p := profileDouble(t)
+	procs := p.Procs()
+
+	top := findStats(procs, TopLevel)
+	require.NotNil(t, top)
+	assert.Equal(t, p.TotalSteps(), top.Steps)
+	assert.Equal(t, procs[0], top)
+
+	// (double 1) -> (double 0), then (double 2) -> (double 1) -> (double 0)
+	double := findStats(procs, "double")
+	require.NotNil(t, double)
+	assert.Equal(t, 5, double.Calls)
+	// Each call evaluates the If, the IsZero and its x, and then either the 0
+	// (4 steps) or the 7 nodes in the else branch outside the nested call
+	// (10 steps)
+	assert.Equal(t, 2*4+3*10, double.SelfSteps)
+	assert.Equal(t, double.SelfSteps, double.Steps)
+
+	// Anonymous procedures are named after the variable they were called through
+	twice := findStats(procs, "twice")
+	require.NotNil(t, twice)
+	assert.Equal(t, 1, twice.Calls)
+	assert.Equal(t, twice.SelfSteps+double.Steps, twice.Steps)
+
+	total := time.Duration(0)
+	for _, s := range procs {
+		total += s.SelfTime
+	}
+	assert.Equal(t, top.Time, total)
+}
+
+func TestNodeStats(t *testing.T) {
+	p := profileDouble(t)
+	nodes := p.Nodes()
+	call := findStats(nodes, "CallExpr")
+	require.NotNil(t, call)
+	// (twice ..), 2 x (f ..) and 3 recursive (double ..)
+	assert.Equal(t, 6, call.Steps)
+	assert.Equal(t, 5, findStats(nodes, "IfExpr").Steps)
+	assert.Equal(t, 1, findStats(nodes, "LetRecExpr").Steps)
+
+	var out bytes.Buffer
+	p.WriteReport(&out)
+	assert.Contains(t, out.String(), "Total steps: ")
+	assert.Contains(t, out.String(), "double")
+	assert.Contains(t, out.String(), "CallExpr")
+}
+
+func TestDuration(t *testing.T) {
+	e := chapter3.NewLetLangEval()
+	chapter3.SetOpFuncs(e)
+	p := New(e)
+	p.Clock = fakeClock()
+	assert.Zero(t, p.Duration())
+
+	// -(1, 2) reads the clock twice for each of its three expressions, from
+	// 1ms to 6ms, and Stop reads it at 7ms
+	_, err := e.Eval(Op("-", 1, 2), epl.NewEnv[any](nil))
+	require.NoError(t, err)
+	p.Stop()
+	assert.Equal(t, 6*time.Millisecond, p.Duration())
+
+	// Reading the clock later does not extend a stopped profile
+	p.Clock()
+	assert.Equal(t, 6*time.Millisecond, p.Duration())
+}
+
+func TestWriteProfile(t *testing.T) {
+	p := profileDouble(t)
+	var out bytes.Buffer
+	require.NoError(t, p.WriteProfile(&out))
+
+	gz, err := gzip.NewReader(&out)
+	require.NoError(t, err)
+	data, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	for _, s := range []string{"steps", "count", "wall", "nanoseconds", "node", "double", "twice", TopLevel, "IfExpr"} {
+		assert.Contains(t, string(data), s)
+	}
+}
@@ -24,11 +24,12 @@ checked = {
 }
 
 inferred = {
+    # Not well typed - the branches of each if have different types
     "case1":  (
         """
         letrec
             even (x : int) -> ? = if isz(x) then 1 else (odd -(x, 1))
             odd (x : ?) -> bool = if isz(x) then 0 else (even -(x, 1))
             in (odd 13)
-        """, True )
+        """, False )
 }